@@ -0,0 +1,63 @@
+// Copyright © 2017 Adam Kunicki <kunickiaj@gmail.com>
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package cmd
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/go-git/go-git/v5"
+
+	"github.com/kunickiaj/beer/pkg/gitbackend"
+	"github.com/kunickiaj/beer/pkg/hooks"
+)
+
+// openBackend returns the backend selected by git.backend before a repository is opened.
+func openBackend() (gitbackend.Backend, error) {
+	switch strings.ToLower(config.Git.Backend) {
+	case "", gitbackend.GoGitName:
+		return &gitbackend.GoGit{}, nil
+	case gitbackend.ExecName:
+		return &gitbackend.Exec{}, nil
+	}
+	return nil, fmt.Errorf("%w: git.backend %q is not supported, use %s or %s", ErrUsage, config.Git.Backend, gitbackend.GoGitName, gitbackend.ExecName)
+}
+
+// gitBackend returns the implementation of the git operations beer performs on repo, selected by
+// git.backend: go-git by default, or the git command line tool. Either runs the repository's
+// hooks unless --no-verify is given.
+func gitBackend(repo *git.Repository) (gitbackend.Backend, error) {
+	switch strings.ToLower(config.Git.Backend) {
+	case "", gitbackend.GoGitName:
+		backend := gitbackend.NewGoGit(repo, func() (git.Signer, error) { return commitSigner(repo) })
+		// go-git doesn't run hooks, so beer runs them like git would
+		cfg, err := gitConfig(repo)
+		if err != nil {
+			return nil, err
+		}
+		if workTree, err := repo.Worktree(); err == nil {
+			backend.Hooks = hooks.New(cfg, gitDir(repo), workTree.Filesystem.Root())
+		}
+		return backend, nil
+	case gitbackend.ExecName:
+		workTree, err := repo.Worktree()
+		if err != nil {
+			return nil, err
+		}
+		return gitbackend.NewExec(workTree.Filesystem.Root()), nil
+	}
+	return nil, fmt.Errorf("%w: git.backend %q is not supported, use %s or %s", ErrUsage, config.Git.Backend, gitbackend.GoGitName, gitbackend.ExecName)
+}
@@ -18,42 +18,25 @@ package cmd
 import (
 	"fmt"
 	"io"
-	"os"
-	"os/user"
-	"path"
-	"regexp"
-	"strings"
-	"time"
-
-	"github.com/go-git/go-git/v5/plumbing/object"
 
 	jira "github.com/andygrunwald/go-jira"
 	"github.com/go-git/go-git/v5"
 	"github.com/go-git/go-git/v5/plumbing"
-	gogitConfig "github.com/go-git/go-git/v5/plumbing/format/config"
-	"github.com/pkg/errors"
 	log "github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
+
+	"github.com/kunickiaj/beer/pkg/gitbackend"
+	"github.com/kunickiaj/beer/pkg/metadata"
 )
 
 var brewCmd = &cobra.Command{
 	Use:   "brew",
 	Short: "Work on an existing JIRA or create a new ticket. Not specifying an ISSUE_ID creates a new JIRA.",
 	Long:  ``,
-	Run:   brew,
-	Args:  cobra.MaximumNArgs(1),
-}
-
-type FileType struct {
-	Name      string
-	Extension string
+	RunE:  brew,
+	Args:  usageArgs(cobra.MaximumNArgs(1)),
 }
 
-const (
-	testingStatusKey = "Testing Status"
-	docImpactKey     = "Doc Impact"
-)
-
 var description string
 var docImpact bool
 var issueType string
@@ -63,44 +46,66 @@ var projectKey string
 var summary string
 var testingStatus bool
 var autoMetadata bool
+var customFields map[string]string
+var templateFields map[string]string
+var fieldFlags []string
+var parentKey string
+var epicKey string
+var linkFlags []string
+var sprintName string
+var fixVersions []string
+var affectsVersions []string
+var priority string
 
 func init() {
 	RootCmd.AddCommand(brewCmd)
 
-	brewCmd.Flags().StringVarP(&projectKey, "project", "p", "", "JIRA project key, e.g. SDC, SDCE")
+	brewCmd.Flags().StringVarP(&projectKey, "project", "p", "", "JIRA project key, e.g. SDC, SDCE. Inferred from .beer.yaml, the remote, the branch or recent commits if not given")
 	brewCmd.Flags().StringVarP(&issueType, "issue-type", "t", "Bug", "Issue type to create, e.g. Bug, 'New Feature', etc. This varies by project.")
 	brewCmd.Flags().StringVarP(&summary, "summary", "s", "", "Issue summary")
 	brewCmd.Flags().StringVarP(&description, "description", "d", "", "Issue detailed description. If not specified defaults to summary")
-	brewCmd.Flags().BoolVarP(&docImpact, "doc-impact", "x", false, "When included, sets the Doc Impact field to 'Yes'")
-	brewCmd.Flags().BoolVarP(&testingStatus, "testing-status", "q", false, "When present, indicates extended testing is required.")
+	brewCmd.Flags().BoolVarP(&docImpact, "doc-impact", "x", false, "When included, sets the Doc Impact field to 'Yes'. The field and values can be changed with jira.flagFields.docImpact")
+	brewCmd.Flags().BoolVarP(&testingStatus, "testing-status", "q", false, "When present, indicates extended testing is required. The field and values can be changed with jira.flagFields.testingStatus")
+	brewCmd.Flags().StringArrayVarP(&fieldFlags, "field", "f", nil, "Sets a field by name, e.g. --field 'Story Points=3'. Can be repeated. Separate multiple values with commas and cascading select values with '>'")
 	brewCmd.Flags().StringSliceVarP(&components, "components", "c", nil, "Sets the components field of the issue. Can be a comma separated list.")
 	brewCmd.Flags().StringSliceVarP(&labels, "labels", "l", nil, "Sets the labels field of the issue. Can be a comma separated list.")
-	brewCmd.Flags().BoolVarP(&autoMetadata, "auto", "a", false, "Enable automatic metadata detection for components and/or labels.")
+	brewCmd.Flags().StringVar(&parentKey, "parent", "", "Create a sub-task of the given issue, e.g. PRJ-100")
+	brewCmd.Flags().StringVar(&epicKey, "epic", "", "Link the new issue to the given epic, e.g. PRJ-50")
+	brewCmd.Flags().StringArrayVar(&linkFlags, "link", nil, "Link the new issue to another one after creating it, e.g. --link 'blocks PRJ-77'. Can be repeated.")
+	brewCmd.Flags().StringVar(&sprintName, "sprint", "", "Add the new issue to a sprint: active, next or a sprint name. The board is configured with jira.boards")
+	brewCmd.Flags().StringSliceVar(&fixVersions, "fix-version", nil, "Sets the fix versions of the issue. Can be a comma separated list.")
+	brewCmd.Flags().StringSliceVar(&affectsVersions, "affects-version", nil, "Sets the affected versions of the issue. Can be a comma separated list.")
+	brewCmd.Flags().StringVar(&priority, "priority", "", "Sets the priority of the issue, e.g. Major")
+	brewCmd.Flags().Bool("worktree", false, "Work on the issue in its own linked worktree instead of switching the current one. Defaults to defaults.worktrees")
+	brewCmd.Flags().BoolVar(&noVerify, "no-verify", false, "Don't run the pre-commit and commit-msg hooks for the seed commit")
+	brewCmd.Flags().BoolVar(&noSign, "no-sign", false, "Don't sign the seed commit even if commit.gpgsign is set")
+	brewCmd.Flags().BoolVarP(&autoMetadata, "auto", "a", false, "Detect components, labels and the project from the changed files and repository URL with the auto rules. Proposes missing ones for existing issues")
 }
 
-func brew(cmd *cobra.Command, args []string) {
+func brew(cmd *cobra.Command, args []string) error {
 	dryRun, _ := cmd.Flags().GetBool("dry-run")
 
-	transport := jira.BasicAuthTransport{
-		Username:  config.Jira.Username,
-		Password:  config.Jira.Password,
+	jiraClient, err := newJiraClient()
+	if err != nil {
+		return err
 	}
-	jiraClient, _ := jira.NewClient(transport.Client(), config.Jira.URL)
 
-	cwd, err := os.Getwd()
+	repo, err := openRepo()
 	if err != nil {
-		panic(err)
+		return err
 	}
 
-	repo, err := git.PlainOpenWithOptions(cwd, &git.PlainOpenOptions{DetectDotGit: true})
-	if err != nil {
-		panic(err)
+	var detected *metadata.Result
+	if autoMetadata {
+		if detected, err = detectMetadata(repo); err != nil {
+			return err
+		}
 	}
 
 	// Get user struct for logged in user
-	jiraUser, _, err := jiraClient.User.GetSelf()
+	jiraUser, err := currentUser(jiraClient)
 	if err != nil {
-		panic(err)
+		return err
 	}
 
 	var issue *jira.Issue
@@ -108,14 +113,20 @@ func brew(cmd *cobra.Command, args []string) {
 		issueKey := args[0]
 
 		// Fetch details for existing issue
-		issue, _, err = jiraClient.Issue.Get(issueKey, nil)
+		var res *jira.Response
+		issue, res, err = jiraClient.Issue.Get(issueKey, nil)
 		if err != nil {
-			log.WithField("error", err).Fatal("Error fetching issue")
-			return
+			return jiraError(res, err, fmt.Sprintf("error fetching issue %s", issueKey))
+		}
+
+		if detected != nil {
+			if err := proposeMetadata(jiraClient, issue, detected, dryRun); err != nil {
+				return err
+			}
 		}
 
 		if dryRun {
-			return
+			return nil
 		}
 
 		// Ensure issue is assigned to self
@@ -123,18 +134,31 @@ func brew(cmd *cobra.Command, args []string) {
 
 		response, err := jiraClient.Issue.UpdateIssue(issue.ID, assignee)
 		if err != nil {
-			log.
-				WithError(err).
-				WithField("response", bodyToString(response)).
-				Fatal("Failed to update issue")
-			return
+			log.WithField("response", bodyToString(response)).Debug("Failed to update issue")
+			return jiraError(response, err, "failed to update issue")
 		}
 
 	} else {
 		// Creating a new JIRA
+		customFields, err = parseFieldFlags(fieldFlags)
+		if err != nil {
+			return err
+		}
+
+		if templateName != "" {
+			if err := applyTemplate(cmd, repo, templateName); err != nil {
+				return err
+			}
+		}
+
+		if summary == "" && isInteractive() {
+			if err := interactiveIssue(cmd, jiraClient, repo); err != nil {
+				return err
+			}
+		}
+
 		if summary == "" {
-			log.Error("When creating a new issue, an issue summary is required.")
-			return
+			return fmt.Errorf("%w: when creating a new issue, an issue summary is required", ErrUsage)
 		}
 
 		if description == "" {
@@ -143,64 +167,81 @@ func brew(cmd *cobra.Command, args []string) {
 
 		if dryRun {
 			log.WithFields(log.Fields{"summary": summary, "description": description}).Info("Dry Run")
-			return
+			return nil
+		}
+
+		if parentKey != "" {
+			if err := resolveParent(cmd, jiraClient, parentKey); err != nil {
+				return err
+			}
+		}
+
+		links, err := parseLinks(jiraClient, linkFlags)
+		if err != nil {
+			return err
 		}
 
 		// Create the issue
 		if len(projectKey) == 0 {
-			projectKey, err = getProjectKey(repo)
+			projectKey, err = inferProjectKey(repo)
+			if err != nil {
+				return err
+			}
+		}
+
+		var sprint *jira.Sprint
+		if sprintName != "" {
+			sprint, err = resolveSprint(jiraClient, projectKey, sprintName)
 			if err != nil {
-				log.WithField("error", err).Fatal()
-				return
+				return err
 			}
 		}
 
 		metaProject, err := createMetaProject(jiraClient, projectKey)
 		if err != nil {
-			log.WithField("error", err).Fatal()
-			return
+			return err
 		}
 
-		metaIssueType, err := createMetaIssueType(metaProject, issueType)
+		metaIssueType, err := createMetaIssueType(jiraClient, metaProject, issueType)
 		if err != nil {
-			log.WithField("error", err).Fatal()
-			return
+			return err
 		}
 
 		fieldsConfig := map[string]string{
 			"Project":     projectKey,
 			"Issue Type":  issueType,
 			"Summary":     summary,
-			"Description": description,
 			"Assignee":    assignee(jiraUser),
 		}
 
-		fields, err := metaIssueType.GetAllFields()
+		fields, err := metaFields(metaIssueType)
 		if err != nil {
-			log.WithField("error", err).Fatal()
-			return
+			return err
 		}
 
-		if _, ok := fields[testingStatusKey]; ok {
-			testingStatusStr := "Not Required"
-			if testingStatus {
-				testingStatusStr = "Required"
-			}
-			fieldsConfig[testingStatusKey] = testingStatusStr
+		issue, err = jira.InitIssueWithMetaAndFields(metaProject, metaIssueType, fieldsConfig)
+		if err != nil {
+			return fmt.Errorf("%w: %w", ErrUsage, err)
 		}
 
-		if _, ok := fields[docImpactKey]; ok {
-			docImpactStr := "No"
-			if docImpact {
-				docImpactStr = "Yes"
-			}
-			fieldsConfig[docImpactKey] = docImpactStr
+		values, err := resolveFieldValues(cmd, fields, projectKey, issueType, templateFields, customFields)
+		if err != nil {
+			return err
+		}
+		if err := planningFieldValues(values, fields); err != nil {
+			return err
+		}
+		if err := applyCustomFields(issue, fields, values, len(jiraUser.AccountID) > 0); err != nil {
+			return err
 		}
 
-		issue, err = jira.InitIssueWithMetaAndFields(metaProject, metaIssueType, fieldsConfig)
-		if err != nil {
-			log.WithField("error", err).Fatal()
-			return
+		if parentKey != "" {
+			issue.Fields.Parent = &jira.Parent{Key: parentKey}
+		}
+		if epicKey != "" {
+			if err := setEpic(issue, fields, epicKey); err != nil {
+				return err
+			}
 		}
 		log.WithField("issue", issue).Debug("Initialized Issue")
 
@@ -215,210 +256,149 @@ func brew(cmd *cobra.Command, args []string) {
 			issue.Fields.Components = jiraComponents
 		}
 
-		issue.Fields.Labels = labels
+		if err := validateComponents(jiraClient, projectKey, components); err != nil {
+			return err
+		}
 
+		issue.Fields.Labels = labels
 
-		issue = appendAutomaticMetadata(repo, issue)
+		if detected != nil {
+			if err := addDetectedMetadata(jiraClient, projectKey, issue, detected); err != nil {
+				return err
+			}
+		}
 
-		var res *jira.Response
-		issue, res, err = jiraClient.Issue.Create(issue)
+		created, res, err := createIssue(jiraClient, issue, description)
 		if err != nil {
-			log.WithFields(log.Fields{"error": err, "response": bodyToString(res)}).Fatal()
-			return
+			log.WithField("response", bodyToString(res)).Debug("Failed to create issue")
+			return jiraError(res, err, "failed to create issue")
 		}
-		issue, _, err = jiraClient.Issue.Get(issue.Key, nil)
+		// The issue exists from here on, so later failures are only warnings. Returning would
+		// skip the checkout and running brew again would create a duplicate issue.
+		if err := addLinks(jiraClient, created.Key, links); err != nil {
+			log.WithError(err).WithField("issue", created.Key).Warn("Created issue, but unable to link it")
+		}
+		if sprint != nil {
+			if err := moveToSprint(jiraClient, created.Key, sprint); err != nil {
+				log.WithError(err).WithFields(log.Fields{"issue": created.Key, "sprint": sprint.Name}).Warn("Created issue, but unable to move it to the sprint")
+			}
+		}
+		issue, res, err = jiraClient.Issue.Get(created.Key, nil)
 		if err != nil {
-			log.WithFields(log.Fields{"error": err, "key": issue.Key}).Fatal("Error fetching issue details")
-			return
+			return jiraError(res, err, fmt.Sprintf("error fetching issue details for %s", created.Key))
 		}
 	}
 
-	err = checkout(repo, issue)
+	// The description is seeded into the commit message, so strip any markup first
+	issue.Fields.Description, err = plainDescription(jiraClient, issue)
 	if err != nil {
-		log.WithField("error", err).Fatal()
-		return
+		return err
 	}
-}
 
-func assignee(jiraUser *jira.User) string {
-	if len(jiraUser.AccountID) > 0 {
-		return jiraUser.AccountID
+	var hash plumbing.Hash
+	var worktree string
+	if useWorktrees(cmd) {
+		worktree, hash, err = checkoutWorktree(repo, issue)
 	} else {
-		return jiraUser.Name
-	}
-}
-
-func appendAutomaticMetadata(repo *git.Repository, issue *jira.Issue) *jira.Issue {
-	if !autoMetadata {
-		return issue
+		hash, err = checkout(repo, issue)
 	}
-
-	w, _ := repo.Worktree()
-	files, err := w.Filesystem.ReadDir(".")
 	if err != nil {
-		log.WithError(err).Warn("metadata inference failed")
-		return issue
+		return err
 	}
 
-	terraformType := FileType{
-		Name:      "Terraform",
-		Extension: ".tf",
-	}
-	if containsFileType(files, terraformType) {
-		component := &jira.Component{
-			Name: terraformType.Name,
-		}
-		issue.Fields.Components = append(issue.Fields.Components, component)
-	}
+	return writeResult(cmd.OutOrStdout(), brewResult{
+		Key:      issue.Key,
+		URL:      issueURL(issue.Key),
+		Branch:   issue.Key,
+		Commit:   hash.String(),
+		Worktree: worktree,
+	})
+}
 
-	remote, err := repo.Remote("origin")
-	if err != nil {
-		log.WithError(err).Warn("metadata inference failed")
-		return issue
-	}
+// brewResult describes the issue and work branch prepared by brew.
+type brewResult struct {
+	Key    string `json:"key" yaml:"key"`
+	URL    string `json:"url" yaml:"url"`
+	Branch string `json:"branch" yaml:"branch"`
+	Commit string `json:"commit" yaml:"commit"`
+	// Worktree is the path of the issue's linked worktree in worktree mode
+	Worktree string `json:"worktree,omitempty" yaml:"worktree,omitempty"`
+}
 
+func (r brewResult) message() string {
+	return "Ready to brew"
+}
 
-	repoName := strings.TrimSuffix(path.Base(remote.Config().URLs[0]), ".git")
-	repoComponent := &jira.Component{
-		Name: repoName,
+func (r brewResult) fields() log.Fields {
+	f := log.Fields{"key": r.Key, "url": r.URL, "branch": r.Branch, "commit": r.Commit}
+	if r.Worktree != "" {
+		f["worktree"] = r.Worktree
 	}
-
-	issue.Fields.Components = append(issue.Fields.Components, repoComponent)
-	return issue
+	return f
 }
 
-func containsFileType(files []os.FileInfo, fileType FileType) bool {
-	for _, f := range files {
-		if !f.IsDir() && strings.HasSuffix(f.Name(), fileType.Extension) {
-			return true
-		}
+func assignee(jiraUser *jira.User) string {
+	if len(jiraUser.AccountID) > 0 {
+		return jiraUser.AccountID
+	} else {
+		return jiraUser.Name
 	}
-	return false
 }
 
 func bodyToString(res *jira.Response) string {
+	if res == nil || res.Body == nil {
+		return ""
+	}
 	bytes, _ := io.ReadAll(res.Body)
 	bodyStr := string(bytes)
 	return bodyStr
 }
 
-func checkout(repo *git.Repository, issue *jira.Issue) error {
-	workTree, err := repo.Worktree()
+// checkout switches to the work branch for issue, creating it along with the seed commit if it
+// doesn't exist yet, and returns the commit the branch points to.
+func checkout(repo *git.Repository, issue *jira.Issue) (plumbing.Hash, error) {
+	backend, err := gitBackend(repo)
 	if err != nil {
-		return err
+		return plumbing.ZeroHash, err
 	}
 
-	branch := fmt.Sprintf("refs/heads/%s", issue.Key)
-	b := plumbing.ReferenceName(branch)
-
 	// First try to checkout branch
 	newBranch := false
-	err = workTree.Checkout(&git.CheckoutOptions{Create: false, Force: false, Keep: true, Branch: b})
+	err = backend.Checkout(issue.Key, false)
 	if err != nil {
 		// didn't exist so try to create it
 		newBranch = true
-		err = workTree.Checkout(&git.CheckoutOptions{Create: true, Force: false, Keep: true, Branch: b})
+		err = backend.Checkout(issue.Key, true)
 	}
 
 	if err != nil {
-		return err
+		return plumbing.ZeroHash, err
 	}
 
 	if newBranch {
-		// If an issue description was provided, that isn't simply a repeat of the summary, we'll automatically include
-		// it in the commit message after the break.
-		extendedDescription := ""
-		if issue.Fields.Summary != issue.Fields.Description {
-			extendedDescription = fmt.Sprintf("\n\n%s", issue.Fields.Description)
-		}
-		commitMessage := fmt.Sprintf("%s. %s%s", issue.Key, issue.Fields.Summary, extendedDescription)
-		usr, err := user.Current()
-		if err != nil {
-			return err
-		}
-		gitConfig, err := os.Open(path.Join(usr.HomeDir, ".gitconfig"))
-		if err != nil {
-			return err
-		}
-		decoder := gogitConfig.NewDecoder(gitConfig)
-		decodedConfig := gogitConfig.New()
-		err = decoder.Decode(decodedConfig)
-
-		if err != nil {
-			return err
-		}
-
-		userSection := decodedConfig.Section("user")
-		_, err = workTree.Commit(commitMessage, &git.CommitOptions{
-			Author: &object.Signature{
-				Name:  userSection.Option("name"),
-				Email: userSection.Option("email"),
-				When:  time.Now(),
-			},
-		})
-		return err
+		return seedCommit(repo, backend, issue)
 	}
-	return nil
-}
 
-func getProjectKey(repo *git.Repository) (string, error) {
-	ref, err := repo.Head()
+	head, err := repo.Head()
 	if err != nil {
-		return "", errors.Wrap(err, "couldn't get HEAD reference when searching for project key")
+		return plumbing.ZeroHash, err
 	}
-
-	cIter, err := repo.Log(&git.LogOptions{From: ref.Hash()})
-	if err != nil {
-		return "", errors.Wrap(err, fmt.Sprintf("couldn't get git log from hash '%s'", ref.Hash().String()))
-	}
-
-	var depth uint
-	commit, err := cIter.Next()
-	for depth < 5 {
-		if err != nil {
-			return "", errors.Wrap(err, "log iterator had no next commit")
-		}
-		re := regexp.MustCompile("^([a-zA-Z]{3,})(-[0-9]+)")
-		message := commit.Message
-		log.WithField("message", commit.Message).Debug("Searching commit message...")
-		match := re.FindStringSubmatch(message)
-		if len(match) == 3 && len(match[1]) > 0 {
-			log.WithField("project_key", match[1]).Info("Inferred project key; override with --project if incorrect")
-			return match[1], nil
-		}
-		depth++
-		commit, err = cIter.Next()
-	}
-
-	return "", errors.Wrap(err, "wasn't able to infer a project key")
+	return head.Hash(), nil
 }
 
-func createMetaProject(jira *jira.Client, projectKey string) (*jira.MetaProject, error) {
-	meta, _, err := jira.Issue.GetCreateMeta(projectKey)
-	if err != nil {
-		return nil, err
+// seedCommit creates the empty commit a new work branch starts with.
+func seedCommit(repo *git.Repository, backend gitbackend.Backend, issue *jira.Issue) (plumbing.Hash, error) {
+	// If an issue description was provided, that isn't simply a repeat of the summary, we'll automatically include
+	// it in the commit message after the break.
+	extendedDescription := ""
+	if issue.Fields.Summary != issue.Fields.Description {
+		extendedDescription = fmt.Sprintf("\n\n%s", issue.Fields.Description)
 	}
-
-	metaProject := meta.GetProjectWithKey(projectKey)
-	if metaProject == nil {
-		return nil, fmt.Errorf("could not find project with key %s", projectKey)
-	}
-
-	return metaProject, nil
-}
-
-func createMetaIssueType(metaProject *jira.MetaProject, issueType string) (*jira.MetaIssueType, error) {
-	MetaIssueType := metaProject.GetIssueTypeWithName(issueType)
-	if MetaIssueType == nil {
-		return nil, fmt.Errorf("could not find issuetype %s, available types are %#v", issueType, getAllIssueTypeNames(metaProject))
+	commitMessage := fmt.Sprintf("%s. %s%s", issue.Key, issue.Fields.Summary, extendedDescription)
+	author, committer, err := commitIdentity(repo)
+	if err != nil {
+		return plumbing.ZeroHash, err
 	}
-	return MetaIssueType, nil
+	return backend.Commit(commitMessage, gitbackend.CommitOptions{Author: author, Committer: committer, AllowEmpty: true, NoSign: noSign, NoVerify: noVerify})
 }
 
-func getAllIssueTypeNames(project *jira.MetaProject) []string {
-	var foundIssueTypes []string
-	for _, m := range project.IssueTypes {
-		foundIssueTypes = append(foundIssueTypes, m.Name)
-	}
-	return foundIssueTypes
-}
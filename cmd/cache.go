@@ -0,0 +1,121 @@
+// Copyright © 2017 Adam Kunicki <kunickiaj@gmail.com>
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package cmd
+
+import (
+	"errors"
+	"net/url"
+	"path"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+
+	"github.com/kunickiaj/beer/pkg/cache"
+	"github.com/kunickiaj/beer/pkg/review"
+)
+
+var cacheCmd = &cobra.Command{
+	Use:   "cache",
+	Short: "Manage the on-disk cache of JIRA metadata.",
+	Long: `beer caches JIRA metadata that rarely changes but is slow to fetch, such as the fields of
+each issue type (createmeta), the current user and project components. The cache lives in
+beer's directory under the user cache directory ($XDG_CACHE_HOME on Linux), or under cache.dir
+if it is configured. 'beer cache clear' only removes beer's directory.
+
+Entries expire after the durations configured under cache.ttl. Pass --refresh to any command
+to ignore the cache. When JIRA can't be reached, expired entries are used instead so commands
+such as 'beer template validate' work offline.`,
+}
+
+var cacheClearCmd = &cobra.Command{
+	Use:   "clear",
+	Short: "Remove all cached JIRA metadata.",
+	RunE:  cacheClear,
+	Args:  usageArgs(cobra.ExactArgs(0)),
+}
+
+func init() {
+	RootCmd.AddCommand(cacheCmd)
+	cacheCmd.AddCommand(cacheClearCmd)
+
+	RootCmd.PersistentFlags().Bool("refresh", false, "Ignore cached JIRA metadata and fetch it again")
+	_ = viper.BindPFlag("cache.refresh", RootCmd.PersistentFlags().Lookup("refresh"))
+
+	viper.SetDefault("cache.ttl.createMeta", 24*time.Hour)
+	viper.SetDefault("cache.ttl.user", 24*time.Hour)
+	viper.SetDefault("cache.ttl.components", time.Hour)
+}
+
+func cacheClear(cmd *cobra.Command, args []string) error {
+	c, err := cache.New(config.Cache.Dir)
+	if err != nil {
+		return err
+	}
+
+	dryRun, _ := cmd.Flags().GetBool("dry-run")
+	if dryRun {
+		log.WithField("dir", c.Dir).Info("Dry Run")
+		return nil
+	}
+
+	if err := c.Clear(); err != nil {
+		return err
+	}
+	log.WithField("dir", c.Dir).Info("Cleared cache")
+	return nil
+}
+
+// cached returns the value stored under key if it is younger than ttl. Otherwise it calls fetch
+// and caches the result. If fetch fails because JIRA can't be reached, an expired value is used.
+// Keys are namespaced by JIRA server so switching servers doesn't mix up metadata.
+func cached[T any](key string, ttl time.Duration, fetch func() (T, error)) (T, error) {
+	c, err := cache.New(config.Cache.Dir)
+	if err != nil {
+		log.WithError(err).Debug("Cache unavailable")
+		return fetch()
+	}
+
+	if u, err := url.Parse(config.Jira.URL); err == nil && u.Host != "" {
+		key = path.Join(u.Host, key)
+	}
+
+	var value T
+	fresh, err := c.Get(key, ttl, &value)
+	hit := err == nil
+	if err != nil && !errors.Is(err, cache.ErrMiss) {
+		log.WithError(err).WithField("key", key).Debug("Ignoring unreadable cache entry")
+	}
+	if hit && fresh && !config.Cache.Refresh {
+		log.WithField("key", key).Debug("Using cached value")
+		return value, nil
+	}
+
+	fetched, err := fetch()
+	if err != nil {
+		if hit && errors.Is(err, review.ErrNetwork) {
+			log.WithError(err).WithField("key", key).Warn("JIRA unreachable, using expired cache entry")
+			return value, nil
+		}
+		return fetched, err
+	}
+
+	if err := c.Put(key, fetched); err != nil {
+		log.WithError(err).WithField("key", key).Debug("Unable to cache value")
+	}
+	return fetched, nil
+}
@@ -0,0 +1,374 @@
+// Copyright © 2017 Adam Kunicki <kunickiaj@gmail.com>
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package cmd
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	jira "github.com/andygrunwald/go-jira"
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+
+	"github.com/kunickiaj/beer/pkg/review"
+)
+
+const (
+	// deletedRefPrefix keeps deleted branches reachable so they survive garbage collection.
+	deletedRefPrefix = "refs/beer/deleted/"
+	// deletedLog records deleted branches, relative to the git directory.
+	deletedLog = "beer/deleted.log"
+)
+
+// changeIDPattern matches the Change-Id trailer Gerrit adds to commit messages.
+var changeIDPattern = regexp.MustCompile(`(?m)^Change-Id: (I[0-9a-f]{40})\s*$`)
+
+var cleanupCmd = &cobra.Command{
+	Use:   "cleanup",
+	Short: "Delete local branches whose issues and reviews are done.",
+	Long: `Finds local branches named after an issue and deletes those that are no longer needed: the
+review was merged, or the issue is resolved and the review isn't open. Branches whose review
+can't be looked up are kept. Their linked worktrees, created by 'brew --worktree', are removed
+too unless they have uncommitted changes. Gerrit changes are looked up by Change-Id using
+gerrit.webURL, and GitHub pull requests by branch name using $GITHUB_TOKEN if set. The current
+branch is never deleted.
+
+The branches to delete are listed and confirmed first. Pass --yes to skip the confirmation,
+which is required when not running interactively, or --dry-run to only list them.
+
+Deleted branches are recorded in .git/beer/deleted.log and kept as refs/beer/deleted/<BRANCH>,
+so 'beer cleanup --restore BRANCH' can bring one back.`,
+	RunE: cleanup,
+	Args: usageArgs(cobra.ExactArgs(0)),
+}
+
+var assumeYes bool
+var restoreBranch string
+
+func init() {
+	RootCmd.AddCommand(cleanupCmd)
+
+	cleanupCmd.Flags().BoolVarP(&assumeYes, "yes", "y", false, "Delete branches without asking for confirmation")
+	cleanupCmd.Flags().StringVar(&restoreBranch, "restore", "", "Restore a branch deleted by cleanup")
+
+	viper.SetDefault("github.apiURL", "https://api.github.com")
+}
+
+func cleanup(cmd *cobra.Command, args []string) error {
+	dryRun, _ := cmd.Flags().GetBool("dry-run")
+
+	repo, err := openRepo()
+	if err != nil {
+		return err
+	}
+	if restoreBranch != "" {
+		return restore(cmd, repo, restoreBranch, dryRun)
+	}
+
+	branches, err := issueBranches(repo)
+	if err != nil {
+		return err
+	}
+	head, err := repo.Head()
+	if err != nil {
+		return fmt.Errorf("unable to resolve HEAD: %w", err)
+	}
+	jiraClient, err := newJiraClient()
+	if err != nil {
+		return err
+	}
+
+	worktrees, err := listWorktrees()
+	if err != nil {
+		log.WithError(err).Warn("Unable to list worktrees")
+	}
+	mainPath, _ := mainWorktree()
+
+	result := cleanupResult{Branches: []cleanupBranch{}}
+	for _, ref := range branches {
+		b, err := inspectBranch(repo, jiraClient, ref, head.Name())
+		if err != nil {
+			return err
+		}
+		if path, ok := worktrees[b.Branch]; ok {
+			if path == mainPath {
+				// checked out in the main worktree while cleanup runs in a linked one
+				b.Delete, b.Reason = false, "checked out"
+			} else if ref.Name() != head.Name() {
+				b.Worktree = path
+			}
+		}
+		result.Branches = append(result.Branches, b)
+	}
+
+	var doomed []cleanupBranch
+	for _, b := range result.Branches {
+		if b.Delete {
+			doomed = append(doomed, b)
+		}
+	}
+	if dryRun || len(doomed) == 0 {
+		return writeResult(cmd.OutOrStdout(), result)
+	}
+
+	if !assumeYes {
+		if !isInteractive() {
+			return fmt.Errorf("%w: pass --yes to delete branches when not running interactively", ErrUsage)
+		}
+		_ = cleanupResult{Branches: doomed}.writeText(os.Stderr)
+		ok, err := confirm(bufio.NewReader(os.Stdin), os.Stderr, fmt.Sprintf("Delete %d branch(es)?", len(doomed)), false)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			return errCancelled
+		}
+	}
+
+	for i, b := range result.Branches {
+		if !b.Delete {
+			continue
+		}
+		if b.Worktree != "" {
+			if err := removeWorktree(b.Worktree); err != nil {
+				log.WithError(err).WithField("worktree", b.Worktree).Warn("Keeping branch, unable to remove its worktree")
+				continue
+			}
+		}
+		if err := deleteBranch(repo, b); err != nil {
+			return err
+		}
+		result.Branches[i].Deleted = true
+	}
+	return writeResult(cmd.OutOrStdout(), result)
+}
+
+// issueBranches returns the local branches named after an issue.
+func issueBranches(repo *git.Repository) ([]*plumbing.Reference, error) {
+	refs, err := repo.Branches()
+	if err != nil {
+		return nil, err
+	}
+	var branches []*plumbing.Reference
+	err = refs.ForEach(func(ref *plumbing.Reference) error {
+		if issueKeyPattern.MatchString(ref.Name().Short()) {
+			branches = append(branches, ref)
+		}
+		return nil
+	})
+	return branches, err
+}
+
+// inspectBranch looks up the issue and review of a branch and decides whether to delete it.
+func inspectBranch(repo *git.Repository, jiraClient *jira.Client, ref *plumbing.Reference, head plumbing.ReferenceName) (cleanupBranch, error) {
+	b := cleanupBranch{Branch: ref.Name().Short(), Commit: ref.Hash().String()}
+	key := strings.ToUpper(b.Branch)
+
+	issueDone := false
+	issue, res, err := jiraClient.Issue.Get(key, &jira.GetQueryOptions{Fields: "status"})
+	if err != nil {
+		err = jiraError(res, err, fmt.Sprintf("unable to fetch %s", key))
+		if !errors.Is(err, review.ErrNotFound) {
+			return b, err
+		}
+		b.Issue = "missing"
+	} else if status := issue.Fields.Status; status != nil {
+		b.Issue = status.Name
+		issueDone = status.StatusCategory.Key == jira.StatusCategoryComplete
+	}
+
+	state, err := reviewState(repo, ref)
+	if err != nil {
+		log.WithError(err).WithField("branch", b.Branch).Warn("Unable to determine review state")
+		state = review.StateUnknown
+	}
+	b.Review = string(state)
+
+	switch {
+	case ref.Name() == head:
+		b.Reason = "checked out"
+	case state == review.StateUnknown:
+		// the review may still be open, so the branch is kept until it can be checked
+		b.Reason = "review state unknown"
+	case state == review.StateMerged:
+		b.Delete, b.Reason = true, "review merged"
+	case state == review.StateOpen:
+		b.Reason = "review open"
+	case issueDone:
+		b.Delete, b.Reason = true, "issue "+b.Issue
+	default:
+		b.Reason = "issue " + b.Issue
+	}
+	return b, nil
+}
+
+// reviewState looks up the review of a branch with the configured review tool.
+func reviewState(repo *git.Repository, ref *plumbing.Reference) (review.State, error) {
+	switch config.ReviewTool.Normalize() {
+	case Gerrit:
+		if !strings.HasPrefix(config.Gerrit.WebURL, "http") {
+			return "", fmt.Errorf("%w: set gerrit.webURL to the Gerrit web URL, e.g. https://gerrit.example.com, to check changes", ErrUsage)
+		}
+		commit, err := repo.CommitObject(ref.Hash())
+		if err != nil {
+			return "", err
+		}
+		match := changeIDPattern.FindStringSubmatch(commit.Message)
+		if match == nil {
+			return review.StateNone, nil
+		}
+		return review.GerritChangeState(config.Gerrit.API(), match[1])
+	case GitHub:
+		upstream, err := resolveRemote(repo, upstreamRemoteName())
+		if err != nil {
+			return "", err
+		}
+		fork, err := resolveRemote(repo, pushRemoteName())
+		if err != nil {
+			return "", err
+		}
+		return review.GitHubPullState(config.GitHub.APIURL, upstream.URL, fork.PushURL, ref.Name().Short(), os.Getenv("GITHUB_TOKEN"))
+	}
+	return "", fmt.Errorf("%w: review tool %q is not yet supported", review.ErrNotImplemented, config.ReviewTool)
+}
+
+// deleteBranch deletes a branch, keeping its commit under refs/beer/deleted and recording it in
+// the log of deleted branches.
+func deleteBranch(repo *git.Repository, b cleanupBranch) error {
+	hash := plumbing.NewHash(b.Commit)
+	if err := repo.Storer.SetReference(plumbing.NewHashReference(plumbing.ReferenceName(deletedRefPrefix+b.Branch), hash)); err != nil {
+		return err
+	}
+	if err := recordDeletion(repo, fmt.Sprintf("%s %s\tdeleted %s: %s", b.Commit, time.Now().Format(time.RFC3339), b.Branch, b.Reason)); err != nil {
+		return err
+	}
+	if err := repo.Storer.RemoveReference(plumbing.NewBranchReferenceName(b.Branch)); err != nil {
+		return err
+	}
+	if err := repo.DeleteBranch(b.Branch); err != nil && !errors.Is(err, git.ErrBranchNotFound) {
+		return err
+	}
+	log.WithFields(log.Fields{"branch": b.Branch, "commit": b.Commit}).Debug("Deleted branch")
+	return nil
+}
+
+// restore recreates a branch deleted by cleanup.
+func restore(cmd *cobra.Command, repo *git.Repository, branch string, dryRun bool) error {
+	deleted := plumbing.ReferenceName(deletedRefPrefix + branch)
+	ref, err := repo.Reference(deleted, false)
+	if err != nil {
+		return fmt.Errorf("%w: %s wasn't deleted by cleanup, see .git/%s", review.ErrNotFound, branch, deletedLog)
+	}
+	name := plumbing.NewBranchReferenceName(branch)
+	if _, err := repo.Reference(name, false); err == nil {
+		return fmt.Errorf("%w: branch %s already exists", review.ErrConflict, branch)
+	}
+
+	if dryRun {
+		log.WithFields(log.Fields{"branch": branch, "commit": ref.Hash().String()}).Info("Dry Run")
+		return nil
+	}
+
+	if err := repo.Storer.SetReference(plumbing.NewHashReference(name, ref.Hash())); err != nil {
+		return err
+	}
+	if err := recordDeletion(repo, fmt.Sprintf("%s %s\trestored %s", ref.Hash(), time.Now().Format(time.RFC3339), branch)); err != nil {
+		return err
+	}
+	if err := repo.Storer.RemoveReference(deleted); err != nil {
+		return err
+	}
+	log.WithFields(log.Fields{"branch": branch, "commit": ref.Hash().String()}).Info("Restored branch")
+	return nil
+}
+
+// recordDeletion appends a line to the log of deleted branches in the git directory.
+func recordDeletion(repo *git.Repository, line string) error {
+	dir := gitDir(repo)
+	if dir == "" {
+		return nil
+	}
+	file := filepath.Join(dir, deletedLog)
+	if err := os.MkdirAll(filepath.Dir(file), 0o755); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(file, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+	if err != nil {
+		return err
+	}
+	if _, err := fmt.Fprintln(f, line); err != nil {
+		_ = f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// cleanupBranch describes a branch considered by cleanup.
+type cleanupBranch struct {
+	Branch  string `json:"branch" yaml:"branch"`
+	Commit  string `json:"commit" yaml:"commit"`
+	Issue   string `json:"issue" yaml:"issue"`   // Issue status
+	Review  string `json:"review" yaml:"review"` // Review state
+	Delete  bool   `json:"delete" yaml:"delete"` // Whether the branch is no longer needed
+	Deleted bool   `json:"deleted" yaml:"deleted"`
+	Reason  string `json:"reason" yaml:"reason"`
+	// Worktree is the linked worktree of the branch, removed along with it
+	Worktree string `json:"worktree,omitempty" yaml:"worktree,omitempty"`
+}
+
+// cleanupResult lists the branches considered by cleanup.
+type cleanupResult struct {
+	Branches []cleanupBranch `json:"branches" yaml:"branches"`
+}
+
+func (r cleanupResult) message() string {
+	return "Cleaned up branches"
+}
+
+func (r cleanupResult) fields() log.Fields {
+	return log.Fields{"count": len(r.Branches)}
+}
+
+// writeText prints the branches as a table.
+func (r cleanupResult) writeText(w io.Writer) error {
+	if len(r.Branches) == 0 {
+		log.Info("No issue branches found")
+		return nil
+	}
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	for _, b := range r.Branches {
+		action := "keep"
+		switch {
+		case b.Deleted:
+			action = "deleted"
+		case b.Delete:
+			action = "delete"
+		}
+		_, _ = fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n", b.Branch, action, b.Reason, b.Review)
+	}
+	return tw.Flush()
+}
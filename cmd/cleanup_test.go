@@ -0,0 +1,148 @@
+// Copyright © 2017 Adam Kunicki <kunickiaj@gmail.com>
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/spf13/cobra"
+
+	"github.com/kunickiaj/beer/pkg/review"
+)
+
+func TestInspectBranchGerrit(t *testing.T) {
+	tests := []struct {
+		name       string
+		status     int    // returned by Gerrit, 0 if gerrit.webURL isn't set
+		change     string // status of the change
+		wantDelete bool
+		wantReview string
+	}{
+		{name: "review merged", status: http.StatusOK, change: "MERGED", wantDelete: true, wantReview: "merged"},
+		{name: "review abandoned", status: http.StatusOK, change: "ABANDONED", wantDelete: true, wantReview: "abandoned"},
+		{name: "review open", status: http.StatusOK, change: "NEW", wantReview: "open"},
+		{name: "Gerrit failing", status: http.StatusServiceUnavailable, wantReview: "unknown"},
+		{name: "web URL not set", wantReview: "unknown"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := newTestRepo(t)
+			r.commit("PRJ-7. r1")
+			r.checkout("PRJ-1", true)
+			r.commit("PRJ-1. Seed\n\nChange-Id: I0123456789abcdef0123456789abcdef01234567\n")
+			r.checkout(plumbing.Main.Short(), false)
+
+			config.ReviewTool = Gerrit
+			newJiraServer(t, map[string]string{"PRJ-1": "done"})
+			jiraClient, err := newJiraClient()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tt.status != 0 {
+				gerrit := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+					w.WriteHeader(tt.status)
+					_, _ = fmt.Fprintf(w, ")]}'\n[{\"status\": %q}]", tt.change)
+				}))
+				t.Cleanup(gerrit.Close)
+				config.Gerrit.WebURL = gerrit.URL
+			}
+
+			ref, err := r.repo.Reference(plumbing.NewBranchReferenceName("PRJ-1"), true)
+			if err != nil {
+				t.Fatal(err)
+			}
+			b, err := inspectBranch(r.repo, jiraClient, ref, plumbing.NewBranchReferenceName(plumbing.Main.Short()))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if b.Delete != tt.wantDelete || b.Review != tt.wantReview {
+				t.Errorf("inspectBranch() = delete %t, review %s (%s), want delete %t, review %s", b.Delete, b.Review, b.Reason, tt.wantDelete, tt.wantReview)
+			}
+		})
+	}
+}
+
+func TestDeleteAndRestoreBranch(t *testing.T) {
+	r := newTestRepo(t)
+	r.commit("PRJ-7. r1")
+	r.checkout("PRJ-1", true)
+	w1 := r.commit("PRJ-1. w1")
+	r.checkout(plumbing.Main.Short(), false)
+
+	branch := plumbing.NewBranchReferenceName("PRJ-1")
+	deleted := plumbing.ReferenceName(deletedRefPrefix + "PRJ-1")
+	logFile := filepath.Join(r.dir, ".git", filepath.FromSlash(deletedLog))
+
+	if err := deleteBranch(r.repo, cleanupBranch{Branch: "PRJ-1", Commit: w1.String(), Reason: "review merged"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := r.repo.Reference(branch, false); err == nil {
+		t.Error("deleteBranch() kept the branch")
+	}
+	if ref, err := r.repo.Reference(deleted, false); err != nil || ref.Hash() != w1 {
+		t.Errorf("deleteBranch() left %s = %v, %v, want %s", deleted, ref, err, w1)
+	}
+	data, err := os.ReadFile(logFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if line := string(data); !strings.HasPrefix(line, w1.String()+" ") || !strings.HasSuffix(line, "\tdeleted PRJ-1: review merged\n") {
+		t.Errorf("%s = %q, want the deleted commit, branch and reason", deletedLog, line)
+	}
+
+	cmd := &cobra.Command{}
+	if err := restore(cmd, r.repo, "PRJ-1", true); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := r.repo.Reference(branch, false); err == nil {
+		t.Error("restore() with dry run recreated the branch")
+	}
+
+	if err := restore(cmd, r.repo, "PRJ-1", false); err != nil {
+		t.Fatal(err)
+	}
+	if ref, err := r.repo.Reference(branch, false); err != nil || ref.Hash() != w1 {
+		t.Errorf("restore() created %v, %v, want PRJ-1 at %s", ref, err, w1)
+	}
+	if _, err := r.repo.Reference(deleted, false); err == nil {
+		t.Errorf("restore() kept %s", deleted)
+	}
+	if data, err = os.ReadFile(logFile); err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	if len(lines) != 2 || !strings.HasPrefix(lines[1], w1.String()+" ") || !strings.HasSuffix(lines[1], "\trestored PRJ-1") {
+		t.Errorf("%s = %q, want the deletion followed by the restore", deletedLog, lines)
+	}
+
+	if err := restore(cmd, r.repo, "PRJ-1", false); !errors.Is(err, review.ErrNotFound) {
+		t.Errorf("restore() of a branch that wasn't deleted = %v, want ErrNotFound", err)
+	}
+	if err := r.repo.Storer.SetReference(plumbing.NewHashReference(deleted, w1)); err != nil {
+		t.Fatal(err)
+	}
+	if err := restore(cmd, r.repo, "PRJ-1", false); !errors.Is(err, review.ErrConflict) {
+		t.Errorf("restore() over an existing branch = %v, want ErrConflict", err)
+	}
+}
@@ -0,0 +1,97 @@
+// Copyright © 2017 Adam Kunicki <kunickiaj@gmail.com>
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package cmd
+
+import (
+	"fmt"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+var commentCmd = &cobra.Command{
+	Use:   "comment [TEXT...]",
+	Short: "Comment on the JIRA issue of the current branch.",
+	Long: `Adds a comment to the issue the current work branch was brewed for. The comment is written
+in Markdown and converted like issue descriptions are. Without any text, the comment is written
+in your editor.`,
+	RunE: comment,
+}
+
+var commentIssue string
+
+func init() {
+	RootCmd.AddCommand(commentCmd)
+
+	commentCmd.Flags().StringVarP(&commentIssue, "issue", "i", "", "Comment on the given issue instead of the current branch's")
+}
+
+func comment(cmd *cobra.Command, args []string) error {
+	dryRun, _ := cmd.Flags().GetBool("dry-run")
+
+	key, err := issueKeyOrBranch(commentIssue)
+	if err != nil {
+		return err
+	}
+
+	text := strings.TrimSpace(strings.Join(args, " "))
+	if text == "" && isInteractive() {
+		text, err = editText(fmt.Sprintf("\n# Comment on %s. Lines starting with '#' are ignored.\n", key))
+		if err != nil {
+			return err
+		}
+	}
+	if text == "" {
+		return fmt.Errorf("%w: comment text is required", ErrUsage)
+	}
+
+	if dryRun {
+		log.WithFields(log.Fields{"issue": key, "comment": text}).Info("Dry Run")
+		return nil
+	}
+
+	jiraClient, err := newJiraClient()
+	if err != nil {
+		return err
+	}
+
+	created, res, err := addComment(jiraClient, key, text)
+	if err != nil {
+		return jiraError(res, err, fmt.Sprintf("unable to comment on %s", key))
+	}
+
+	return writeResult(cmd.OutOrStdout(), commentResult{
+		Key: key,
+		ID:  created.ID,
+		URL: fmt.Sprintf("%s?focusedCommentId=%s", issueURL(key), created.ID),
+	})
+}
+
+// commentResult describes a comment added by comment.
+type commentResult struct {
+	Key string `json:"key" yaml:"key"`
+	ID  string `json:"id" yaml:"id"`
+	URL string `json:"url" yaml:"url"`
+}
+
+func (r commentResult) message() string {
+	return "Added comment"
+}
+
+func (r commentResult) fields() log.Fields {
+	return log.Fields{"key": r.Key, "id": r.ID, "url": r.URL}
+}
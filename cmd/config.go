@@ -1,12 +1,26 @@
 package cmd
 
-import "strings"
+import (
+	"os"
+	"strings"
+	"time"
+
+	"github.com/kunickiaj/beer/pkg/metadata"
+	"github.com/kunickiaj/beer/pkg/review"
+)
 
 type Config struct {
 	Jira JiraConfig
 	Gerrit GerritConfig
 	GitHub GithubConfig
 	ReviewTool ReviewTool
+	Templates map[string]issueTemplate
+	Cache CacheConfig
+	State StateConfig
+	Worklog WorklogConfig
+	Verify VerifyConfig
+	Git GitConfig
+	Auto metadata.Rules
 }
 
 type ReviewTool string
@@ -23,19 +37,101 @@ const (
 type Defaults struct {
 	Branch string
 	ReviewTool ReviewTool
+	Worktrees bool // Brew each issue in its own linked worktree
+	WorktreeDir string // Where linked worktrees are created, relative to the repository
+	Remote string // Remote reviews are pushed to
+	Upstream string // Remote the target branch is fetched from, defaults to Remote
+	HistoryDepth int // Number of recent commits searched for the project of new issues
 }
 // JiraConfig configuration structure for JIRA
 type JiraConfig struct {
 	URL      string
 	Username string
 	Password string
+	Queries  map[string]string // Named JQL queries for pour, keyed by name
+	Boards   map[string]int    // Agile board IDs used for --sprint, keyed by project
+
+	// APIVersion is the JIRA REST API version, 2 for JIRA Server and Data Center or 3 for
+	// JIRA Cloud's Atlassian Document Format descriptions
+	APIVersion int
+	// Markdown converts descriptions from Markdown to wiki markup for API version 2. Disable it
+	// to pass wiki markup through unchanged.
+	Markdown bool
+
+	// FieldDefaults are custom field values for new issues keyed by project, issue type and
+	// field name. Use "*" to match any project or issue type.
+	FieldDefaults map[string]map[string]map[string]string
+	// Notify configures what beer writes back to issues on review events.
+	Notify NotifyConfig
+	// FlagFields configures the fields set by brew's --testing-status and --doc-impact flags.
+	FlagFields map[string]FlagField
+}
+
+// FlagField maps a boolean brew flag onto a custom field value.
+type FlagField struct {
+	Field string // Name of the field
+	On    string // Value when the flag is set
+	Off   string // Value when the flag is not set
+}
+
+// NotifyConfig configures automatic updates of JIRA issues
+type NotifyConfig struct {
+	Taste       bool // Record reviews published by taste on the issue
+	RemoteLinks bool // Record reviews as remote links instead of comments
 }
 
 // GerritConfig configuration structure for gerrit
 type GerritConfig struct {
-	URL string
+	URL      string
+	WebURL   string // Web URL of the server, used to look up changes with the REST API
+	Username string // HTTP credentials for the REST API
+	Password string // HTTP password, or set $BEER_GERRIT_PASSWORD
+}
+
+// API returns the REST API of the configured Gerrit server.
+func (g GerritConfig) API() review.GerritAPI {
+	return review.GerritAPI{URL: g.WebURL, Username: g.Username, Password: firstNonEmpty(g.Password, os.Getenv("BEER_GERRIT_PASSWORD"))}
 }
 
 type GithubConfig struct {
+	APIURL string // GitHub API used to look up pull requests, for GitHub Enterprise
+}
+
+// GitConfig configures how beer runs git operations and authenticates to git remotes
+type GitConfig struct {
+	Backend  string // gogit to run git operations in process, exec to run the git command line tool
+	SSHKey   string // Private key for SSH remotes, instead of ssh-agent
+	Username string // User name for HTTPS remotes whose URL has none
+	Token    string // Password or access token for HTTPS remotes, instead of a credential helper
+}
+
+// CacheConfig configuration structure for the on-disk cache of JIRA metadata
+type CacheConfig struct {
+	Dir     string // Directory beer's cache directory is created in, defaults to the user cache directory
+	Refresh bool   // Ignore cached values and fetch them again
+	TTL     CacheTTL
+}
+
+// CacheTTL is how long each kind of cached JIRA metadata stays fresh
+type CacheTTL struct {
+	CreateMeta time.Duration
+	User       time.Duration
+	Components time.Duration
+}
+
+// StateConfig configuration structure for state kept between runs, such as running timers
+type StateConfig struct {
+	Dir string // Defaults to beer's directory under $XDG_STATE_HOME
+}
+
+// WorklogConfig configures how worklog --suggest estimates time from commits
+type WorklogConfig struct {
+	SessionGap   time.Duration // Longest pause between commits within one session of work
+	SessionStart time.Duration // Work assumed before the first commit of each session
+}
 
+// VerifyConfig configures the checks taste runs on commit messages before publishing
+type VerifyConfig struct {
+	Mode          string // warn, block or off
+	SubjectLength int    // Longest allowed subject line, 0 for no limit
 }
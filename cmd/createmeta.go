@@ -0,0 +1,174 @@
+// Copyright © 2017 Adam Kunicki <kunickiaj@gmail.com>
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"sort"
+
+	jira "github.com/andygrunwald/go-jira"
+
+	"github.com/kunickiaj/beer/pkg/review"
+)
+
+// metaField describes a field of an issue type as reported by JIRA's createmeta.
+type metaField struct {
+	ID            string
+	Name          string
+	Required      bool
+	HasDefault    bool
+	Type          string // schema type, e.g. string, array, option, user
+	Items         string // element type when Type is array
+	Custom        string // custom field type, e.g. com.atlassian.jira.plugin.system.customfieldtypes:select
+	AllowedValues []string
+	Children      map[string][]string // allowed child values of cascading selects, keyed by parent value
+}
+
+// metaFields lists the fields of an issue type sorted by name.
+func metaFields(issueType *jira.MetaIssueType) ([]metaField, error) {
+	// The fields are a generic map; round trip them through JSON to get typed values.
+	data, err := json.Marshal(issueType.Fields)
+	if err != nil {
+		return nil, err
+	}
+
+	var raw map[string]struct {
+		Name       string `json:"name"`
+		Required   bool   `json:"required"`
+		HasDefault bool   `json:"hasDefaultValue"`
+		Schema     struct {
+			Type   string `json:"type"`
+			Items  string `json:"items"`
+			Custom string `json:"custom"`
+		} `json:"schema"`
+		AllowedValues []struct {
+			Name     string `json:"name"`
+			Value    string `json:"value"`
+			Children []struct {
+				Value string `json:"value"`
+			} `json:"children"`
+		} `json:"allowedValues"`
+	}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, err
+	}
+
+	fields := make([]metaField, 0, len(raw))
+	for id, f := range raw {
+		field := metaField{
+			ID:         id,
+			Name:       f.Name,
+			Required:   f.Required,
+			HasDefault: f.HasDefault,
+			Type:       f.Schema.Type,
+			Items:      f.Schema.Items,
+			Custom:     f.Schema.Custom,
+		}
+		for _, v := range f.AllowedValues {
+			if v.Value != "" {
+				field.AllowedValues = append(field.AllowedValues, v.Value)
+			} else if v.Name != "" {
+				field.AllowedValues = append(field.AllowedValues, v.Name)
+			}
+			for _, c := range v.Children {
+				if field.Children == nil {
+					field.Children = map[string][]string{}
+				}
+				field.Children[v.Value] = append(field.Children[v.Value], c.Value)
+			}
+		}
+		fields = append(fields, field)
+	}
+
+	sort.Slice(fields, func(i, j int) bool { return fields[i].Name < fields[j].Name })
+	return fields, nil
+}
+
+// createMetaProject returns the project with its issue types. Fields aren't included, use
+// createMetaIssueType to get those for a single issue type.
+func createMetaProject(jiraClient *jira.Client, projectKey string) (*jira.MetaProject, error) {
+	meta, err := cached("createmeta/"+projectKey, config.Cache.TTL.CreateMeta, func() (*jira.CreateMetaInfo, error) {
+		return getCreateMeta(jiraClient, url.Values{"projectKeys": {projectKey}})
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	metaProject := meta.GetProjectWithKey(projectKey)
+	if metaProject == nil {
+		return nil, fmt.Errorf("%w: could not find project with key %s", review.ErrNotFound, projectKey)
+	}
+
+	return metaProject, nil
+}
+
+// createMetaIssueType returns an issue type of the project including its fields. Only the fields
+// of the requested issue type are fetched since createmeta for a whole project can be huge.
+func createMetaIssueType(jiraClient *jira.Client, metaProject *jira.MetaProject, issueType string) (*jira.MetaIssueType, error) {
+	metaIssueType := metaProject.GetIssueTypeWithName(issueType)
+	if metaIssueType == nil {
+		return nil, fmt.Errorf("%w: could not find issuetype %s, available types are %#v", review.ErrNotFound, issueType, getAllIssueTypeNames(metaProject))
+	}
+
+	key := fmt.Sprintf("createmeta/%s/%s", metaProject.Key, metaIssueType.Name)
+	meta, err := cached(key, config.Cache.TTL.CreateMeta, func() (*jira.CreateMetaInfo, error) {
+		return getCreateMeta(jiraClient, url.Values{
+			"projectKeys":  {metaProject.Key},
+			"issuetypeIds": {metaIssueType.Id},
+			"expand":       {"projects.issuetypes.fields"},
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if p := meta.GetProjectWithKey(metaProject.Key); p != nil {
+		if t := p.GetIssueTypeWithName(issueType); t != nil {
+			return t, nil
+		}
+	}
+	return nil, fmt.Errorf("%w: no create metadata for issuetype %s", review.ErrNotFound, issueType)
+}
+
+// getCreateMeta fetches createmeta from the configured REST API version. go-jira always uses
+// version 2, so the request is built by hand.
+func getCreateMeta(jiraClient *jira.Client, query url.Values) (*jira.CreateMetaInfo, error) {
+	version := 2
+	if config.Jira.APIVersion >= 3 {
+		version = 3
+	}
+	req, err := jiraClient.NewRequest("GET", fmt.Sprintf("rest/api/%d/issue/createmeta?%s", version, query.Encode()), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	meta := new(jira.CreateMetaInfo)
+	res, err := jiraClient.Do(req, meta)
+	if err != nil {
+		return nil, jiraError(res, err, "unable to fetch create metadata")
+	}
+	return meta, nil
+}
+
+func getAllIssueTypeNames(project *jira.MetaProject) []string {
+	var foundIssueTypes []string
+	for _, m := range project.IssueTypes {
+		foundIssueTypes = append(foundIssueTypes, m.Name)
+	}
+	return foundIssueTypes
+}
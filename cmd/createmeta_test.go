@@ -0,0 +1,61 @@
+// Copyright © 2017 Adam Kunicki <kunickiaj@gmail.com>
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package cmd
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestCreateMetaAPIVersion(t *testing.T) {
+	for _, version := range []int{2, 3} {
+		t.Run(fmt.Sprintf("version %d", version), func(t *testing.T) {
+			saved := config
+			t.Cleanup(func() { config = saved })
+			config = Config{}
+			config.Jira.APIVersion = version
+			config.Cache.Dir = t.TempDir()
+
+			var paths []string
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+				paths = append(paths, req.URL.Path)
+				w.Header().Set("Content-Type", "application/json")
+				_, _ = fmt.Fprint(w, `{"projects": [{"key": "PRJ", "issuetypes": [{"id": "1", "name": "Bug", "fields": {"summary": {"name": "Summary", "required": true, "schema": {"type": "string"}}}}]}]}`)
+			}))
+			t.Cleanup(server.Close)
+			config.Jira.URL = server.URL
+
+			jiraClient, err := newJiraClient()
+			if err != nil {
+				t.Fatal(err)
+			}
+			project, err := createMetaProject(jiraClient, "PRJ")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if _, err := createMetaIssueType(jiraClient, project, "Bug"); err != nil {
+				t.Fatal(err)
+			}
+
+			want := fmt.Sprintf("/rest/api/%d/issue/createmeta", version)
+			if len(paths) != 2 || paths[0] != want || paths[1] != want {
+				t.Errorf("requested %v, want %s twice", paths, want)
+			}
+		})
+	}
+}
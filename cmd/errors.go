@@ -0,0 +1,94 @@
+// Copyright © 2017 Adam Kunicki <kunickiaj@gmail.com>
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	jira "github.com/andygrunwald/go-jira"
+	"github.com/spf13/cobra"
+
+	"github.com/kunickiaj/beer/pkg/review"
+)
+
+// Exit codes returned by beer. Scripts can rely on these values remaining stable.
+const (
+	ExitOK             = 0 // Command completed successfully
+	ExitError          = 1 // Unclassified error
+	ExitUsage          = 2 // Invalid flags or arguments
+	ExitAuthentication = 3 // JIRA or git remote rejected the credentials
+	ExitNotFound       = 4 // Issue, project, repository or remote not found
+	ExitConflict       = 5 // Push rejected or conflicting update
+	ExitNetwork        = 6 // Server could not be reached
+	ExitNotImplemented = 7 // Functionality not supported by the configured tool
+)
+
+// ErrUsage is returned when a command is invoked with invalid flags or arguments.
+var ErrUsage = errors.New("invalid usage")
+
+// exitCode maps an error returned by a command to one of the documented exit codes.
+func exitCode(err error) int {
+	switch {
+	case err == nil:
+		return ExitOK
+	case errors.Is(err, ErrUsage):
+		return ExitUsage
+	case errors.Is(err, review.ErrAuthentication):
+		return ExitAuthentication
+	case errors.Is(err, review.ErrNotFound):
+		return ExitNotFound
+	case errors.Is(err, review.ErrConflict):
+		return ExitConflict
+	case errors.Is(err, review.ErrNetwork):
+		return ExitNetwork
+	case errors.Is(err, review.ErrNotImplemented):
+		return ExitNotImplemented
+	}
+	return ExitError
+}
+
+// jiraError wraps an error returned by the JIRA client with the matching error category
+// based on the HTTP status of the response, if any.
+func jiraError(res *jira.Response, err error, msg string) error {
+	if err == nil {
+		return nil
+	}
+	if res == nil || res.Response == nil {
+		return fmt.Errorf("%s: %w: %w", msg, review.ErrNetwork, err)
+	}
+
+	switch res.StatusCode {
+	case http.StatusUnauthorized, http.StatusForbidden:
+		return fmt.Errorf("%s: %w: %w", msg, review.ErrAuthentication, err)
+	case http.StatusNotFound:
+		return fmt.Errorf("%s: %w: %w", msg, review.ErrNotFound, err)
+	case http.StatusConflict:
+		return fmt.Errorf("%s: %w: %w", msg, review.ErrConflict, err)
+	}
+	return fmt.Errorf("%s: %w", msg, err)
+}
+
+// usageArgs wraps a cobra positional argument validator so that failures are reported as usage errors.
+func usageArgs(validate cobra.PositionalArgs) cobra.PositionalArgs {
+	return func(cmd *cobra.Command, args []string) error {
+		if err := validate(cmd, args); err != nil {
+			return fmt.Errorf("%w: %w", ErrUsage, err)
+		}
+		return nil
+	}
+}
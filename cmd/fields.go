@@ -0,0 +1,275 @@
+// Copyright © 2017 Adam Kunicki <kunickiaj@gmail.com>
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package cmd
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	jira "github.com/andygrunwald/go-jira"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+const (
+	testingStatusKey = "Testing Status"
+	docImpactKey     = "Doc Impact"
+)
+
+// defaultFlagFields preserves the original meaning of the --testing-status and --doc-impact
+// flags when they aren't configured under jira.flagFields.
+var defaultFlagFields = map[string]FlagField{
+	"testing-status": {Field: testingStatusKey, On: "Required", Off: "Not Required"},
+	"doc-impact":     {Field: docImpactKey, On: "Yes", Off: "No"},
+}
+
+// cascadeSeparator separates the parent and child values of a cascading select, e.g. "Hardware > Disk".
+const cascadeSeparator = ">"
+
+// parseFieldFlags parses repeated --field Name=value flags.
+func parseFieldFlags(flags []string) (map[string]string, error) {
+	values := map[string]string{}
+	for _, f := range flags {
+		name, value, ok := strings.Cut(f, "=")
+		name = strings.TrimSpace(name)
+		if !ok || name == "" {
+			return nil, fmt.Errorf("%w: invalid field %q, expected Name=value", ErrUsage, f)
+		}
+		values[name] = strings.TrimSpace(value)
+	}
+	return values, nil
+}
+
+// resolveFieldValues merges the custom field values for a new issue. Configured defaults for the
+// project and issue type are applied first, then the template's fields, the boolean alias flags
+// and finally --field.
+// Values are keyed by field ID, so a field named in a different case or by ID is only set once
+// and the later value wins. Defaults naming a field the issue type doesn't have are skipped, since
+// they may be configured for every issue type.
+func resolveFieldValues(cmd *cobra.Command, fields []metaField, project string, issueType string, template map[string]string, explicit map[string]string) (map[string]string, error) {
+	values := map[string]string{}
+	for _, p := range []string{"*", project} {
+		for _, t := range []string{"*", issueType} {
+			if err := setFieldValues(values, fields, config.Jira.FieldDefaults[strings.ToLower(p)][strings.ToLower(t)], true); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	if err := setFieldValues(values, fields, template, false); err != nil {
+		return nil, err
+	}
+
+	for flag, alias := range flagFields() {
+		f := findField(fields, alias.Field)
+		if f == nil {
+			continue
+		}
+		if _, hasDefault := values[f.ID]; hasDefault && !cmd.Flags().Changed(flag) {
+			continue
+		}
+		on, _ := cmd.Flags().GetBool(flag)
+		values[f.ID] = alias.Off
+		if on {
+			values[f.ID] = alias.On
+		}
+	}
+
+	if err := setFieldValues(values, fields, explicit, false); err != nil {
+		return nil, err
+	}
+	return values, nil
+}
+
+// setFieldValue sets the value of the field called name in values keyed by field ID.
+func setFieldValue(values map[string]string, fields []metaField, name string, value string) error {
+	f := findField(fields, name)
+	if f == nil {
+		return fmt.Errorf("%w: field %q is not available for this issue type", ErrUsage, name)
+	}
+	values[f.ID] = value
+	return nil
+}
+
+// setFieldValues sets values given by field name in values keyed by field ID. Names are applied in
+// order so the result doesn't depend on map iteration when two of them name the same field. Names
+// of fields the issue type doesn't have are an error unless optional is set.
+func setFieldValues(values map[string]string, fields []metaField, named map[string]string, optional bool) error {
+	names := make([]string, 0, len(named))
+	for name := range named {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if optional && findField(fields, name) == nil {
+			log.WithField("field", name).Debug("Skipping default for a field the issue type doesn't have")
+			continue
+		}
+		if err := setFieldValue(values, fields, name, named[name]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// flagFields returns the fields set by the boolean alias flags, keyed by flag name.
+func flagFields() map[string]FlagField {
+	aliases := map[string]FlagField{}
+	for flag, alias := range defaultFlagFields {
+		// viper lower cases keys and drops the dash, e.g. testingStatus becomes testingstatus
+		if configured, ok := config.Jira.FlagFields[strings.ReplaceAll(flag, "-", "")]; ok {
+			alias = configured
+		}
+		aliases[flag] = alias
+	}
+	return aliases
+}
+
+// applyCustomFields validates values, keyed by field name or ID, against the issue type's
+// createmeta and sets them on issue.
+func applyCustomFields(issue *jira.Issue, fields []metaField, values map[string]string, cloud bool) error {
+	for name, value := range values {
+		f := findField(fields, name)
+		if f == nil {
+			return fmt.Errorf("%w: field %q is not available for this issue type", ErrUsage, name)
+		}
+
+		v, err := fieldValue(f, value, cloud)
+		if err != nil {
+			return fmt.Errorf("%w: field %q: %w", ErrUsage, f.Name, err)
+		}
+		issue.Fields.Unknowns[f.ID] = v
+	}
+	return nil
+}
+
+// findField looks up a field by name, ignoring case, or by ID such as customfield_10010.
+func findField(fields []metaField, name string) *metaField {
+	for i := range fields {
+		if strings.EqualFold(fields[i].Name, name) || fields[i].ID == name {
+			return &fields[i]
+		}
+	}
+	return nil
+}
+
+// lookupValue finds the value for a field in values keyed by field name in any case.
+func lookupValue(values map[string]string, f *metaField) (string, bool) {
+	for name, value := range values {
+		if strings.EqualFold(name, f.Name) || name == f.ID {
+			return value, true
+		}
+	}
+	return "", false
+}
+
+// fieldValue converts a value given on the command line or in config to the representation JIRA
+// expects for the field's type. Multiple values are comma separated and cascading selects use
+// "Parent > Child".
+func fieldValue(f *metaField, value string, cloud bool) (interface{}, error) {
+	switch {
+	case strings.HasSuffix(f.Custom, ":cascadingselect"):
+		parent, child, hasChild := strings.Cut(value, cascadeSeparator)
+		parent, err := allowedValue(f.AllowedValues, strings.TrimSpace(parent))
+		if err != nil {
+			return nil, err
+		}
+		option := map[string]interface{}{"value": parent}
+		if hasChild {
+			child, err := allowedValue(f.Children[parent], strings.TrimSpace(child))
+			if err != nil {
+				return nil, err
+			}
+			option["child"] = map[string]string{"value": child}
+		}
+		return option, nil
+	case f.Type == "array":
+		var items []interface{}
+		for _, v := range splitList(value) {
+			item, err := scalarValue(f, f.Items, v, cloud)
+			if err != nil {
+				return nil, err
+			}
+			items = append(items, item)
+		}
+		return items, nil
+	}
+	return scalarValue(f, f.Type, value, cloud)
+}
+
+// scalarValue converts a single value of the given schema type.
+func scalarValue(f *metaField, schemaType string, value string, cloud bool) (interface{}, error) {
+	switch schemaType {
+	case "option":
+		v, err := allowedValue(f.AllowedValues, value)
+		return map[string]string{"value": v}, err
+	case "priority", "version", "component", "resolution":
+		v, err := allowedValue(f.AllowedValues, value)
+		return map[string]string{"name": v}, err
+	case "user":
+		if cloud {
+			return map[string]string{"accountId": value}, nil
+		}
+		return map[string]string{"name": value}, nil
+	case "number":
+		return strconv.ParseFloat(value, 64)
+	case "date":
+		if _, err := time.Parse("2006-01-02", value); err != nil {
+			return nil, fmt.Errorf("expected a date formatted as YYYY-MM-DD: %w", err)
+		}
+		return value, nil
+	case "datetime":
+		t, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			return nil, fmt.Errorf("expected an RFC 3339 timestamp: %w", err)
+		}
+		return t.Format("2006-01-02T15:04:05.000-0700"), nil
+	case "string", "any", "":
+		if len(f.AllowedValues) > 0 {
+			return allowedValue(f.AllowedValues, value)
+		}
+		return value, nil
+	}
+	return nil, fmt.Errorf("unsupported field type %s", schemaType)
+}
+
+// allowedValue returns the allowed value matching value, ignoring case. Any value is accepted
+// when createmeta doesn't list allowed values.
+func allowedValue(allowed []string, value string) (string, error) {
+	if len(allowed) == 0 {
+		return value, nil
+	}
+	for _, a := range allowed {
+		if strings.EqualFold(a, value) {
+			return a, nil
+		}
+	}
+	return "", fmt.Errorf("%q is not one of the allowed values %q", value, allowed)
+}
+
+// splitList splits a comma separated list, trimming whitespace and dropping empty values.
+func splitList(value string) []string {
+	var values []string
+	for _, v := range strings.Split(value, ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			values = append(values, v)
+		}
+	}
+	return values
+}
@@ -0,0 +1,143 @@
+// Copyright © 2017 Adam Kunicki <kunickiaj@gmail.com>
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package cmd
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/spf13/cobra"
+)
+
+func TestResolveFieldValues(t *testing.T) {
+	fields := []metaField{
+		{ID: "customfield_1", Name: "Story Points", Type: "number"},
+		{ID: "customfield_2", Name: "Testing Status", Type: "option"},
+		{ID: "customfield_3", Name: "Team", Type: "string"},
+		{ID: "priority", Name: "Priority", Type: "priority"},
+	}
+	tests := []struct {
+		name     string
+		defaults map[string]string // for any project and issue type, lower cased like viper does
+		flags    []string
+		template map[string]string
+		explicit map[string]string
+		priority string
+		want     map[string]string
+	}{
+		{
+			name:     "--field overrides a default named in another case",
+			defaults: map[string]string{"story points": "3"},
+			explicit: map[string]string{"Story Points": "5"},
+			want:     map[string]string{"customfield_1": "5", "customfield_2": "Not Required"},
+		},
+		{
+			name:     "--field by ID overrides a default by name",
+			defaults: map[string]string{"story points": "3"},
+			explicit: map[string]string{"customfield_1": "8"},
+			want:     map[string]string{"customfield_1": "8", "customfield_2": "Not Required"},
+		},
+		{
+			name:     "template overrides a default",
+			defaults: map[string]string{"story points": "3"},
+			template: map[string]string{"Story Points": "8"},
+			want:     map[string]string{"customfield_1": "8", "customfield_2": "Not Required"},
+		},
+		{
+			name:     "--field overrides a template field named in another case",
+			template: map[string]string{"story points": "3"},
+			explicit: map[string]string{"Story Points": "5"},
+			want:     map[string]string{"customfield_1": "5", "customfield_2": "Not Required"},
+		},
+		{
+			name:     "template kept over unset alias flag",
+			template: map[string]string{"Testing Status": "Maybe"},
+			want:     map[string]string{"customfield_2": "Maybe"},
+		},
+		{
+			name:     "default kept over unset alias flag",
+			defaults: map[string]string{"testing status": "Maybe"},
+			want:     map[string]string{"customfield_2": "Maybe"},
+		},
+		{
+			name:     "alias flag overrides default",
+			defaults: map[string]string{"testing status": "Maybe"},
+			flags:    []string{"--testing-status"},
+			want:     map[string]string{"customfield_2": "Required"},
+		},
+		{
+			name:     "--field overrides alias flag",
+			flags:    []string{"--testing-status"},
+			explicit: map[string]string{"testing status": "Maybe"},
+			want:     map[string]string{"customfield_2": "Maybe"},
+		},
+		{
+			name:     "default for a field the issue type doesn't have is skipped",
+			defaults: map[string]string{"severity": "High", "story points": "3"},
+			explicit: map[string]string{"Story Points": "5"},
+			want:     map[string]string{"customfield_1": "5", "customfield_2": "Not Required"},
+		},
+		{
+			name:     "--priority overrides --field",
+			explicit: map[string]string{"Priority": "Minor"},
+			priority: "Major",
+			want:     map[string]string{"customfield_2": "Not Required", "priority": "Major"},
+		},
+	}
+
+	saved := config
+	t.Cleanup(func() { config, priority = saved, "" })
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config = Config{}
+			config.Jira.FieldDefaults = map[string]map[string]map[string]string{"*": {"*": tt.defaults}}
+			priority = tt.priority
+			cmd := &cobra.Command{}
+			cmd.Flags().Bool("testing-status", false, "")
+			cmd.Flags().Bool("doc-impact", false, "")
+			if err := cmd.Flags().Parse(tt.flags); err != nil {
+				t.Fatal(err)
+			}
+
+			// repeated to catch results depending on map iteration order
+			for i := 0; i < 20; i++ {
+				got, err := resolveFieldValues(cmd, fields, "PRJ", "Bug", tt.template, tt.explicit)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if err := planningFieldValues(got, fields); err != nil {
+					t.Fatal(err)
+				}
+				if !reflect.DeepEqual(got, tt.want) {
+					t.Fatalf("resolveFieldValues() = %v, want %v", got, tt.want)
+				}
+			}
+		})
+	}
+}
+
+func TestResolveFieldValuesUnknownField(t *testing.T) {
+	saved := config
+	t.Cleanup(func() { config = saved })
+	config = Config{}
+	cmd := &cobra.Command{}
+	if _, err := resolveFieldValues(cmd, nil, "PRJ", "Bug", nil, map[string]string{"Nope": "1"}); err == nil {
+		t.Error("resolveFieldValues() with an unknown field succeeded")
+	}
+	if _, err := resolveFieldValues(cmd, nil, "PRJ", "Bug", map[string]string{"Nope": "1"}, nil); err == nil {
+		t.Error("resolveFieldValues() with an unknown template field succeeded")
+	}
+}
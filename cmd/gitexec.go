@@ -0,0 +1,62 @@
+// Copyright © 2017 Adam Kunicki <kunickiaj@gmail.com>
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// runGit runs the git CLI for operations go-git doesn't support, such as rebasing, and returns
+// its trimmed output. Stderr is included in the error if the command fails.
+func runGit(args ...string) (string, error) {
+	log.WithField("args", args).Debug("Running git")
+	c := exec.Command("git", args...)
+	var stdout, stderr bytes.Buffer
+	c.Stdout = &stdout
+	c.Stderr = &stderr
+	if err := c.Run(); err != nil {
+		return strings.TrimSpace(stdout.String()), fmt.Errorf("git %s failed: %w: %s", args[0], err, strings.TrimSpace(stderr.String()))
+	}
+	return strings.TrimSpace(stdout.String()), nil
+}
+
+// operationInProgress returns the name of the rebase, merge or similar operation that is stopped
+// in the repository, or an empty string if there is none.
+func operationInProgress() (string, error) {
+	gitDir, err := runGit("rev-parse", "--absolute-git-dir")
+	if err != nil {
+		return "", err
+	}
+	for file, operation := range map[string]string{
+		"rebase-merge":     "rebase",
+		"rebase-apply":     "rebase",
+		"MERGE_HEAD":       "merge",
+		"CHERRY_PICK_HEAD": "cherry-pick",
+		"REVERT_HEAD":      "revert",
+	} {
+		if _, err := os.Stat(filepath.Join(gitDir, file)); err == nil {
+			return operation, nil
+		}
+	}
+	return "", nil
+}
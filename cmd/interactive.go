@@ -0,0 +1,218 @@
+// Copyright © 2017 Adam Kunicki <kunickiaj@gmail.com>
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package cmd
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	jira "github.com/andygrunwald/go-jira"
+	"github.com/go-git/go-git/v5"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+// promptedFields are filled in by brew itself and are never prompted for as custom fields.
+var promptedFields = map[string]bool{
+	"Project":        true,
+	"Issue Type":     true,
+	"Summary":        true,
+	"Description":    true,
+	"Assignee":       true,
+	"Reporter":       true,
+	"Component/s":    true,
+	"Components":     true,
+	"Labels":         true,
+	testingStatusKey: true,
+	docImpactKey:     true,
+}
+
+// interactiveIssue prompts for the details of a new issue using the project's createmeta to
+// discover required fields and their allowed values. The answers are stored in brew's flag
+// variables so issue creation proceeds exactly as if they had been passed on the command line.
+func interactiveIssue(cmd *cobra.Command, jiraClient *jira.Client, repo *git.Repository) error {
+	in := bufio.NewReader(os.Stdin)
+	out := os.Stderr
+
+	var err error
+	if projectKey == "" {
+		var inferred string
+		if candidates, _ := projectCandidates(repo); len(candidates) > 0 {
+			inferred = candidates[0].Key
+		}
+		if projectKey, err = prompt(in, out, "Project key", inferred); err != nil {
+			return err
+		}
+	}
+
+	metaProject, err := createMetaProject(jiraClient, projectKey)
+	if err != nil {
+		return err
+	}
+
+	issueTypes := getAllIssueTypeNames(metaProject)
+	if !cmd.Flags().Changed("issue-type") {
+		idx, err := pick(in, out, "Issue type", issueTypes)
+		if err != nil {
+			return err
+		}
+		if err := cmd.Flags().Set("issue-type", issueTypes[idx]); err != nil {
+			return err
+		}
+	}
+
+	metaIssueType, err := createMetaIssueType(jiraClient, metaProject, issueType)
+	if err != nil {
+		return err
+	}
+
+	for summary == "" {
+		if summary, err = prompt(in, out, "Summary", ""); err != nil {
+			return err
+		}
+	}
+
+	if description == "" {
+		initial := fmt.Sprintf("%s\n\n# Describe the issue. Lines starting with '#' are ignored.\n", summary)
+		if description, err = editText(initial); err != nil {
+			return err
+		}
+	}
+
+	fields, err := metaFields(metaIssueType)
+	if err != nil {
+		return err
+	}
+
+	for _, f := range fields {
+		switch {
+		case f.ID == "components":
+			if len(components) == 0 {
+				components, err = promptList(in, out, "Components", f.AllowedValues)
+			}
+		case f.Name == "Labels":
+			if len(labels) == 0 {
+				labels, err = promptList(in, out, "Labels", nil)
+			}
+		case f.Required && !f.HasDefault && !promptedFields[f.Name]:
+			if !fieldGiven(f) {
+				err = promptField(in, out, f)
+			}
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	var name string
+	for {
+		if name, err = prompt(in, out, "Save as template (name, empty to skip)", ""); err != nil || name == "" {
+			return err
+		}
+		invalid := checkTemplateName(name)
+		if invalid == nil {
+			break
+		}
+		_, _ = fmt.Fprintf(out, "  %v\n", invalid)
+	}
+
+	file, err := saveTemplate(repo, name, issueTemplate{
+		Project:     projectKey,
+		IssueType:   issueType,
+		Summary:     summary,
+		Description: description,
+		Components:  components,
+		Labels:      labels,
+		Fields:      givenFields(),
+	})
+	if err != nil {
+		return err
+	}
+	log.WithField("template", file).Info("Saved issue template")
+	return nil
+}
+
+// fieldGiven reports whether f has a value from --field or the template, named in any case or by
+// ID.
+func fieldGiven(f metaField) bool {
+	for _, named := range []map[string]string{templateFields, customFields} {
+		for name := range named {
+			if name == f.ID || strings.EqualFold(name, f.Name) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// givenFields returns the template's fields overridden by --field and the prompted values.
+func givenFields() map[string]string {
+	fields := map[string]string{}
+	for name, value := range templateFields {
+		fields[name] = value
+	}
+	for name, value := range customFields {
+		for t := range fields {
+			if strings.EqualFold(t, name) {
+				delete(fields, t)
+			}
+		}
+		fields[name] = value
+	}
+	return fields
+}
+
+// promptField asks for the value of a required field, offering its allowed values if any.
+func promptField(in *bufio.Reader, out io.Writer, f metaField) error {
+	var value string
+	if len(f.AllowedValues) > 0 {
+		idx, err := pick(in, out, f.Name, f.AllowedValues)
+		if err != nil {
+			return err
+		}
+		value = f.AllowedValues[idx]
+	} else {
+		for value == "" {
+			var err error
+			if value, err = prompt(in, out, f.Name, ""); err != nil {
+				return err
+			}
+		}
+	}
+
+	if customFields == nil {
+		customFields = map[string]string{}
+	}
+	customFields[f.Name] = value
+	return nil
+}
+
+// promptList asks for a comma separated list of values, listing the allowed values if any.
+func promptList(in *bufio.Reader, out io.Writer, name string, allowed []string) ([]string, error) {
+	if len(allowed) > 0 {
+		_, _ = fmt.Fprintf(out, "\n%s: %s\n", name, strings.Join(allowed, ", "))
+	}
+
+	answer, err := prompt(in, out, name+" (comma separated, empty for none)", "")
+	if err != nil || answer == "" {
+		return nil, err
+	}
+
+	return splitList(answer), nil
+}
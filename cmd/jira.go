@@ -0,0 +1,222 @@
+// Copyright © 2017 Adam Kunicki <kunickiaj@gmail.com>
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	jira "github.com/andygrunwald/go-jira"
+
+	"github.com/kunickiaj/beer/pkg/markup"
+)
+
+// issueKeyPattern matches an issue key such as PRJ-123.
+var issueKeyPattern = regexp.MustCompile(`^[A-Za-z][A-Za-z0-9_]*-[0-9]+$`)
+
+// newJiraClient returns a JIRA client authenticated with the configured credentials.
+func newJiraClient() (*jira.Client, error) {
+	transport := jira.BasicAuthTransport{
+		Username: config.Jira.Username,
+		Password: config.Jira.Password,
+	}
+	jiraClient, err := jira.NewClient(transport.Client(), config.Jira.URL)
+	if err != nil {
+		return nil, fmt.Errorf("%w: invalid JIRA URL: %w", ErrUsage, err)
+	}
+	return jiraClient, nil
+}
+
+// issueURL returns the link to an issue in the JIRA web UI.
+func issueURL(key string) string {
+	return fmt.Sprintf("%s/browse/%s", strings.TrimSuffix(config.Jira.URL, "/"), key)
+}
+
+// currentUser returns the JIRA user beer is logged in as.
+func currentUser(jiraClient *jira.Client) (*jira.User, error) {
+	return cached("user/"+config.Jira.Username, config.Cache.TTL.User, func() (*jira.User, error) {
+		jiraUser, res, err := jiraClient.User.GetSelf()
+		if err != nil {
+			return nil, jiraError(res, err, "unable to fetch current JIRA user")
+		}
+		return jiraUser, nil
+	})
+}
+
+// projectComponents returns the names of a project's components.
+func projectComponents(jiraClient *jira.Client, projectKey string) ([]string, error) {
+	return cached("components/"+projectKey, config.Cache.TTL.Components, func() ([]string, error) {
+		project, res, err := jiraClient.Project.Get(projectKey)
+		if err != nil {
+			return nil, jiraError(res, err, fmt.Sprintf("unable to fetch project %s", projectKey))
+		}
+
+		names := make([]string, len(project.Components))
+		for i, c := range project.Components {
+			names[i] = c.Name
+		}
+		return names, nil
+	})
+}
+
+// validateComponents checks that each of the names is a component of the project.
+func validateComponents(jiraClient *jira.Client, projectKey string, names []string) error {
+	if len(names) == 0 {
+		return nil
+	}
+
+	allowed, err := projectComponents(jiraClient, projectKey)
+	if err != nil {
+		return err
+	}
+
+	for _, name := range names {
+		if _, err := allowedValue(allowed, name); err != nil {
+			return fmt.Errorf("%w: component %w", ErrUsage, err)
+		}
+	}
+	return nil
+}
+
+// createIssue creates issue with the given Markdown description. The description is converted
+// to wiki markup for REST API version 2, or to Atlassian Document Format for version 3.
+func createIssue(jiraClient *jira.Client, issue *jira.Issue, description string) (*jira.Issue, *jira.Response, error) {
+	if config.Jira.APIVersion < 3 {
+		if config.Jira.Markdown {
+			description = markup.MarkdownToWiki(description)
+		}
+		issue.Fields.Unknowns["description"] = description
+		return jiraClient.Issue.Create(issue)
+	}
+
+	// go-jira only speaks version 2, so build the version 3 request by hand
+	data, err := json.Marshal(issue)
+	if err != nil {
+		return nil, nil, err
+	}
+	var payload map[string]interface{}
+	if err := json.Unmarshal(data, &payload); err != nil {
+		return nil, nil, err
+	}
+	fields, _ := payload["fields"].(map[string]interface{})
+	if fields == nil {
+		fields = map[string]interface{}{}
+		payload["fields"] = fields
+	}
+	fields["description"] = markup.MarkdownToADF(description)
+
+	req, err := jiraClient.NewRequest("POST", "rest/api/3/issue", payload)
+	if err != nil {
+		return nil, nil, err
+	}
+	created := new(jira.Issue)
+	res, err := jiraClient.Do(req, created)
+	return created, res, err
+}
+
+// addComment adds a comment written in Markdown to an issue, converting it like createIssue does
+// for descriptions.
+func addComment(jiraClient *jira.Client, key string, body string) (*jira.Comment, *jira.Response, error) {
+	if config.Jira.APIVersion < 3 {
+		if config.Jira.Markdown {
+			body = markup.MarkdownToWiki(body)
+		}
+		return jiraClient.Issue.AddComment(key, &jira.Comment{Body: body})
+	}
+
+	payload := map[string]interface{}{"body": markup.MarkdownToADF(body)}
+	req, err := jiraClient.NewRequest("POST", fmt.Sprintf("rest/api/3/issue/%s/comment", key), payload)
+	if err != nil {
+		return nil, nil, err
+	}
+	// the body is returned as ADF, so only decode the comment's identity
+	var created struct {
+		ID string `json:"id"`
+	}
+	res, err := jiraClient.Do(req, &created)
+	return &jira.Comment{ID: created.ID}, res, err
+}
+
+// addWorklog logs time spent on an issue with a comment written in Markdown.
+func addWorklog(jiraClient *jira.Client, key string, started time.Time, spent time.Duration, comment string) (*jira.WorklogRecord, *jira.Response, error) {
+	startedAt := jira.Time(started)
+	record := &jira.WorklogRecord{Started: &startedAt, TimeSpentSeconds: int(spent.Seconds())}
+	if config.Jira.APIVersion < 3 {
+		if config.Jira.Markdown {
+			comment = markup.MarkdownToWiki(comment)
+		}
+		record.Comment = comment
+		return jiraClient.Issue.AddWorklogRecord(key, record)
+	}
+
+	payload := map[string]interface{}{"started": record.Started, "timeSpentSeconds": record.TimeSpentSeconds}
+	if comment != "" {
+		payload["comment"] = markup.MarkdownToADF(comment)
+	}
+	req, err := jiraClient.NewRequest("POST", fmt.Sprintf("rest/api/3/issue/%s/worklog", key), payload)
+	if err != nil {
+		return nil, nil, err
+	}
+	// the comment is returned as ADF, so only decode the worklog's identity
+	var created struct {
+		ID string `json:"id"`
+	}
+	res, err := jiraClient.Do(req, &created)
+	return &jira.WorklogRecord{ID: created.ID}, res, err
+}
+
+// plainDescription returns the description of an issue as plain text. Wiki markup is stripped
+// for REST API version 2 and Atlassian Document Format is rendered as text for version 3.
+func plainDescription(jiraClient *jira.Client, issue *jira.Issue) (string, error) {
+	if config.Jira.APIVersion < 3 {
+		return markup.WikiToText(issue.Fields.Description), nil
+	}
+
+	req, err := jiraClient.NewRequest("GET", fmt.Sprintf("rest/api/3/issue/%s?fields=description", issue.Key), nil)
+	if err != nil {
+		return "", err
+	}
+	var v3 struct {
+		Fields struct {
+			Description *markup.Node `json:"description"`
+		} `json:"fields"`
+	}
+	res, err := jiraClient.Do(req, &v3)
+	if err != nil {
+		return "", jiraError(res, err, fmt.Sprintf("unable to fetch description of %s", issue.Key))
+	}
+	return markup.ADFToText(v3.Fields.Description), nil
+}
+
+// searchIssues runs a JQL query. JIRA Cloud (API version 3) has replaced the search endpoint
+// used by JIRA Server, so the Cloud specific one is used there.
+func searchIssues(jiraClient *jira.Client, query string, maxResults int, fields []string) ([]jira.Issue, error) {
+	var issues []jira.Issue
+	var res *jira.Response
+	var err error
+	if config.Jira.APIVersion < 3 {
+		issues, res, err = jiraClient.Issue.Search(query, &jira.SearchOptions{MaxResults: maxResults, Fields: fields})
+	} else {
+		issues, res, err = jiraClient.Issue.SearchV2JQL(query, &jira.SearchOptionsV2{MaxResults: maxResults, Fields: fields})
+	}
+	if err != nil {
+		return nil, jiraError(res, err, "issue search failed")
+	}
+	return issues, nil
+}
@@ -0,0 +1,174 @@
+// Copyright © 2017 Adam Kunicki <kunickiaj@gmail.com>
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package cmd
+
+import (
+	"fmt"
+	"strings"
+
+	jira "github.com/andygrunwald/go-jira"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+// epicLinkField is the custom field classic JIRA projects use to link an issue to its epic.
+// Team-managed (next-gen) projects use the parent field instead.
+const epicLinkField = "Epic Link"
+
+// issueLink is a link to create from a new issue to an existing one.
+type issueLink struct {
+	linkType jira.IssueLinkType
+	outward  bool   // true if the new issue is the source of the link, e.g. "blocks"
+	key      string // the existing issue
+}
+
+// parseLinks parses --link flags such as "blocks PRJ-77" or "is blocked by PRJ-77". The
+// relation must be the name, outward or inward description of one of the server's link types.
+func parseLinks(jiraClient *jira.Client, flags []string) ([]issueLink, error) {
+	if len(flags) == 0 {
+		return nil, nil
+	}
+
+	types, err := issueLinkTypes(jiraClient)
+	if err != nil {
+		return nil, err
+	}
+
+	var links []issueLink
+	for _, f := range flags {
+		trimmed := strings.TrimSpace(f)
+		i := strings.LastIndex(trimmed, " ")
+		if i < 0 {
+			return nil, fmt.Errorf("%w: invalid link %q, expected '<relation> <ISSUE-KEY>'", ErrUsage, f)
+		}
+		relation, key := strings.TrimSpace(trimmed[:i]), trimmed[i+1:]
+		if !issueKeyPattern.MatchString(key) {
+			return nil, fmt.Errorf("%w: invalid link %q, %q is not an issue key", ErrUsage, f, key)
+		}
+
+		link, err := findLinkType(types, relation)
+		if err != nil {
+			return nil, err
+		}
+		link.key = strings.ToUpper(key)
+		links = append(links, link)
+	}
+	return links, nil
+}
+
+// findLinkType matches a relation against the link types' names and descriptions.
+func findLinkType(types []jira.IssueLinkType, relation string) (issueLink, error) {
+	var relations []string
+	for _, t := range types {
+		switch {
+		case strings.EqualFold(t.Outward, relation), strings.EqualFold(t.Name, relation):
+			return issueLink{linkType: t, outward: true}, nil
+		case strings.EqualFold(t.Inward, relation):
+			return issueLink{linkType: t, outward: false}, nil
+		}
+		relations = append(relations, t.Outward, t.Inward)
+	}
+	return issueLink{}, fmt.Errorf("%w: unknown link relation %q, available relations are %q", ErrUsage, relation, relations)
+}
+
+// issueLinkTypes returns the issue link types configured on the server.
+func issueLinkTypes(jiraClient *jira.Client) ([]jira.IssueLinkType, error) {
+	return cached("linktypes", config.Cache.TTL.CreateMeta, func() ([]jira.IssueLinkType, error) {
+		types, res, err := jiraClient.IssueLinkType.GetList()
+		if err != nil {
+			return nil, jiraError(res, err, "unable to fetch issue link types")
+		}
+		return types, nil
+	})
+}
+
+// addLinks links the issue with the given key to each of the linked issues.
+func addLinks(jiraClient *jira.Client, key string, links []issueLink) error {
+	for _, l := range links {
+		// JIRA calls the source of a link the inward issue, e.g. in "A blocks B" A is the inward issue
+		link := &jira.IssueLink{
+			Type:         jira.IssueLinkType{Name: l.linkType.Name},
+			InwardIssue:  &jira.Issue{Key: key},
+			OutwardIssue: &jira.Issue{Key: l.key},
+		}
+		relation := l.linkType.Outward
+		if !l.outward {
+			link.InwardIssue, link.OutwardIssue = link.OutwardIssue, link.InwardIssue
+			relation = l.linkType.Inward
+		}
+
+		res, err := jiraClient.Issue.AddLink(link)
+		if err != nil {
+			return jiraError(res, err, fmt.Sprintf("unable to link %s %s %s", key, relation, l.key))
+		}
+		log.WithFields(log.Fields{"issue": key, "relation": relation, "linked": l.key}).Info("Linked issue")
+	}
+	return nil
+}
+
+// resolveParent checks that the parent issue exists and fills in the project and a sub-task issue
+// type unless they were chosen with a flag, template or prompt. A chosen issue type must be a
+// sub-task type.
+func resolveParent(cmd *cobra.Command, jiraClient *jira.Client, parent string) error {
+	issue, res, err := jiraClient.Issue.Get(parent, &jira.GetQueryOptions{Fields: "project"})
+	if err != nil {
+		return jiraError(res, err, fmt.Sprintf("unable to fetch parent issue %s", parent))
+	}
+
+	if projectKey == "" && issue.Fields != nil {
+		projectKey = issue.Fields.Project.Key
+	}
+
+	metaProject, err := createMetaProject(jiraClient, projectKey)
+	if err != nil {
+		return err
+	}
+
+	var subtaskTypes []string
+	for _, t := range metaProject.IssueTypes {
+		if t.Subtasks {
+			subtaskTypes = append(subtaskTypes, t.Name)
+		}
+	}
+	if len(subtaskTypes) == 0 {
+		return fmt.Errorf("%w: project %s has no sub-task issue types", ErrUsage, projectKey)
+	}
+
+	if !cmd.Flags().Changed("issue-type") {
+		issueType = subtaskTypes[0]
+		return nil
+	}
+	for _, t := range subtaskTypes {
+		if strings.EqualFold(t, issueType) {
+			return nil
+		}
+	}
+	return fmt.Errorf("%w: %s is not a sub-task issue type, available types are %q", ErrUsage, issueType, subtaskTypes)
+}
+
+// setEpic links a new issue to an epic, using the classic Epic Link field if the issue type has
+// one and the parent field used by team-managed projects otherwise.
+func setEpic(issue *jira.Issue, fields []metaField, epic string) error {
+	if f := findField(fields, epicLinkField); f != nil {
+		issue.Fields.Unknowns[f.ID] = epic
+		return nil
+	}
+	if f := findField(fields, "parent"); f != nil {
+		issue.Fields.Parent = &jira.Parent{Key: epic}
+		return nil
+	}
+	return fmt.Errorf("%w: issue type %s can't be linked to an epic", ErrUsage, issueType)
+}
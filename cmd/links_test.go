@@ -0,0 +1,85 @@
+// Copyright © 2017 Adam Kunicki <kunickiaj@gmail.com>
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package cmd
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/spf13/cobra"
+)
+
+func TestResolveParent(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch req.URL.Path {
+		case "/rest/api/2/issue/PRJ-100":
+			_, _ = fmt.Fprint(w, `{"key": "PRJ-100", "fields": {"project": {"key": "PRJ"}}}`)
+		case "/rest/api/2/issue/createmeta":
+			_, _ = fmt.Fprint(w, `{"projects": [{"key": "PRJ", "issuetypes": [{"id": "1", "name": "Bug"}, {"id": "5", "name": "Sub-task", "subtask": true}, {"id": "6", "name": "Sub-bug", "subtask": true}]}]}`)
+		default:
+			http.NotFound(w, req)
+		}
+	}))
+	t.Cleanup(server.Close)
+
+	tests := []struct {
+		name    string
+		flags   []string // chosen issue type, as given by a flag, template or prompt
+		want    string
+		wantErr bool
+	}{
+		{name: "first sub-task type by default", want: "Sub-task"},
+		{name: "chosen sub-task type is kept", flags: []string{"--issue-type", "Sub-bug"}, want: "Sub-bug"},
+		{name: "chosen issue type must be a sub-task type", flags: []string{"--issue-type", "Bug"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			saved, savedType, savedProject := config, issueType, projectKey
+			t.Cleanup(func() { config, issueType, projectKey = saved, savedType, savedProject })
+			config = Config{}
+			config.Jira.URL = server.URL
+			config.Cache.Dir = t.TempDir()
+			projectKey = ""
+
+			cmd := &cobra.Command{}
+			cmd.Flags().StringVarP(&issueType, "issue-type", "t", "Bug", "")
+			if err := cmd.Flags().Parse(tt.flags); err != nil {
+				t.Fatal(err)
+			}
+			jiraClient, err := newJiraClient()
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			err = resolveParent(cmd, jiraClient, "PRJ-100")
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("resolveParent() chose %s, want an error", issueType)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if issueType != tt.want || projectKey != "PRJ" {
+				t.Errorf("resolveParent() chose %s in %s, want %s in PRJ", issueType, projectKey, tt.want)
+			}
+		})
+	}
+}
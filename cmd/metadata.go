@@ -0,0 +1,220 @@
+// Copyright © 2017 Adam Kunicki <kunickiaj@gmail.com>
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package cmd
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+
+	jira "github.com/andygrunwald/go-jira"
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/viper"
+
+	"github.com/kunickiaj/beer/pkg/metadata"
+)
+
+// detectMetadata applies the auto rules, or the default rules if none are configured, to the
+// files changed on the current branch and the URL of the upstream remote. Before anything has
+// been changed on the branch, the files tracked in the repository are used instead.
+func detectMetadata(repo *git.Repository) (*metadata.Result, error) {
+	rules := config.Auto
+	if len(rules.Files) == 0 && len(rules.Repos) == 0 {
+		rules = metadata.DefaultRules
+	}
+
+	files, err := changedFiles(repo)
+	if err != nil {
+		log.WithError(err).Debug("Unable to list the files changed on the branch")
+	}
+	if len(files) == 0 {
+		if files, err = metadata.TrackedFiles(repo); err != nil {
+			return nil, fmt.Errorf("unable to list files for automatic metadata: %w", err)
+		}
+	}
+
+	var url string
+	if upstream, err := resolveRemote(repo, upstreamRemoteName()); err == nil {
+		url = upstream.URL
+	} else {
+		log.WithError(err).Warn("Skipping repository rules for automatic metadata")
+	}
+
+	result, err := rules.Detect(files, url)
+	if err != nil {
+		return nil, fmt.Errorf("%w: auto: %w", ErrUsage, err)
+	}
+	log.WithFields(log.Fields{"project": result.Project, "components": result.Components, "labels": result.Labels}).Debug("Detected metadata")
+	return result, nil
+}
+
+// changedFiles returns the paths of the files changed by the commits on the current branch. Files
+// changed on the target branch and merged into the branch aren't included.
+func changedFiles(repo *git.Repository) ([]string, error) {
+	branch := viper.GetString("defaults.branch")
+	commits, err := branchCommits(repo, branch)
+	if err != nil || len(commits) == 0 {
+		return nil, err
+	}
+
+	// commits may come from git log, so read them again to get at their trees
+	head, err := repo.CommitObject(commits[0].Hash)
+	if err != nil {
+		return nil, err
+	}
+	headTree, err := head.Tree()
+	if err != nil {
+		return nil, err
+	}
+	base, err := branchBase(repo, head.Hash, branch)
+	if err != nil {
+		return nil, err
+	}
+	baseTree, err := base.Tree()
+	if err != nil {
+		return nil, err
+	}
+
+	changes, err := object.DiffTree(baseTree, headTree)
+	if err != nil {
+		return nil, err
+	}
+	var files []string
+	for _, c := range changes {
+		if c.To.Name != "" {
+			files = append(files, c.To.Name)
+		} else {
+			files = append(files, c.From.Name)
+		}
+	}
+	return files, nil
+}
+
+// knownComponents drops the detected components that the project doesn't have, since JIRA
+// refuses to create issues with unknown components.
+func knownComponents(jiraClient *jira.Client, project string, names []string) ([]string, error) {
+	if len(names) == 0 {
+		return nil, nil
+	}
+	allowed, err := projectComponents(jiraClient, project)
+	if err != nil {
+		return nil, err
+	}
+
+	var known []string
+	for _, name := range names {
+		if value, err := allowedValue(allowed, name); err == nil {
+			known = append(known, value)
+		} else {
+			log.WithFields(log.Fields{"component": name, "project": project}).Info("Ignoring detected component that the project doesn't have")
+		}
+	}
+	return known, nil
+}
+
+// addDetectedMetadata adds the detected components and labels to a new issue.
+func addDetectedMetadata(jiraClient *jira.Client, project string, issue *jira.Issue, detected *metadata.Result) error {
+	components, err := knownComponents(jiraClient, project, detected.Components)
+	if err != nil {
+		return err
+	}
+	for _, c := range components {
+		if !hasComponent(issue, c) {
+			issue.Fields.Components = append(issue.Fields.Components, &jira.Component{Name: c})
+		}
+	}
+	for _, l := range detected.Labels {
+		if !metadata.Contains(issue.Fields.Labels, l) {
+			issue.Fields.Labels = append(issue.Fields.Labels, l)
+		}
+	}
+	return nil
+}
+
+// proposeMetadata offers to add the detected components and labels an existing issue is
+// missing. They are only reported when beer can't ask.
+func proposeMetadata(jiraClient *jira.Client, issue *jira.Issue, detected *metadata.Result, dryRun bool) error {
+	components, err := knownComponents(jiraClient, issue.Fields.Project.Key, detected.Components)
+	if err != nil {
+		return err
+	}
+	missing := &metadata.Result{}
+	for _, c := range components {
+		if !hasComponent(issue, c) {
+			missing.Components = append(missing.Components, c)
+		}
+	}
+	for _, l := range detected.Labels {
+		if !metadata.Contains(issue.Fields.Labels, l) {
+			missing.Labels = append(missing.Labels, l)
+		}
+	}
+	if missing.IsEmpty() {
+		return nil
+	}
+
+	log.WithFields(log.Fields{"issue": issue.Key, "components": missing.Components, "labels": missing.Labels}).Info("Detected metadata missing from the issue")
+	if dryRun || !isInteractive() {
+		return nil
+	}
+	ok, err := confirm(bufio.NewReader(os.Stdin), os.Stderr, fmt.Sprintf("Add them to %s?", issue.Key), true)
+	if err != nil || !ok {
+		return err
+	}
+
+	if err := addIssueMetadata(jiraClient, issue, missing); err != nil {
+		return err
+	}
+	log.WithField("issue", issue.Key).Info("Added detected metadata")
+	return nil
+}
+
+// addIssueMetadata adds components and labels to an existing issue. Only the fields with values
+// to add are updated, as JIRA rejects updates of fields missing from the issue's edit screen.
+func addIssueMetadata(jiraClient *jira.Client, issue *jira.Issue, add *metadata.Result) error {
+	fields := map[string]interface{}{}
+	if len(add.Components) > 0 {
+		var ops []map[string]interface{}
+		for _, c := range add.Components {
+			ops = append(ops, map[string]interface{}{"add": map[string]string{"name": c}})
+		}
+		fields["components"] = ops
+	}
+	if len(add.Labels) > 0 {
+		var ops []map[string]interface{}
+		for _, l := range add.Labels {
+			ops = append(ops, map[string]interface{}{"add": l})
+		}
+		fields["labels"] = ops
+	}
+	res, err := jiraClient.Issue.UpdateIssue(issue.ID, map[string]interface{}{"update": fields})
+	if err != nil {
+		return jiraError(res, err, fmt.Sprintf("unable to update the metadata of %s", issue.Key))
+	}
+	return nil
+}
+
+func hasComponent(issue *jira.Issue, name string) bool {
+	for _, c := range issue.Fields.Components {
+		if c != nil && strings.EqualFold(c.Name, name) {
+			return true
+		}
+	}
+	return false
+}
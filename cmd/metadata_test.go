@@ -0,0 +1,119 @@
+// Copyright © 2017 Adam Kunicki <kunickiaj@gmail.com>
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package cmd
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	jira "github.com/andygrunwald/go-jira"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/spf13/viper"
+
+	"github.com/kunickiaj/beer/pkg/metadata"
+)
+
+func TestChangedFilesWithMergedTargetBranch(t *testing.T) {
+	for _, backend := range backends(t) {
+		t.Run(backend, func(t *testing.T) {
+			r := newTestRepo(t)
+			config.Git.Backend = backend
+			viper.Set("defaults.branch", plumbing.Main.Short())
+			t.Cleanup(func() { viper.Set("defaults.branch", nil) })
+
+			r.write("README.md", "readme")
+			r.commit("PRJ-7. r1")
+			r.checkout("PRJ-1", true)
+			r.commit("PRJ-1. Seed")
+			r.write("infra/main.tf", "terraform")
+			w := r.commit("PRJ-1. w1")
+			r.checkout(plumbing.Main.Short(), false)
+			r.write("docs/guide.md", "docs")
+			r2 := r.commit("PRJ-8. r2")
+			r.checkout("PRJ-1", false)
+			// the merge brings in docs/guide.md from main
+			r.write("docs/guide.md", "docs")
+			r.commit("Merge branch 'main' into PRJ-1", w, r2)
+			r.write("infra/vars.tf", "variables")
+			r.commit("PRJ-1. w2")
+
+			files, err := changedFiles(r.repo)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got, want := strings.Join(files, " "), "infra/main.tf infra/vars.tf"; got != want {
+				t.Errorf("changedFiles() = %s, want %s", got, want)
+			}
+		})
+	}
+}
+
+func TestAddIssueMetadata(t *testing.T) {
+	tests := []struct {
+		name string
+		add  metadata.Result
+		want string
+	}{
+		{
+			name: "components and labels",
+			add:  metadata.Result{Components: []string{"Infra"}, Labels: []string{"terraform", "ops"}},
+			want: `{"update":{"components":[{"add":{"name":"Infra"}}],"labels":[{"add":"terraform"},{"add":"ops"}]}}`,
+		},
+		{
+			name: "only components",
+			add:  metadata.Result{Components: []string{"Infra"}},
+			want: `{"update":{"components":[{"add":{"name":"Infra"}}]}}`,
+		},
+		{
+			name: "only labels",
+			add:  metadata.Result{Labels: []string{"terraform"}},
+			want: `{"update":{"labels":[{"add":"terraform"}]}}`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var body string
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+				if req.Method != http.MethodPut || req.URL.Path != "/rest/api/2/issue/10001" {
+					http.NotFound(w, req)
+					return
+				}
+				data, _ := io.ReadAll(req.Body)
+				body = strings.TrimSpace(string(data))
+				w.WriteHeader(http.StatusNoContent)
+			}))
+			t.Cleanup(server.Close)
+			saved := config
+			t.Cleanup(func() { config = saved })
+			config = Config{}
+			config.Jira.URL = server.URL
+			jiraClient, err := newJiraClient()
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if err := addIssueMetadata(jiraClient, &jira.Issue{ID: "10001", Key: "PRJ-1"}, &tt.add); err != nil {
+				t.Fatal(err)
+			}
+			if body != tt.want {
+				t.Errorf("update request = %s, want %s", body, tt.want)
+			}
+		})
+	}
+}
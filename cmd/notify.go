@@ -0,0 +1,86 @@
+// Copyright © 2017 Adam Kunicki <kunickiaj@gmail.com>
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package cmd
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	jira "github.com/andygrunwald/go-jira"
+	log "github.com/sirupsen/logrus"
+
+	"github.com/kunickiaj/beer/pkg/review"
+)
+
+// notifyReview records a review published by taste on its JIRA issue, either as a comment or,
+// with jira.notify.remoteLinks, as a remote link that is updated on every new patchset.
+func notifyReview(jiraClient *jira.Client, key string, result *review.Result, isWIP bool) error {
+	if result == nil || result.URL == "" {
+		log.WithField("issue", key).Debug("No review URL to record in JIRA")
+		return nil
+	}
+
+	title := "Review"
+	if result.Number > 0 {
+		title = fmt.Sprintf("%s %d", reviewName(), result.Number)
+	}
+	var details []string
+	if result.Patchset > 0 {
+		details = append(details, "patchset "+strconv.Itoa(result.Patchset))
+	}
+	if isWIP {
+		details = append(details, "work in progress")
+	}
+
+	if config.Jira.Notify.RemoteLinks {
+		link := &jira.RemoteLink{
+			// JIRA updates the existing link with the same global ID instead of adding another
+			GlobalID:     result.URL,
+			Relationship: "review",
+			Application:  &jira.RemoteLinkApplication{Name: string(config.ReviewTool.Normalize())},
+			Object: &jira.RemoteLinkObject{
+				URL:     result.URL,
+				Title:   title,
+				Summary: strings.Join(details, ", "),
+			},
+		}
+		_, res, err := jiraClient.Issue.AddRemoteLink(key, link)
+		if err != nil {
+			return jiraError(res, err, fmt.Sprintf("unable to link %s to %s", key, result.URL))
+		}
+		log.WithFields(log.Fields{"issue": key, "url": result.URL}).Info("Linked review")
+		return nil
+	}
+
+	text := fmt.Sprintf("Published [%s](%s)", title, result.URL)
+	if len(details) > 0 {
+		text += " (" + strings.Join(details, ", ") + ")"
+	}
+	if _, res, err := addComment(jiraClient, key, text); err != nil {
+		return jiraError(res, err, fmt.Sprintf("unable to comment on %s", key))
+	}
+	log.WithFields(log.Fields{"issue": key, "url": result.URL}).Info("Commented on issue")
+	return nil
+}
+
+// reviewName is what the configured review tool calls a review.
+func reviewName() string {
+	if config.ReviewTool.Normalize() == GitHub {
+		return "Pull request"
+	}
+	return "Gerrit change"
+}
@@ -0,0 +1,107 @@
+// Copyright © 2017 Adam Kunicki <kunickiaj@gmail.com>
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/viper"
+	"go.yaml.in/yaml/v3"
+)
+
+// OutputFormat controls how command results are written to stdout.
+type OutputFormat string
+
+const (
+	OutputText OutputFormat = "text"
+	OutputJSON OutputFormat = "json"
+	OutputYAML OutputFormat = "yaml"
+)
+
+// result is implemented by anything a command reports on success.
+type result interface {
+	// message is the summary logged in text mode.
+	message() string
+	// fields are the details logged in text mode.
+	fields() log.Fields
+}
+
+// textWriter is implemented by results that need more than a single log line in text mode,
+// e.g. tables.
+type textWriter interface {
+	writeText(w io.Writer) error
+}
+
+// errorResult is written in place of a result when a command fails.
+type errorResult struct {
+	Error errorDetail `json:"error" yaml:"error"`
+}
+
+type errorDetail struct {
+	Code    int    `json:"code" yaml:"code"`
+	Message string `json:"message" yaml:"message"`
+}
+
+func (e errorResult) message() string {
+	return "Fatal Error"
+}
+
+func (e errorResult) fields() log.Fields {
+	return log.Fields{"code": e.Error.Code, "error": e.Error.Message}
+}
+
+func outputFormat() OutputFormat {
+	return OutputFormat(strings.ToLower(viper.GetString("output")))
+}
+
+func validateOutputFormat() error {
+	switch outputFormat() {
+	case OutputText, OutputJSON, OutputYAML:
+		return nil
+	}
+	return fmt.Errorf("%w: unsupported output format %q, expected one of text, json or yaml", ErrUsage, outputFormat())
+}
+
+// writeResult writes a command result to w in the configured output format. Text output
+// goes through the logger so it reads like the rest of beer's messages.
+func writeResult(w io.Writer, r result) error {
+	switch outputFormat() {
+	case OutputJSON:
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(r)
+	case OutputYAML:
+		enc := yaml.NewEncoder(w)
+		defer func() { _ = enc.Close() }()
+		return enc.Encode(r)
+	case OutputText:
+		fallthrough
+	default:
+		if t, ok := r.(textWriter); ok {
+			return t.writeText(w)
+		}
+		if e, ok := r.(errorResult); ok {
+			log.WithFields(e.fields()).Error(e.message())
+			return nil
+		}
+		log.WithFields(r.fields()).Info(r.message())
+		return nil
+	}
+}
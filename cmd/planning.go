@@ -0,0 +1,132 @@
+// Copyright © 2017 Adam Kunicki <kunickiaj@gmail.com>
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package cmd
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	jira "github.com/andygrunwald/go-jira"
+	log "github.com/sirupsen/logrus"
+
+	"github.com/kunickiaj/beer/pkg/review"
+)
+
+const (
+	activeSprint = "active"
+	nextSprint   = "next"
+)
+
+// planningFieldValues adds the --fix-version, --affects-version and --priority flags to the
+// field values of a new issue, keyed by field ID. They are validated against createmeta like any
+// other field.
+func planningFieldValues(values map[string]string, fields []metaField) error {
+	planning := map[string]string{}
+	if len(fixVersions) > 0 {
+		planning["fixVersions"] = strings.Join(fixVersions, ",")
+	}
+	if len(affectsVersions) > 0 {
+		planning["versions"] = strings.Join(affectsVersions, ",")
+	}
+	if priority != "" {
+		planning["priority"] = priority
+	}
+	return setFieldValues(values, fields, planning, false)
+}
+
+// projectBoard returns the ID of the agile board used for a project's sprints. It is read from
+// jira.boards or discovered when the project has exactly one scrum board.
+func projectBoard(jiraClient *jira.Client, project string) (int, error) {
+	// viper lower cases map keys
+	if id, ok := config.Jira.Boards[strings.ToLower(project)]; ok {
+		return id, nil
+	}
+
+	boards, err := cached("boards/"+project, config.Cache.TTL.Components, func() ([]jira.Board, error) {
+		list, res, err := jiraClient.Board.GetAllBoards(&jira.BoardListOptions{ProjectKeyOrID: project, BoardType: "scrum"})
+		if err != nil {
+			return nil, jiraError(res, err, fmt.Sprintf("unable to list boards of %s", project))
+		}
+		return list.Values, nil
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	switch len(boards) {
+	case 0:
+		return 0, fmt.Errorf("%w: project %s has no scrum board", review.ErrNotFound, project)
+	case 1:
+		return boards[0].ID, nil
+	}
+
+	var names []string
+	for _, b := range boards {
+		names = append(names, fmt.Sprintf("%d (%s)", b.ID, b.Name))
+	}
+	return 0, fmt.Errorf("%w: project %s has several boards %v, configure one under jira.boards.%s", ErrUsage, project, names, project)
+}
+
+// resolveSprint finds the sprint named by --sprint: the active sprint, the next future sprint or
+// an active or future sprint with the given name.
+func resolveSprint(jiraClient *jira.Client, project string, name string) (*jira.Sprint, error) {
+	board, err := projectBoard(jiraClient, project)
+	if err != nil {
+		return nil, err
+	}
+
+	list, res, err := jiraClient.Board.GetAllSprintsWithOptions(board, &jira.GetAllSprintsOptions{State: "active,future"})
+	if err != nil {
+		return nil, jiraError(res, err, fmt.Sprintf("unable to list sprints of board %d", board))
+	}
+	sprints := list.Values
+
+	var future []jira.Sprint
+	var names []string
+	for i, s := range sprints {
+		switch {
+		case strings.EqualFold(name, activeSprint) && s.State == "active":
+			return &sprints[i], nil
+		case strings.EqualFold(name, s.Name):
+			return &sprints[i], nil
+		case s.State == "future":
+			future = append(future, s)
+		}
+		names = append(names, s.Name)
+	}
+
+	if strings.EqualFold(name, nextSprint) && len(future) > 0 {
+		sort.SliceStable(future, func(i, j int) bool {
+			if future[i].StartDate != nil && future[j].StartDate != nil {
+				return future[i].StartDate.Before(*future[j].StartDate)
+			}
+			return future[i].ID < future[j].ID
+		})
+		return &future[0], nil
+	}
+	return nil, fmt.Errorf("%w: no %s sprint on board %d, open sprints are %q", review.ErrNotFound, name, board, names)
+}
+
+// moveToSprint adds an issue to a sprint.
+func moveToSprint(jiraClient *jira.Client, key string, sprint *jira.Sprint) error {
+	res, err := jiraClient.Sprint.MoveIssuesToSprint(sprint.ID, []string{key})
+	if err != nil {
+		return jiraError(res, err, fmt.Sprintf("unable to add %s to sprint %s", key, sprint.Name))
+	}
+	log.WithFields(log.Fields{"issue": key, "sprint": sprint.Name}).Info("Added issue to sprint")
+	return nil
+}
@@ -0,0 +1,190 @@
+// Copyright © 2017 Adam Kunicki <kunickiaj@gmail.com>
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	jira "github.com/andygrunwald/go-jira"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+// defaultQuery is used by pour when neither --jql nor a saved query name is given.
+const defaultQuery = "assignee = currentUser() AND resolution = Unresolved AND sprint in openSprints() ORDER BY priority DESC, updated DESC"
+
+var pourCmd = &cobra.Command{
+	Use:   "pour [QUERY_NAME]",
+	Short: "List your JIRA issues and pick one to brew.",
+	Long: `Runs a JQL query and lists the matching issues so one can be picked to brew.
+
+By default the query finds unresolved issues assigned to you in the current sprint.
+Named queries can be saved in the config file under jira.queries and selected by name,
+e.g. 'beer pour bugs'. A query named 'default' replaces the built-in default.
+
+When run interactively, type text to filter the list and a number to select an issue.
+The selected issue is then brewed exactly as 'beer brew ISSUE_ID' would.`,
+	RunE: pour,
+	Args: usageArgs(cobra.MaximumNArgs(1)),
+}
+
+var jql string
+var maxResults int
+var listOnly bool
+
+func init() {
+	RootCmd.AddCommand(pourCmd)
+
+	pourCmd.Flags().StringVar(&jql, "jql", "", "JQL query to run instead of a saved or default query")
+	pourCmd.Flags().IntVarP(&maxResults, "max-results", "n", 50, "Maximum number of issues to list")
+	pourCmd.Flags().BoolVar(&listOnly, "list", false, "Only list matching issues, don't pick one to brew")
+}
+
+func pour(cmd *cobra.Command, args []string) error {
+	query, err := resolveQuery(args)
+	if err != nil {
+		return err
+	}
+	log.WithField("jql", query).Debug("Searching for issues")
+
+	jiraClient, err := newJiraClient()
+	if err != nil {
+		return err
+	}
+
+	issues, err := searchIssues(jiraClient, query, maxResults, []string{"summary", "status", "priority"})
+	if err != nil {
+		return err
+	}
+
+	result := pourResult{Issues: make([]issueSummary, len(issues))}
+	for i := range issues {
+		result.Issues[i] = summarizeIssue(&issues[i])
+	}
+
+	if listOnly || !isInteractive() {
+		return writeResult(cmd.OutOrStdout(), result)
+	}
+
+	if len(issues) == 0 {
+		log.WithField("jql", query).Info("No issues found")
+		return nil
+	}
+
+	idx, err := pick(os.Stdin, os.Stderr, "Select an issue to brew", result.lines())
+	if err != nil {
+		return err
+	}
+
+	return brew(cmd, []string{result.Issues[idx].Key})
+}
+
+// resolveQuery picks the JQL to run: --jql, then a saved query named by the argument, then the
+// saved 'default' query and finally the built-in default.
+func resolveQuery(args []string) (string, error) {
+	if jql != "" {
+		return jql, nil
+	}
+
+	if len(args) > 0 {
+		// viper lower cases map keys
+		if q, ok := config.Jira.Queries[strings.ToLower(args[0])]; ok {
+			return q, nil
+		}
+		return "", fmt.Errorf("%w: no saved query named %q, available queries are %v", ErrUsage, args[0], savedQueryNames())
+	}
+
+	if q, ok := config.Jira.Queries["default"]; ok {
+		return q, nil
+	}
+	return defaultQuery, nil
+}
+
+func savedQueryNames() []string {
+	names := make([]string, 0, len(config.Jira.Queries))
+	for name := range config.Jira.Queries {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// issueSummary is the subset of issue details listed by pour.
+type issueSummary struct {
+	Key      string `json:"key" yaml:"key"`
+	Status   string `json:"status" yaml:"status"`
+	Priority string `json:"priority" yaml:"priority"`
+	Summary  string `json:"summary" yaml:"summary"`
+	URL      string `json:"url" yaml:"url"`
+}
+
+func summarizeIssue(issue *jira.Issue) issueSummary {
+	s := issueSummary{Key: issue.Key, URL: issueURL(issue.Key)}
+	if issue.Fields == nil {
+		return s
+	}
+	s.Summary = issue.Fields.Summary
+	if issue.Fields.Status != nil {
+		s.Status = issue.Fields.Status.Name
+	}
+	if issue.Fields.Priority != nil {
+		s.Priority = issue.Fields.Priority.Name
+	}
+	return s
+}
+
+// pourResult lists the issues matching a query.
+type pourResult struct {
+	Issues []issueSummary `json:"issues" yaml:"issues"`
+}
+
+func (r pourResult) message() string {
+	return "Found issues"
+}
+
+func (r pourResult) fields() log.Fields {
+	return log.Fields{"count": len(r.Issues)}
+}
+
+// writeText prints the issues as a table.
+func (r pourResult) writeText(w io.Writer) error {
+	for _, line := range r.lines() {
+		if _, err := fmt.Fprintln(w, line); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// lines formats each issue as a row with aligned columns.
+func (r pourResult) lines() []string {
+	if len(r.Issues) == 0 {
+		return nil
+	}
+
+	var sb strings.Builder
+	tw := tabwriter.NewWriter(&sb, 0, 4, 2, ' ', 0)
+	for _, issue := range r.Issues {
+		_, _ = fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n", issue.Key, issue.Status, issue.Priority, issue.Summary)
+	}
+	_ = tw.Flush()
+	return strings.Split(strings.TrimSuffix(sb.String(), "\n"), "\n")
+}
@@ -0,0 +1,251 @@
+// Copyright © 2017 Adam Kunicki <kunickiaj@gmail.com>
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/go-git/go-git/v5/plumbing/storer"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/viper"
+	"go.yaml.in/yaml/v3"
+
+	"github.com/kunickiaj/beer/pkg/metadata"
+)
+
+// repoConfigFile is the per repository configuration in the root of the working tree
+const repoConfigFile = ".beer.yaml"
+
+var (
+	// branchKeyPattern finds an issue key in a branch name such as PRJ-12 or feature/PRJ_2-12-fix
+	branchKeyPattern = regexp.MustCompile(`(?:^|/)([A-Z][A-Z0-9_]*)-[0-9]+(?:[^0-9]|$)`)
+	// trailerKeyPattern finds issue keys in trailers such as "Jira: PRJ-12"
+	trailerKeyPattern = regexp.MustCompile(`(?im)^(?:jira|issue|refs?|fixes|closes|resolves):\s*([A-Za-z][A-Za-z0-9_]*)-[0-9]+\b`)
+	// mergeKeyPattern finds issue keys anywhere in the subject of a merge commit, e.g.
+	// "Merge pull request #4 from alice/PRJ-12"
+	mergeKeyPattern = regexp.MustCompile(`\b([A-Z][A-Z0-9_]*)-[0-9]+\b`)
+)
+
+func init() {
+	viper.SetDefault("defaults.historyDepth", 20)
+}
+
+// repoConfig is read from .beer.yaml in the root of the repository.
+type repoConfig struct {
+	Project string `yaml:"project"` // Project for new issues created from this repository
+}
+
+// projectCandidate is a project key found while inferring the project of a new issue.
+type projectCandidate struct {
+	Key     string
+	Source  string
+	Commits int // Number of commits mentioning the project, when found in the history
+}
+
+func (c projectCandidate) String() string {
+	switch c.Commits {
+	case 0:
+		return c.Key
+	case 1:
+		return c.Key + " (1 commit)"
+	}
+	return fmt.Sprintf("%s (%d commits)", c.Key, c.Commits)
+}
+
+// inferProjectKey determines the project of a new issue when --project isn't given, from the
+// repository's .beer.yaml, the remote URL rules under auto.repos, the branch name and finally
+// the issue keys in the recent history. When the history mentions several projects, the user
+// picks one or they are listed in the error.
+func inferProjectKey(repo *git.Repository) (string, error) {
+	candidates, err := projectCandidates(repo)
+	if err != nil {
+		return "", err
+	}
+
+	switch {
+	case len(candidates) == 0:
+		return "", fmt.Errorf("%w: wasn't able to infer a project key, specify one with --project or set project in %s", ErrUsage, repoConfigFile)
+	case len(candidates) == 1:
+		log.WithFields(log.Fields{"project_key": candidates[0].Key, "source": candidates[0].Source}).Info("Inferred project key; override with --project if incorrect")
+		return candidates[0].Key, nil
+	}
+
+	options := make([]string, len(candidates))
+	for i, c := range candidates {
+		options[i] = c.String()
+	}
+	if !isInteractive() {
+		return "", fmt.Errorf("%w: recent commits mention several projects: %s, specify one with --project", ErrUsage, strings.Join(options, ", "))
+	}
+	idx, err := pick(os.Stdin, os.Stderr, "Recent commits mention several projects, pick one for the new issue", options)
+	if err != nil {
+		return "", err
+	}
+	return candidates[idx].Key, nil
+}
+
+// projectCandidates returns the project keys found by the first source that finds any. Only the
+// history can find several, which are ordered by how many commits mention them.
+func projectCandidates(repo *git.Repository) ([]projectCandidate, error) {
+	sources := []struct {
+		name  string
+		infer func(*git.Repository) (string, error)
+	}{
+		{repoConfigFile, repoConfigProject},
+		{"remote URL", remoteProject},
+		{"branch name", branchProject},
+	}
+	for _, s := range sources {
+		key, err := s.infer(repo)
+		if err != nil {
+			return nil, err
+		}
+		if key != "" {
+			return []projectCandidate{{Key: strings.ToUpper(key), Source: s.name}}, nil
+		}
+	}
+	return historyProjects(repo, viper.GetInt("defaults.historyDepth"))
+}
+
+// repoConfigProject returns the project set in the repository's .beer.yaml.
+func repoConfigProject(repo *git.Repository) (string, error) {
+	workTree, err := repo.Worktree()
+	if err != nil {
+		return "", nil
+	}
+	data, err := os.ReadFile(filepath.Join(workTree.Filesystem.Root(), repoConfigFile))
+	if errors.Is(err, os.ErrNotExist) {
+		return "", nil
+	} else if err != nil {
+		return "", err
+	}
+
+	var rc repoConfig
+	if err := yaml.Unmarshal(data, &rc); err != nil {
+		return "", fmt.Errorf("%w: invalid %s: %w", ErrUsage, repoConfigFile, err)
+	}
+	return rc.Project, nil
+}
+
+// remoteProject returns the project the auto.repos rules map the upstream remote's URL to.
+func remoteProject(repo *git.Repository) (string, error) {
+	if len(config.Auto.Repos) == 0 {
+		return "", nil
+	}
+	upstream, err := resolveRemote(repo, upstreamRemoteName())
+	if err != nil {
+		log.WithError(err).Debug("Not inferring the project from the remote URL")
+		return "", nil
+	}
+	result, err := metadata.Rules{Repos: config.Auto.Repos}.Detect(nil, upstream.URL)
+	if err != nil {
+		return "", fmt.Errorf("%w: auto: %w", ErrUsage, err)
+	}
+	return result.Project, nil
+}
+
+// branchProject returns the project of the issue key in the current branch's name.
+func branchProject(repo *git.Repository) (string, error) {
+	head, err := repo.Head()
+	if err != nil || !head.Name().IsBranch() {
+		return "", nil
+	}
+	if match := branchKeyPattern.FindStringSubmatch(head.Name().Short()); match != nil {
+		return match[1], nil
+	}
+	return "", nil
+}
+
+// historyProjects counts the projects of the issue keys in the last depth commits on HEAD,
+// including merged commits. Keys are taken from the start of the subject, from anywhere in the
+// subject of merge commits and from trailers like "Jira: PRJ-12".
+func historyProjects(repo *git.Repository, depth int) ([]projectCandidate, error) {
+	head, err := repo.Head()
+	if err != nil {
+		// a repository without commits has no history to search
+		return nil, nil
+	}
+	commits, err := repo.Log(&git.LogOptions{From: head.Hash()})
+	if err != nil {
+		return nil, fmt.Errorf("couldn't get git log from %s: %w", head.Hash(), err)
+	}
+	defer commits.Close()
+
+	counts := map[string]int{}
+	var order []string
+	seen := 0
+	err = commits.ForEach(func(c *object.Commit) error {
+		if seen >= depth {
+			return storer.ErrStop
+		}
+		seen++
+		for _, key := range commitProjects(c) {
+			if counts[key] == 0 {
+				order = append(order, key)
+			}
+			counts[key]++
+		}
+		return nil
+	})
+	if err != nil && !errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("unable to search the history for project keys: %w", err)
+	}
+
+	candidates := make([]projectCandidate, len(order))
+	for i, key := range order {
+		candidates[i] = projectCandidate{Key: key, Source: "history", Commits: counts[key]}
+	}
+	// the most mentioned first, the most recent first among equals
+	sort.SliceStable(candidates, func(i, j int) bool { return candidates[i].Commits > candidates[j].Commits })
+	return candidates, nil
+}
+
+// commitProjects returns the distinct projects a commit message mentions.
+func commitProjects(c *object.Commit) []string {
+	subject := firstLine(c.Message)
+	var keys []string
+	add := func(key string) {
+		key = strings.ToUpper(key)
+		for _, k := range keys {
+			if k == key {
+				return
+			}
+		}
+		keys = append(keys, key)
+	}
+
+	if match := commitKeyPattern.FindStringSubmatch(subject); match != nil {
+		add(strings.SplitN(match[1], "-", 2)[0])
+	}
+	if c.NumParents() > 1 {
+		for _, match := range mergeKeyPattern.FindAllStringSubmatch(subject, -1) {
+			add(match[1])
+		}
+	}
+	for _, match := range trailerKeyPattern.FindAllStringSubmatch(c.Message, -1) {
+		add(match[1])
+	}
+	return keys
+}
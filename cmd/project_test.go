@@ -0,0 +1,189 @@
+// Copyright © 2017 Adam Kunicki <kunickiaj@gmail.com>
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package cmd
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	gitconfig "github.com/go-git/go-git/v5/config"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/spf13/viper"
+
+	"github.com/kunickiaj/beer/pkg/metadata"
+)
+
+func TestProjectCandidates(t *testing.T) {
+	tests := []struct {
+		name       string
+		repoConfig string // contents of .beer.yaml, if any
+		remoteRule bool   // whether an auto.repos rule maps the remote to RUL
+		branch     string // checked out branch, main if empty
+		want       []projectCandidate
+	}{
+		{
+			name:       ".beer.yaml first",
+			repoConfig: "project: cfg\n",
+			remoteRule: true,
+			branch:     "BR-1",
+			want:       []projectCandidate{{Key: "CFG", Source: repoConfigFile}},
+		},
+		{
+			name:       "remote rule before the branch",
+			repoConfig: "# no project\n",
+			remoteRule: true,
+			branch:     "BR-1",
+			want:       []projectCandidate{{Key: "RUL", Source: "remote URL"}},
+		},
+		{
+			name:   "branch before the history",
+			branch: "feature/AB_2-12-fix",
+			want:   []projectCandidate{{Key: "AB_2", Source: "branch name"}},
+		},
+		{
+			name:   "branch without an issue key",
+			branch: "feature/login",
+			want:   []projectCandidate{{Key: "PRJ", Source: "history", Commits: 2}, {Key: "OPS", Source: "history", Commits: 1}},
+		},
+		{
+			name: "history",
+			want: []projectCandidate{{Key: "PRJ", Source: "history", Commits: 2}, {Key: "OPS", Source: "history", Commits: 1}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := newTestRepo(t)
+			r.commit("PRJ-1. First")
+			r.commit("OPS-2. Second")
+			r.commit("PRJ-3. Third")
+			if tt.repoConfig != "" {
+				r.write(repoConfigFile, tt.repoConfig)
+				r.commit("Configure beer")
+			}
+			if _, err := r.repo.CreateRemote(&gitconfig.RemoteConfig{Name: "origin", URLs: []string{"git@github.com:acme/widgets.git"}}); err != nil {
+				t.Fatal(err)
+			}
+			if tt.remoteRule {
+				config.Auto.Repos = []metadata.RepoRule{
+					{Pattern: `github\.com[:/]other/`, Project: "OTH"},
+					{Pattern: `github\.com[:/]acme/`, Project: "RUL"},
+				}
+			}
+			if tt.branch != "" {
+				r.checkout(tt.branch, true)
+			}
+
+			got, err := projectCandidates(r.repo)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("projectCandidates() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCommitProjects(t *testing.T) {
+	merge := []plumbing.Hash{plumbing.NewHash("1"), plumbing.NewHash("2")}
+	tests := []struct {
+		name    string
+		message string
+		parents []plumbing.Hash
+		want    []string
+	}{
+		{name: "key at the start of the subject", message: "PRJ-12. Fix login", want: []string{"PRJ"}},
+		{name: "key with underscore and digits", message: "AB_2-12 Fix login", want: []string{"AB_2"}},
+		{name: "lower case key", message: "prj-12: fix login", want: []string{"PRJ"}},
+		{name: "key elsewhere in the subject", message: "Fix login for PRJ-12", want: nil},
+		{name: "trailers", message: "Fix login\n\nJira: OPS-3\nRefs: prj-4\nFixes: OPS-5\n", want: []string{"OPS", "PRJ"}},
+		{name: "subject and trailer of the same project", message: "PRJ-1. Fix\n\nIssue: PRJ-2\n", want: []string{"PRJ"}},
+		{name: "trailer-like text in the body", message: "Fix\n\nSee jira PRJ-2 for details\n", want: nil},
+		{name: "merge subject", message: "Merge pull request #4 from alice/PRJ-12-login", parents: merge, want: []string{"PRJ"}},
+		{name: "merge subject with several keys", message: "Merge branch 'OPS-1' into PRJ-2", parents: merge, want: []string{"OPS", "PRJ"}},
+		{name: "keys in a subject that isn't a merge", message: "Merge branch 'OPS-1' into PRJ-2", want: nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := commitProjects(&object.Commit{Message: tt.message, ParentHashes: tt.parents})
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("commitProjects() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestHistoryProjects(t *testing.T) {
+	r := newTestRepo(t)
+	// oldest first: only the last depth commits count
+	for _, message := range []string{"OLD-1. a", "OLD-2. b", "OLD-3. c", "PRJ-1. d", "OPS-1. e", "Fix typo", "OPS-2. f", "PRJ-2. g", "PRJ-3. h"} {
+		r.commit(message)
+	}
+
+	tests := []struct {
+		depth int
+		want  []projectCandidate
+	}{
+		{depth: 0, want: []projectCandidate{}},
+		{depth: 1, want: []projectCandidate{{Key: "PRJ", Source: "history", Commits: 1}}},
+		{depth: 3, want: []projectCandidate{{Key: "PRJ", Source: "history", Commits: 2}, {Key: "OPS", Source: "history", Commits: 1}}},
+		// equal counts are ordered by the most recent mention
+		{depth: 5, want: []projectCandidate{{Key: "PRJ", Source: "history", Commits: 2}, {Key: "OPS", Source: "history", Commits: 2}}},
+		{depth: 20, want: []projectCandidate{{Key: "PRJ", Source: "history", Commits: 3}, {Key: "OLD", Source: "history", Commits: 3}, {Key: "OPS", Source: "history", Commits: 2}}},
+	}
+	for _, tt := range tests {
+		got, err := historyProjects(r.repo, tt.depth)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("historyProjects(%d) = %+v, want %+v", tt.depth, got, tt.want)
+		}
+	}
+
+	viper.Set("defaults.historyDepth", 3)
+	t.Cleanup(func() { viper.Set("defaults.historyDepth", nil) })
+	got, err := projectCandidates(r.repo)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 2 || got[0].Commits != 2 || got[1].Commits != 1 {
+		t.Errorf("projectCandidates() = %+v, want the projects of the last defaults.historyDepth commits", got)
+	}
+}
+
+func TestInferProjectKey(t *testing.T) {
+	r := newTestRepo(t)
+	if _, err := inferProjectKey(r.repo); !errors.Is(err, ErrUsage) {
+		t.Errorf("inferProjectKey() without any project = %v, want a usage error", err)
+	}
+
+	r.commit("PRJ-1. First")
+	if key, err := inferProjectKey(r.repo); err != nil || key != "PRJ" {
+		t.Errorf("inferProjectKey() = %s, %v, want PRJ", key, err)
+	}
+
+	// without a terminal several projects can't be picked from, so they are listed
+	r.commit("OPS-1. Second")
+	r.commit("PRJ-2. Third")
+	_, err := inferProjectKey(r.repo)
+	if !errors.Is(err, ErrUsage) || !strings.Contains(err.Error(), "PRJ (2 commits), OPS (1 commit)") || !strings.Contains(err.Error(), "--project") {
+		t.Errorf("inferProjectKey() with several projects = %v, want them listed with a hint to use --project", err)
+	}
+}
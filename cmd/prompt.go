@@ -0,0 +1,144 @@
+// Copyright © 2017 Adam Kunicki <kunickiaj@gmail.com>
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package cmd
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	"golang.org/x/term"
+)
+
+// errCancelled is returned when the user aborts an interactive prompt.
+var errCancelled = errors.New("selection cancelled")
+
+// isInteractive reports whether beer can prompt the user, i.e. stdin is a terminal and
+// results aren't being written in a machine-readable format.
+func isInteractive() bool {
+	return term.IsTerminal(int(os.Stdin.Fd())) && outputFormat() == OutputText
+}
+
+// pick interactively selects one of options and returns its index. Entering text narrows the
+// list with a fuzzy match, entering a number selects that entry and an empty line selects the
+// only remaining entry.
+func pick(in io.Reader, out io.Writer, title string, options []string) (int, error) {
+	if len(options) == 0 {
+		return -1, errors.New("nothing to choose from")
+	}
+
+	reader := bufio.NewReader(in)
+	filter := ""
+	for {
+		matches := fuzzyFilter(options, filter)
+		_, _ = fmt.Fprintf(out, "\n%s\n", title)
+		for i, idx := range matches {
+			_, _ = fmt.Fprintf(out, "%3d) %s\n", i+1, options[idx])
+		}
+		if len(matches) == 0 {
+			_, _ = fmt.Fprintf(out, "  no matches for %q\n", filter)
+		}
+		_, _ = fmt.Fprint(out, "Number, text to filter, or empty to clear filter: ")
+
+		line, err := reader.ReadString('\n')
+		if err != nil && (err != io.EOF || line == "") {
+			return -1, errCancelled
+		}
+		line = strings.TrimSpace(line)
+
+		if n, err := strconv.Atoi(line); err == nil {
+			if n >= 1 && n <= len(matches) {
+				return matches[n-1], nil
+			}
+			_, _ = fmt.Fprintf(out, "%d is not a valid choice\n", n)
+			continue
+		}
+
+		if line == "" {
+			if len(matches) == 1 {
+				return matches[0], nil
+			}
+			filter = ""
+			continue
+		}
+		filter = line
+	}
+}
+
+// prompt asks a free form question and returns the trimmed answer, or def if the answer is empty.
+func prompt(in *bufio.Reader, out io.Writer, question string, def string) (string, error) {
+	if def != "" {
+		_, _ = fmt.Fprintf(out, "%s [%s]: ", question, def)
+	} else {
+		_, _ = fmt.Fprintf(out, "%s: ", question)
+	}
+
+	line, err := in.ReadString('\n')
+	if err != nil && (err != io.EOF || line == "") {
+		return "", errCancelled
+	}
+	line = strings.TrimSpace(line)
+	if line == "" {
+		return def, nil
+	}
+	return line, nil
+}
+
+// confirm asks a yes or no question and returns def if the answer is empty.
+func confirm(in *bufio.Reader, out io.Writer, question string, def bool) (bool, error) {
+	hint := "y/N"
+	if def {
+		hint = "Y/n"
+	}
+	for {
+		_, _ = fmt.Fprintf(out, "%s [%s]: ", question, hint)
+		line, err := in.ReadString('\n')
+		if err != nil && (err != io.EOF || line == "") {
+			return false, errCancelled
+		}
+		switch strings.ToLower(strings.TrimSpace(line)) {
+		case "":
+			return def, nil
+		case "y", "yes":
+			return true, nil
+		case "n", "no":
+			return false, nil
+		}
+	}
+}
+
+// fuzzyFilter returns the indices of options containing the characters of filter in order,
+// ignoring case.
+func fuzzyFilter(options []string, filter string) []int {
+	var matches []int
+	needle := []rune(strings.ToLower(filter))
+	for i, option := range options {
+		n := 0
+		for _, r := range strings.ToLower(option) {
+			if n < len(needle) && r == needle[n] {
+				n++
+			}
+		}
+		if n == len(needle) {
+			matches = append(matches, i)
+		}
+	}
+	return matches
+}
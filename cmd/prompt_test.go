@@ -0,0 +1,111 @@
+// Copyright © 2017 Adam Kunicki <kunickiaj@gmail.com>
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package cmd
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestFuzzyFilter(t *testing.T) {
+	options := []string{"PRJ-1 Fix login", "PRJ-2 Add logout", "OPS-3 Rotate keys"}
+	tests := []struct {
+		filter string
+		want   []int
+	}{
+		{filter: "", want: []int{0, 1, 2}},
+		{filter: "login", want: []int{0}},
+		{filter: "LOG", want: []int{0, 1}},
+		{filter: "prj2", want: []int{1}},
+		{filter: "ops", want: []int{2}},
+		{filter: "keysx", want: nil},
+		{filter: "nigol", want: nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.filter, func(t *testing.T) {
+			if got := fuzzyFilter(options, tt.filter); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("fuzzyFilter(%q) = %v, want %v", tt.filter, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPick(t *testing.T) {
+	options := []string{"Bug", "Story", "Sub-task", "Task"}
+	tests := []struct {
+		name    string
+		input   string
+		want    int
+		wantOut string // printed along the way
+		wantErr error
+	}{
+		{name: "number", input: "2\n", want: 1},
+		{name: "number without newline", input: "4", want: 3},
+		{name: "filter to one then empty line", input: "stor\n\n", want: 1},
+		{name: "number within filtered list", input: "task\n2\n", want: 3, wantOut: "  2) Task\n"},
+		{name: "out of range number", input: "9\n1\n", want: 0, wantOut: "9 is not a valid choice"},
+		{name: "no matches then clear filter", input: "xyz\n\n3\n", want: 2, wantOut: "no matches for \"xyz\""},
+		{name: "empty line with several matches clears", input: "\n1\n", want: 0},
+		{name: "end of input", input: "sto", wantErr: errCancelled},
+		{name: "no input", input: "", wantErr: errCancelled},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var out strings.Builder
+			got, err := pick(strings.NewReader(tt.input), &out, "Issue type", options)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Errorf("pick() = %d, %v, want %v", got, err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("pick() = %d (%s), want %d (%s)", got, options[got], tt.want, options[tt.want])
+			}
+			if !strings.Contains(out.String(), tt.wantOut) {
+				t.Errorf("pick() printed %q, want it to contain %q", out.String(), tt.wantOut)
+			}
+		})
+	}
+
+	if _, err := pick(strings.NewReader("1\n"), io.Discard, "Issue type", nil); err == nil {
+		t.Error("pick() of no options succeeded")
+	}
+}
+
+func TestPromptAndConfirm(t *testing.T) {
+	in := bufio.NewReader(strings.NewReader("\n  PRJ  \n\nmaybe\nYES\nn\n"))
+	for _, want := range []string{"OPS", "PRJ"} {
+		if got, err := prompt(in, io.Discard, "Project key", "OPS"); err != nil || got != want {
+			t.Errorf("prompt() = %q, %v, want %q", got, err, want)
+		}
+	}
+	for _, want := range []bool{true, true, false} {
+		if got, err := confirm(in, io.Discard, "Continue?", true); err != nil || got != want {
+			t.Errorf("confirm() = %t, %v, want %t", got, err, want)
+		}
+	}
+	if _, err := confirm(in, io.Discard, "Continue?", true); !errors.Is(err, errCancelled) {
+		t.Errorf("confirm() at the end of input = %v, want errCancelled", err)
+	}
+}
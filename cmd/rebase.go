@@ -0,0 +1,267 @@
+// Copyright © 2017 Adam Kunicki <kunickiaj@gmail.com>
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	jira "github.com/andygrunwald/go-jira"
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+
+	"github.com/kunickiaj/beer/pkg/gitbackend"
+	"github.com/kunickiaj/beer/pkg/remote"
+	"github.com/kunickiaj/beer/pkg/review"
+)
+
+const (
+	rebased   = "rebased"
+	upToDate  = "up to date"
+	conflict  = "conflict"
+	tasteFail = "rebased, taste failed"
+)
+
+var rebaseCmd = &cobra.Command{
+	Use:   "rebase",
+	Short: "Rebase the current work branch onto the latest target branch.",
+	Long: `Fetches the target branch from the upstream remote and rebases the current work branch onto
+it, so the review can be merged. The target branch is defaults.branch unless --branch is given.
+The upstream remote is defaults.upstream, or the --remote reviews are pushed to.
+
+If the rebase stops on a conflict, resolve it and run 'git rebase --continue', or give up with
+'git rebase --abort'. With --taste the rebased branch is published again with 'beer taste',
+for review on the same target branch.
+
+--all rebases every local branch named after an issue that is still open. Branches that conflict
+are left as they were and reported. The branch that was checked out is checked out again when
+--all finishes or stops on an error. The rebase uses the git command line tool.`,
+	RunE: rebase,
+	Args: usageArgs(cobra.ExactArgs(0)),
+}
+
+var rebaseAll bool
+var retaste bool
+
+func init() {
+	RootCmd.AddCommand(rebaseCmd)
+
+	rebaseCmd.Flags().BoolVar(&rebaseAll, "all", false, "Rebase every local branch whose issue is still open")
+	rebaseCmd.Flags().BoolVar(&retaste, "taste", false, "Publish rebased branches for review again")
+	rebaseCmd.Flags().String("branch", "", "Target branch to rebase onto. Defaults to defaults.branch")
+}
+
+func rebase(cmd *cobra.Command, args []string) error {
+	dryRun, _ := cmd.Flags().GetBool("dry-run")
+
+	repo, err := openRepo()
+	if err != nil {
+		return err
+	}
+	if operation, err := operationInProgress(); err != nil {
+		return err
+	} else if operation != "" {
+		return fmt.Errorf("%w: a %s is in progress, finish or abort it first", review.ErrConflict, operation)
+	}
+	if changes, err := runGit("status", "--porcelain", "--untracked-files=no"); err != nil {
+		return err
+	} else if changes != "" {
+		return fmt.Errorf("%w: there are uncommitted changes, commit or stash them first", ErrUsage)
+	}
+
+	head, err := repo.Head()
+	if err != nil {
+		return fmt.Errorf("unable to resolve HEAD: %w", err)
+	}
+	if !head.Name().IsBranch() {
+		return fmt.Errorf("%w: HEAD is detached, check out a work branch first", ErrUsage)
+	}
+	current := head.Name().Short()
+
+	branches := []string{current}
+	if rebaseAll {
+		if branches, err = openIssueBranches(repo); err != nil {
+			return err
+		}
+	}
+
+	target := targetBranch(cmd)
+	if dryRun {
+		log.WithFields(log.Fields{"onto": upstreamRemoteName() + "/" + target, "branches": branches}).Info("Dry Run")
+		return nil
+	}
+
+	if err := fetchBranch(repo, target); err != nil {
+		return err
+	}
+
+	result := rebaseResult{Onto: upstreamRemoteName() + "/" + target, Branches: map[string]string{}}
+	for _, b := range branches {
+		status, err := rebaseBranch(b, result.Onto)
+		// with --all, conflicting branches are reported instead of stopping
+		if err != nil && (!rebaseAll || !errors.Is(err, review.ErrConflict)) {
+			return returnToBranch(current, err)
+		}
+
+		if status == rebased && retaste {
+			// taste publishes the branch against rebase's --branch target
+			if err := taste(cmd, nil); err != nil {
+				if !rebaseAll {
+					return err
+				}
+				log.WithError(err).WithField("branch", b).Warn("Unable to publish rebased branch")
+				status = tasteFail
+			}
+		}
+		result.Branches[b] = status
+	}
+
+	if rebaseAll {
+		if _, err := runGit("checkout", "--quiet", current); err != nil {
+			return err
+		}
+	}
+	return writeResult(cmd.OutOrStdout(), result)
+}
+
+// returnToBranch checks out branch again when --all stops on an error after moving on to other
+// branches, and returns err.
+func returnToBranch(branch string, err error) error {
+	if !rebaseAll {
+		return err
+	}
+	if _, checkoutErr := runGit("checkout", "--quiet", branch); checkoutErr != nil {
+		log.WithError(checkoutErr).WithField("branch", branch).Warn("Unable to check out the original branch again")
+	}
+	return err
+}
+
+// fetchBranch updates the upstream remote's copy of the target branch.
+func fetchBranch(repo *git.Repository, branch string) error {
+	upstream, err := resolveRemote(repo, upstreamRemoteName())
+	if err != nil {
+		return err
+	}
+	backend, err := gitBackend(repo)
+	if err != nil {
+		return err
+	}
+	auth, err := remoteAuth(backend, upstream.URL)
+	if err != nil {
+		return err
+	}
+
+	refspec := fmt.Sprintf("+%s:%s", plumbing.NewBranchReferenceName(branch), plumbing.NewRemoteReferenceName(upstream.Name, branch))
+	err = backend.Fetch(gitbackend.FetchOptions{
+		Remote:   upstream.Name,
+		URL:      upstream.URL,
+		RefSpecs: []string{refspec},
+		Auth:     auth,
+	})
+	if err != nil {
+		return fmt.Errorf("unable to fetch %s: %w", branch, remote.Hint(review.ClassifyGitError(err), upstream.URL))
+	}
+	return nil
+}
+
+// rebaseBranch rebases branch onto upstream. In --all mode a conflicting rebase is aborted,
+// otherwise it is left stopped so the conflict can be resolved.
+func rebaseBranch(branch string, upstream string) (string, error) {
+	before, err := runGit("rev-parse", branch)
+	if err != nil {
+		return "", err
+	}
+
+	if _, err := runGit("rebase", "--quiet", upstream, branch); err != nil {
+		if operation, _ := operationInProgress(); operation != "rebase" {
+			return "", err
+		}
+		if rebaseAll {
+			log.WithField("branch", branch).Warn("Rebase conflicts, leaving the branch unchanged")
+			if _, err := runGit("rebase", "--abort"); err != nil {
+				return "", err
+			}
+			return conflict, fmt.Errorf("%w: rebasing %s", review.ErrConflict, branch)
+		}
+		return conflict, fmt.Errorf("%w: rebasing %s onto %s stopped on a conflict. Resolve it, 'git add' the files and run 'git rebase --continue', or run 'git rebase --abort' to give up",
+			review.ErrConflict, branch, upstream)
+	}
+
+	after, err := runGit("rev-parse", branch)
+	if err != nil {
+		return "", err
+	}
+	if before == after {
+		return upToDate, nil
+	}
+	log.WithField("branch", branch).Info("Rebased branch")
+	return rebased, nil
+}
+
+// openIssueBranches returns the local branches named after an issue that isn't done yet.
+func openIssueBranches(repo *git.Repository) ([]string, error) {
+	branches, err := issueBranches(repo)
+	if err != nil || len(branches) == 0 {
+		return nil, err
+	}
+
+	jiraClient, err := newJiraClient()
+	if err != nil {
+		return nil, err
+	}
+
+	var open []string
+	for _, ref := range branches {
+		name := ref.Name().Short()
+		key := strings.ToUpper(name)
+		issue, res, err := jiraClient.Issue.Get(key, &jira.GetQueryOptions{Fields: "status"})
+		if err != nil {
+			err = jiraError(res, err, fmt.Sprintf("unable to fetch %s", key))
+			if errors.Is(err, review.ErrNotFound) {
+				log.WithField("branch", name).Warn("Skipping branch, its issue doesn't exist")
+				continue
+			}
+			return nil, err
+		}
+		if status := issue.Fields.Status; status != nil && status.StatusCategory.Key == jira.StatusCategoryComplete {
+			log.WithFields(log.Fields{"branch": name, "status": status.Name}).Debug("Skipping branch of finished issue")
+			continue
+		}
+		open = append(open, name)
+	}
+	return open, nil
+}
+
+// rebaseResult describes the branches rebased by rebase.
+type rebaseResult struct {
+	Onto     string            `json:"onto" yaml:"onto"`
+	Branches map[string]string `json:"branches" yaml:"branches"` // Status keyed by branch
+}
+
+func (r rebaseResult) message() string {
+	return "Rebased onto " + r.Onto
+}
+
+func (r rebaseResult) fields() log.Fields {
+	f := log.Fields{}
+	for b, status := range r.Branches {
+		f[b] = status
+	}
+	return f
+}
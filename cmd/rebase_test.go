@@ -0,0 +1,78 @@
+// Copyright © 2017 Adam Kunicki <kunickiaj@gmail.com>
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package cmd
+
+import (
+	"bytes"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	gitconfig "github.com/go-git/go-git/v5/config"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/spf13/viper"
+
+	"github.com/kunickiaj/beer/pkg/gitbackend"
+)
+
+func TestRebaseAllReturnsToBranchOnError(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not found")
+	}
+	r := newTestRepo(t)
+	r.commit("r1")
+	r.checkout("PRJ-1", true)
+	r.commit("PRJ-1. w1")
+	r.checkout(plumbing.Main.Short(), false)
+	r.checkout("PRJ-2", true)
+	r.commit("PRJ-2. w1")
+	r.checkout(plumbing.Main.Short(), false)
+	r.commit("r2")
+	r.checkout("PRJ-2", false)
+
+	// the repository is its own upstream, and a hook makes rebasing PRJ-2 fail without a conflict
+	if _, err := r.repo.CreateRemote(&gitconfig.RemoteConfig{Name: "origin", URLs: []string{r.dir}}); err != nil {
+		t.Fatal(err)
+	}
+	hook := filepath.Join(r.dir, ".git", "hooks", "pre-rebase")
+	if err := os.MkdirAll(filepath.Dir(hook), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(hook, []byte("#!/bin/sh\n[ \"$2\" != PRJ-2 ]\n"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	config.Git.Backend = gitbackend.ExecName
+	newJiraServer(t, map[string]string{"PRJ-1": "indeterminate", "PRJ-2": "indeterminate"})
+	viper.Set("defaults.branch", plumbing.Main.Short())
+	t.Cleanup(func() { viper.Set("defaults.branch", nil) })
+	rebaseAll = true
+	t.Cleanup(func() { rebaseAll = false })
+	t.Chdir(r.dir)
+
+	rebaseCmd.SetOut(&bytes.Buffer{})
+	if err := rebase(rebaseCmd, nil); err == nil {
+		t.Fatal("rebase() succeeded, want the hook's error")
+	}
+	head, err := r.repo.Head()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if head.Name().Short() != "PRJ-2" {
+		t.Errorf("rebase() left %s checked out, want PRJ-2", head.Name().Short())
+	}
+}
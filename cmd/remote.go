@@ -0,0 +1,93 @@
+// Copyright © 2017 Adam Kunicki <kunickiaj@gmail.com>
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing/transport"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/viper"
+
+	"github.com/kunickiaj/beer/pkg/gitbackend"
+	"github.com/kunickiaj/beer/pkg/remote"
+	"github.com/kunickiaj/beer/pkg/review"
+)
+
+// pushRemoteName returns the remote reviews are pushed to, --remote or defaults.remote.
+func pushRemoteName() string {
+	return viper.GetString("defaults.remote")
+}
+
+// upstreamRemoteName returns the remote the target branch is fetched from. It differs from the
+// push remote in fork workflows, where reviews are pushed to a fork of the upstream repository.
+func upstreamRemoteName() string {
+	return firstNonEmpty(viper.GetString("defaults.upstream"), pushRemoteName())
+}
+
+// resolveRemote looks up a remote, applying pushurl and insteadOf rewriting from the git
+// configuration.
+func resolveRemote(repo *git.Repository, name string) (*remote.Remote, error) {
+	cfg, err := gitConfig(repo)
+	if err != nil {
+		return nil, err
+	}
+	r, err := remote.Resolve(cfg, name)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w, set defaults.remote or --remote to one of your remotes", review.ErrNotFound, err)
+	}
+	log.WithFields(log.Fields{"remote": r.Name, "url": r.URL, "pushURL": r.PushURL}).Debug("Resolved remote")
+	return r, nil
+}
+
+// remoteAuth returns the credentials for a remote URL as configured under git. The exec backend
+// needs none since git finds its own, which may involve prompting.
+func remoteAuth(backend gitbackend.Backend, url string) (transport.AuthMethod, error) {
+	if _, ok := backend.(*gitbackend.Exec); ok {
+		return nil, nil
+	}
+	auth, err := remote.Auth(url, remote.AuthOptions{
+		SSHKey:   config.Git.SSHKey,
+		Username: config.Git.Username,
+		Token:    firstNonEmpty(config.Git.Token, os.Getenv("BEER_GIT_TOKEN")),
+		Passphrase: func(key string) ([]byte, error) {
+			return passphrase("SSH key " + key)()
+		},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", review.ErrAuthentication, err)
+	}
+	return auth, nil
+}
+
+// reviewTarget returns where taste pushes reviews.
+func reviewTarget(repo *git.Repository) (review.Target, error) {
+	r, err := resolveRemote(repo, pushRemoteName())
+	if err != nil {
+		return review.Target{}, err
+	}
+	backend, err := gitBackend(repo)
+	if err != nil {
+		return review.Target{}, err
+	}
+	auth, err := remoteAuth(backend, r.PushURL)
+	if err != nil {
+		return review.Target{}, err
+	}
+	return review.Target{Remote: r.Name, URL: r.PushURL, Auth: auth, Git: backend, NoVerify: noVerify}, nil
+}
@@ -0,0 +1,206 @@
+// Copyright © 2017 Adam Kunicki <kunickiaj@gmail.com>
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/go-git/go-git/v5/storage/filesystem"
+	"github.com/pkg/errors"
+
+	"github.com/kunickiaj/beer/pkg/gitconfig"
+	"github.com/kunickiaj/beer/pkg/review"
+)
+
+// openRepo opens the git repository containing the current working directory.
+func openRepo() (*git.Repository, error) {
+	cwd, err := os.Getwd()
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to determine working directory")
+	}
+
+	backend, err := openBackend()
+	if err != nil {
+		return nil, err
+	}
+	repo, err := backend.Open(cwd)
+	if err != nil {
+		return nil, fmt.Errorf("%w: unable to open git repository: %w", review.ErrNotFound, err)
+	}
+	return repo, nil
+}
+
+// currentIssueKey returns the issue key of the checked out work branch.
+func currentIssueKey(repo *git.Repository) (string, error) {
+	head, err := repo.Head()
+	if err != nil {
+		return "", errors.Wrap(err, "unable to determine the current branch")
+	}
+	branch := head.Name().Short()
+	if !head.Name().IsBranch() || !issueKeyPattern.MatchString(branch) {
+		return "", fmt.Errorf("%w: current branch %s is not named after an issue, check out a branch created by brew", ErrUsage, branch)
+	}
+	return strings.ToUpper(branch), nil
+}
+
+// targetCommit returns the tip of the branch reviews are merged into, preferring the remote
+// tracking branch since the local one is often stale.
+func targetCommit(repo *git.Repository, branch string) (*object.Commit, error) {
+	for _, name := range []plumbing.ReferenceName{plumbing.NewRemoteReferenceName(upstreamRemoteName(), branch), plumbing.NewBranchReferenceName(branch)} {
+		ref, err := repo.Reference(name, true)
+		if err != nil {
+			continue
+		}
+		return repo.CommitObject(ref.Hash())
+	}
+	return nil, fmt.Errorf("%w: target branch %s not found, set defaults.branch or --branch", review.ErrNotFound, branch)
+}
+
+// branchCommits returns the commits on HEAD that aren't on the target branch, newest first. Only
+// first parents are followed and everything reachable from the target branch is excluded, so
+// commits brought in by merging the target branch are skipped.
+func branchCommits(repo *git.Repository, branch string) ([]*object.Commit, error) {
+	head, err := repo.Head()
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to resolve HEAD")
+	}
+	target, err := targetCommit(repo, branch)
+	if err != nil {
+		return nil, err
+	}
+	backend, err := gitBackend(repo)
+	if err != nil {
+		return nil, err
+	}
+
+	commits, err := backend.Log(head.Hash(), target.Hash)
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to find where the branch diverged from "+branch)
+	}
+	return commits, nil
+}
+
+// branchBase returns the commit of the target branch that the branch ending at tip builds on:
+// where it diverged from the target branch or, when the target branch was merged into it since,
+// the last commit merged.
+func branchBase(repo *git.Repository, tip plumbing.Hash, branch string) (*object.Commit, error) {
+	target, err := targetCommit(repo, branch)
+	if err != nil {
+		return nil, err
+	}
+	head, err := repo.CommitObject(tip)
+	if err != nil {
+		return nil, err
+	}
+	bases, err := head.MergeBase(target)
+	if err != nil {
+		return nil, err
+	}
+	if len(bases) != 1 {
+		return nil, fmt.Errorf("%w: unable to tell where the branch diverged from %s, rebase the branch first", review.ErrConflict, branch)
+	}
+	return bases[0], nil
+}
+
+// gitDir returns the git directory of a repository, or an empty string for in-memory repositories.
+func gitDir(repo *git.Repository) string {
+	if storage, ok := repo.Storer.(*filesystem.Storage); ok {
+		return storage.Filesystem().Root()
+	}
+	return ""
+}
+
+// gitConfig returns the effective git configuration of a repository, read the way git does.
+func gitConfig(repo *git.Repository) (*gitconfig.Config, error) {
+	return gitconfig.Load(gitDir(repo))
+}
+
+// commitIdentity returns the author and committer for new commits, resolved like git does from
+// the GIT_AUTHOR_* and GIT_COMMITTER_* environment variables, then author.*, committer.* and
+// user.* in the repository's git configuration.
+func commitIdentity(repo *git.Repository) (author *object.Signature, committer *object.Signature, err error) {
+	cfg, err := gitConfig(repo)
+	if err != nil {
+		return nil, nil, err
+	}
+	if author, err = identity(cfg, "author"); err != nil {
+		return nil, nil, err
+	}
+	if committer, err = identity(cfg, "committer"); err != nil {
+		return nil, nil, err
+	}
+	return author, committer, nil
+}
+
+// identity resolves the author or committer signature.
+func identity(cfg *gitconfig.Config, role string) (*object.Signature, error) {
+	env := "GIT_" + strings.ToUpper(role) + "_"
+	sig := &object.Signature{
+		Name:  firstNonEmpty(os.Getenv(env+"NAME"), cfg.Get(role+".name"), cfg.Get("user.name")),
+		Email: firstNonEmpty(os.Getenv(env+"EMAIL"), cfg.Get(role+".email"), cfg.Get("user.email"), os.Getenv("EMAIL")),
+		When:  time.Now(),
+	}
+	if sig.Name == "" || sig.Email == "" {
+		return nil, fmt.Errorf("%w: unable to determine the %s identity, set it with 'git config --global user.name \"Your Name\"' and 'git config --global user.email you@example.com'", ErrUsage, role)
+	}
+
+	if date := os.Getenv(env + "DATE"); date != "" {
+		when, err := parseGitDate(date)
+		if err != nil {
+			return nil, fmt.Errorf("%w: invalid %sDATE: %w", ErrUsage, env, err)
+		}
+		sig.When = when
+	}
+	return sig, nil
+}
+
+// parseGitDate parses the date formats git accepts in GIT_AUTHOR_DATE and GIT_COMMITTER_DATE:
+// its internal "<unix timestamp> <offset>" format, RFC 2822 and ISO 8601.
+func parseGitDate(date string) (time.Time, error) {
+	date = strings.TrimSpace(date)
+	if seconds, offset, ok := strings.Cut(strings.TrimPrefix(date, "@"), " "); ok || strings.HasPrefix(date, "@") {
+		if unix, err := strconv.ParseInt(seconds, 10, 64); err == nil {
+			t := time.Unix(unix, 0)
+			if zone, err := time.Parse("-0700", offset); err == nil {
+				t = t.In(zone.Location())
+			}
+			return t, nil
+		}
+	}
+	for _, layout := range []string{time.RFC1123Z, time.RFC3339, "2006-01-02 15:04:05 -0700", "2006-01-02T15:04:05"} {
+		// like git, a date without a time zone is local time
+		if t, err := time.ParseInLocation(layout, date, time.Local); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("unrecognized date %q", date)
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
@@ -0,0 +1,209 @@
+// Copyright © 2017 Adam Kunicki <kunickiaj@gmail.com>
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package cmd
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+
+	"github.com/kunickiaj/beer/pkg/gitbackend"
+)
+
+// testRepo is a repository in a temporary directory whose commits are created with go-git.
+type testRepo struct {
+	t    *testing.T
+	dir  string
+	repo *git.Repository
+	when time.Time
+}
+
+// newTestRepo creates an empty repository on main, isolated from the user's git configuration.
+// The configuration of beer is reset for the test.
+func newTestRepo(t *testing.T) *testRepo {
+	t.Helper()
+	global := filepath.Join(t.TempDir(), "gitconfig")
+	if err := os.WriteFile(global, []byte("[user]\n\tname = Test\n\temail = test@example.com\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("GIT_CONFIG_GLOBAL", global)
+	t.Setenv("GIT_CONFIG_NOSYSTEM", "1")
+
+	saved := config
+	config = Config{}
+	t.Cleanup(func() { config = saved })
+
+	dir := t.TempDir()
+	repo, err := git.PlainInitWithOptions(dir, &git.PlainInitOptions{InitOptions: git.InitOptions{DefaultBranch: plumbing.Main}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &testRepo{t: t, dir: dir, repo: repo, when: time.Date(2024, 1, 1, 9, 0, 0, 0, time.UTC)}
+}
+
+// commit creates an empty commit on the current branch. With parents, the first one must be HEAD.
+func (r *testRepo) commit(message string, parents ...plumbing.Hash) plumbing.Hash {
+	r.t.Helper()
+	workTree, err := r.repo.Worktree()
+	if err != nil {
+		r.t.Fatal(err)
+	}
+	r.when = r.when.Add(time.Minute)
+	sig := &object.Signature{Name: "Test", Email: "test@example.com", When: r.when}
+	hash, err := workTree.Commit(message, &git.CommitOptions{Author: sig, Committer: sig, AllowEmptyCommits: true, Parents: parents})
+	if err != nil {
+		r.t.Fatal(err)
+	}
+	return hash
+}
+
+// write creates a file in the working tree and stages it.
+func (r *testRepo) write(name string, content string) {
+	r.t.Helper()
+	path := filepath.Join(r.dir, filepath.FromSlash(name))
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		r.t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		r.t.Fatal(err)
+	}
+	workTree, err := r.repo.Worktree()
+	if err != nil {
+		r.t.Fatal(err)
+	}
+	if _, err := workTree.Add(name); err != nil {
+		r.t.Fatal(err)
+	}
+}
+
+// checkout switches to a branch, creating it from HEAD if create is set.
+func (r *testRepo) checkout(branch string, create bool) {
+	r.t.Helper()
+	workTree, err := r.repo.Worktree()
+	if err != nil {
+		r.t.Fatal(err)
+	}
+	if err := workTree.Checkout(&git.CheckoutOptions{Branch: plumbing.NewBranchReferenceName(branch), Create: create}); err != nil {
+		r.t.Fatal(err)
+	}
+}
+
+// newMergedWorkBranch creates a work branch PRJ-1 on which main was merged after main moved on:
+//
+//	main:  r1 - r2 - r3
+//	         \         \
+//	PRJ-1:    w1 - w2 - merge - w3
+func newMergedWorkBranch(t *testing.T) *testRepo {
+	r := newTestRepo(t)
+	r.commit("PRJ-7. r1")
+	r.checkout("PRJ-1", true)
+	r.commit("PRJ-1. w1")
+	w2 := r.commit("PRJ-1. w2")
+	r.checkout(plumbing.Main.Short(), false)
+	r.commit("PRJ-8. r2")
+	r3 := r.commit("PRJ-9. r3")
+	r.checkout("PRJ-1", false)
+	r.commit("Merge branch 'main' into PRJ-1", w2, r3)
+	r.commit("PRJ-1. w3")
+	return r
+}
+
+// backends lists the git.backend values whose prerequisites are available.
+func backends(t *testing.T) []string {
+	names := []string{gitbackend.GoGitName}
+	if _, err := exec.LookPath("git"); err == nil {
+		names = append(names, gitbackend.ExecName)
+	} else {
+		t.Log("git not found, skipping the exec backend")
+	}
+	return names
+}
+
+func subjects(commits []*object.Commit) []string {
+	var s []string
+	for _, c := range commits {
+		s = append(s, firstLine(c.Message))
+	}
+	return s
+}
+
+func TestBranchCommitsSkipsMergedTargetBranch(t *testing.T) {
+	for _, backend := range backends(t) {
+		t.Run(backend, func(t *testing.T) {
+			r := newMergedWorkBranch(t)
+			config.Git.Backend = backend
+
+			commits, err := branchCommits(r.repo, plumbing.Main.Short())
+			if err != nil {
+				t.Fatal(err)
+			}
+			want := "PRJ-1. w3,Merge branch 'main' into PRJ-1,PRJ-1. w2,PRJ-1. w1"
+			if got := strings.Join(subjects(commits), ","); got != want {
+				t.Errorf("branchCommits() = %s, want %s", got, want)
+			}
+		})
+	}
+}
+
+func TestParseGitDate(t *testing.T) {
+	utc := time.Date(2024, 1, 1, 9, 0, 0, 0, time.UTC)
+	tests := []struct {
+		date       string
+		want       time.Time
+		wantOffset int  // seconds east of UTC
+		local      bool // in the local time zone instead of at wantOffset
+		wantErr    bool
+	}{
+		{date: "1704099600 +0000", want: utc},
+		{date: "1704099600 +0130", want: utc, wantOffset: 90 * 60},
+		{date: "1704099600 -0500", want: utc, wantOffset: -5 * 3600},
+		{date: "@1704099600", want: utc, local: true},
+		{date: "  1704099600 +0000\n", want: utc},
+		{date: "Mon, 01 Jan 2024 10:00:00 +0100", want: utc, wantOffset: 3600},
+		{date: "2024-01-01T09:00:00Z", want: utc},
+		{date: "2024-01-01T11:00:00+02:00", want: utc, wantOffset: 2 * 3600},
+		{date: "2024-01-01 04:00:00 -0500", want: utc, wantOffset: -5 * 3600},
+		{date: "2024-01-01T09:00:00", want: time.Date(2024, 1, 1, 9, 0, 0, 0, time.Local), local: true},
+		{date: "yesterday", wantErr: true},
+		{date: "", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := parseGitDate(tt.date)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("parseGitDate(%q) = %s, want an error", tt.date, got)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("parseGitDate(%q) = %v", tt.date, err)
+			continue
+		}
+		if tt.local {
+			_, tt.wantOffset = tt.want.In(time.Local).Zone()
+		}
+		if _, offset := got.Zone(); !got.Equal(tt.want) || offset != tt.wantOffset {
+			t.Errorf("parseGitDate(%q) = %s, want %s with offset %d", tt.date, got, tt.want, tt.wantOffset)
+		}
+	}
+}
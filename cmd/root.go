@@ -52,20 +52,36 @@ For example:
 
 	This will branch from the current HEAD and set the branch name to ABC-123 and add
 	an empty commit with the JIRA ID and JIRA summary text as the first line of
-	the commit message.`,
+	the commit message.
+
+Exit codes:
+	0  success
+	1  unclassified error
+	2  invalid flags or arguments
+	3  authentication failure (JIRA or git remote)
+	4  issue, project, repository or remote not found
+	5  conflict, e.g. push rejected by the remote
+	6  network error, server could not be reached
+	7  not implemented by the configured review tool`,
+	SilenceUsage:      true,
+	SilenceErrors:     true,
+	PersistentPreRunE: initConfig,
 }
 
 // Execute adds all child commands to the root command and sets flags appropriately.
 // This is called by main.main(). It only needs to happen once to the rootCmd.
 func Execute() {
 	if err := RootCmd.Execute(); err != nil {
-		log.WithField("error", err).Fatal("Fatal Error")
-		os.Exit(1)
+		code := exitCode(err)
+		_ = writeResult(os.Stdout, errorResult{Error: errorDetail{Code: code, Message: err.Error()}})
+		os.Exit(code)
 	}
 }
 
 func init() {
-	cobra.OnInitialize(initConfig)
+	RootCmd.SetFlagErrorFunc(func(cmd *cobra.Command, err error) error {
+		return fmt.Errorf("%w: %w", ErrUsage, err)
+	})
 
 	RootCmd.PersistentFlags().StringVar(&cfgFile, "config", "", "config file (default is $HOME/.beer.yaml)")
 	RootCmd.PersistentFlags().BoolVar(&debugMode, "debug", false, "Enables debug messages")
@@ -74,17 +90,24 @@ func init() {
 	RootCmd.PersistentFlags().String("jira-username", "", "JIRA username")
 	RootCmd.PersistentFlags().String("jira-password", "", "JIRA password")
 	RootCmd.PersistentFlags().String("gerrit-url", "", "Gerrit SSH URL")
+	RootCmd.PersistentFlags().String("remote", "origin", "Git remote reviews are pushed to")
 	RootCmd.PersistentFlags().String("review-tool", "gerrit", "Tool for publishing reviews, e.g. Gerrit")
+	RootCmd.PersistentFlags().StringP("output", "o", string(OutputText), "Output format for results and errors: text, json or yaml")
 
 	_ = viper.BindPFlag("jira.url", RootCmd.PersistentFlags().Lookup("jira-url"))
 	_ = viper.BindPFlag("jira.username", RootCmd.PersistentFlags().Lookup("jira-username"))
 	_ = viper.BindPFlag("jira.password", RootCmd.PersistentFlags().Lookup("jira-password"))
 	_ = viper.BindPFlag("gerrit.url", RootCmd.PersistentFlags().Lookup("gerrit-url"))
+	_ = viper.BindPFlag("defaults.remote", RootCmd.PersistentFlags().Lookup("remote"))
 	_ = viper.BindPFlag("reviewTool", RootCmd.PersistentFlags().Lookup("review-tool"))
+	_ = viper.BindPFlag("output", RootCmd.PersistentFlags().Lookup("output"))
+
+	viper.SetDefault("jira.apiVersion", 2)
+	viper.SetDefault("jira.markdown", true)
 }
 
 // initConfig reads in config file and ENV variables if set.
-func initConfig() {
+func initConfig(cmd *cobra.Command, args []string) error {
 	// Init log level
 	if debugMode {
 		log.SetLevel(log.DebugLevel)
@@ -97,8 +120,7 @@ func initConfig() {
 		// Find home directory.
 		home, err := homedir.Dir()
 		if err != nil {
-			log.WithField("error", err).Fatal("Unable to find home directory")
-			os.Exit(1)
+			return fmt.Errorf("unable to find home directory: %w", err)
 		}
 
 		// Search config in home directory with name ".beer" (without extension).
@@ -113,6 +135,10 @@ func initConfig() {
 		log.WithField("config", viper.ConfigFileUsed()).Debug("Using config file")
 	}
 
+	if err := validateOutputFormat(); err != nil {
+		return err
+	}
+
 	log.WithField("config_keys", viper.AllKeys()).Debug("Configuration keys")
 
 	if err := viper.Unmarshal(&config); err == nil {
@@ -120,9 +146,12 @@ func initConfig() {
 	}
 
 	// last step, check os keychain for credentials
-	ring, _ := keyring.Open(keyring.Config{
+	ring, err := keyring.Open(keyring.Config{
 		ServiceName: "beer", // ref: https://github.com/99designs/keyring/issues/44
 	})
+	if err != nil {
+		return fmt.Errorf("unable to open keychain: %w", err)
+	}
 
 	// if users have existing config files with a password, let's inform them to migrate their config
 	if len(config.Jira.Password) > 0 {
@@ -139,10 +168,11 @@ func initConfig() {
 		}
 		_ = ring.Set(i)
 	} else if err != nil {
-		log.WithError(err).Fatal("Unable to access keychain")
+		return fmt.Errorf("unable to access keychain: %w", err)
 	}
 
 	config.Jira.Password = string(i.Data)
+	return nil
 }
 
 func credentials() (string, error) {
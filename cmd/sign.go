@@ -0,0 +1,114 @@
+// Copyright © 2017 Adam Kunicki <kunickiaj@gmail.com>
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/mitchellh/go-homedir"
+	log "github.com/sirupsen/logrus"
+	"golang.org/x/term"
+
+	"github.com/kunickiaj/beer/pkg/signing"
+)
+
+// noSign disables signing of the commits beer creates, overriding commit.gpgsign
+var noSign bool
+
+// commitSigner returns the signer for new commits as configured by commit.gpgsign, gpg.format
+// and user.signingkey, or nil when commits aren't signed.
+func commitSigner(repo *git.Repository) (git.Signer, error) {
+	cfg, err := gitConfig(repo)
+	if err != nil {
+		return nil, err
+	}
+	if noSign || !cfg.Bool("commit.gpgsign") {
+		return nil, nil
+	}
+
+	key := cfg.Get("user.signingkey")
+	format := firstNonEmpty(cfg.Get("gpg.format"), "openpgp")
+	log.WithFields(log.Fields{"format": format, "key": key}).Debug("Signing commit")
+
+	switch format {
+	case "openpgp":
+		if key == "" {
+			// like git, sign with the key of the committer's identity when none is configured
+			_, committer, err := commitIdentity(repo)
+			if err != nil {
+				return nil, err
+			}
+			key = fmt.Sprintf("%s <%s>", committer.Name, committer.Email)
+		}
+		return signing.NewGPGSigner(firstNonEmpty(cfg.Get("gpg.openpgp.program"), cfg.Get("gpg.program"), "gpg"), key), nil
+	case "ssh":
+		if key == "" {
+			return nil, fmt.Errorf("%w: commit.gpgsign is set but user.signingkey isn't, set it to your SSH key or pass --no-sign", ErrUsage)
+		}
+		if !strings.HasPrefix(key, "key::") {
+			if key, err = homedir.Expand(key); err != nil {
+				return nil, err
+			}
+		}
+		signer, err := signing.LoadSSHKey(key, passphrase("SSH signing key"))
+		if err != nil {
+			return nil, err
+		}
+		return signing.NewSSHSigner(signer), nil
+	}
+	return nil, fmt.Errorf("%w: gpg.format %s isn't supported, use openpgp or ssh or pass --no-sign", ErrUsage, format)
+}
+
+// passphrase returns a function asking for the passphrase of an encrypted key.
+func passphrase(what string) func() ([]byte, error) {
+	return func() ([]byte, error) {
+		if !isInteractive() {
+			return nil, fmt.Errorf("%w: the %s is encrypted and its passphrase can't be asked for, add the key to your agent", ErrUsage, what)
+		}
+		fmt.Fprintf(os.Stderr, "Enter passphrase for %s: ", what)
+		pass, err := term.ReadPassword(int(os.Stdin.Fd()))
+		fmt.Fprintln(os.Stderr)
+		if err != nil {
+			return nil, errors.New("unable to read passphrase")
+		}
+		return pass, nil
+	}
+}
+
+// signCommit adds a signature to a commit that is built directly rather than through
+// Worktree.Commit.
+func signCommit(signer git.Signer, commit *object.Commit) error {
+	obj := &plumbing.MemoryObject{}
+	if err := commit.EncodeWithoutSignature(obj); err != nil {
+		return err
+	}
+	r, err := obj.Reader()
+	if err != nil {
+		return err
+	}
+	sig, err := signer.Sign(r)
+	if err != nil {
+		return err
+	}
+	commit.PGPSignature = string(sig)
+	return nil
+}
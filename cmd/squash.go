@@ -0,0 +1,304 @@
+// Copyright © 2017 Adam Kunicki <kunickiaj@gmail.com>
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package cmd
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+
+	"github.com/kunickiaj/beer/pkg/review"
+)
+
+// backupRefPrefix is where squash keeps the branch as it was before squashing.
+const backupRefPrefix = "refs/beer/backup/"
+
+var squashCmd = &cobra.Command{
+	Use:   "squash",
+	Short: "Squash the commits on the current branch into the commit created by brew.",
+	Long: `Collapses all commits made since the branch diverged from the target branch into a single
+commit. The squashed commit keeps the message of the first commit, the one brew created with the
+issue key, summary and Change-Id, and the files of the latest commit. The subjects of the other
+commits can be appended to its body. If the target branch was merged into the work branch, the
+squashed commit is placed on top of the last commit merged.
+
+The branch as it was before squashing is kept as refs/beer/backup/<BRANCH>, so
+'beer squash --undo' can restore it.`,
+	RunE: squash,
+	Args: usageArgs(cobra.ExactArgs(0)),
+}
+
+var appendSubjects bool
+var undoSquash bool
+
+// trailerPattern matches git trailers such as Change-Id or Signed-off-by.
+var trailerPattern = regexp.MustCompile(`^[A-Za-z][A-Za-z0-9-]*: `)
+
+func init() {
+	RootCmd.AddCommand(squashCmd)
+
+	squashCmd.Flags().BoolVar(&appendSubjects, "append-subjects", false, "Append the subjects of the squashed commits to the message body. Asked interactively if not given")
+	squashCmd.Flags().BoolVar(&undoSquash, "undo", false, "Restore the branch as it was before the last squash")
+	squashCmd.Flags().BoolVar(&noSign, "no-sign", false, "Don't sign the squashed commit even if commit.gpgsign is set")
+	squashCmd.Flags().String("branch", "", "Target branch the work branch was brewed from. Defaults to defaults.branch")
+}
+
+// targetBranch returns the --branch flag of cmd if given, otherwise defaults.branch.
+func targetBranch(cmd *cobra.Command) string {
+	if f := cmd.Flags().Lookup("branch"); f != nil && f.Changed {
+		return f.Value.String()
+	}
+	return viper.GetString("defaults.branch")
+}
+
+func squash(cmd *cobra.Command, args []string) error {
+	dryRun, _ := cmd.Flags().GetBool("dry-run")
+
+	repo, err := openRepo()
+	if err != nil {
+		return err
+	}
+	head, err := repo.Head()
+	if err != nil {
+		return fmt.Errorf("unable to resolve HEAD: %w", err)
+	}
+	if !head.Name().IsBranch() {
+		return fmt.Errorf("%w: HEAD is detached, check out a work branch first", ErrUsage)
+	}
+	backup := plumbing.ReferenceName(backupRefPrefix + head.Name().Short())
+
+	if undoSquash {
+		return restoreBackup(cmd, repo, head, backup, dryRun)
+	}
+
+	branch := targetBranch(cmd)
+	commits, err := branchCommits(repo, branch)
+	if err != nil {
+		return err
+	}
+	if len(commits) < 2 {
+		log.WithFields(log.Fields{"branch": head.Name().Short(), "commits": len(commits)}).Info("Nothing to squash")
+		return nil
+	}
+
+	// commits are newest first, so the last one is the commit brew created
+	seed, others := commits[len(commits)-1], commits[:len(commits)-1]
+	parent, err := squashParent(repo, seed, commits[0], branch)
+	if err != nil {
+		return err
+	}
+	var subjects []string
+	for i := len(others) - 1; i >= 0; i-- {
+		// merges of the target branch don't describe the work on the branch
+		if others[i].NumParents() > 1 {
+			continue
+		}
+		subject, _, _ := strings.Cut(strings.TrimSpace(others[i].Message), "\n")
+		subjects = append(subjects, subject)
+	}
+
+	if !cmd.Flags().Changed("append-subjects") && isInteractive() {
+		in := bufio.NewReader(os.Stdin)
+		_, _ = fmt.Fprintf(os.Stderr, "Squashing %d commits into %q:\n", len(others), firstLine(seed.Message))
+		for _, s := range subjects {
+			_, _ = fmt.Fprintf(os.Stderr, "  %s\n", s)
+		}
+		if appendSubjects, err = confirm(in, os.Stderr, "Append these subjects to the commit message?", false); err != nil {
+			return err
+		}
+	}
+
+	message := seed.Message
+	if appendSubjects {
+		message = appendToBody(message, subjects)
+	}
+
+	if dryRun {
+		log.WithFields(log.Fields{"branch": head.Name().Short(), "commits": len(commits), "message": message}).Info("Dry Run")
+		return nil
+	}
+
+	tip := commits[0]
+	_, committer, err := commitIdentity(repo)
+	if err != nil {
+		return err
+	}
+	squashed := &object.Commit{
+		Author:       seed.Author,
+		Committer:    *committer,
+		Message:      message,
+		TreeHash:     tip.TreeHash,
+		ParentHashes: []plumbing.Hash{parent},
+	}
+	signer, err := commitSigner(repo)
+	if err != nil {
+		return err
+	}
+	if signer != nil {
+		if err := signCommit(signer, squashed); err != nil {
+			return err
+		}
+	}
+	obj := repo.Storer.NewEncodedObject()
+	if err := squashed.Encode(obj); err != nil {
+		return err
+	}
+	hash, err := repo.Storer.SetEncodedObject(obj)
+	if err != nil {
+		return err
+	}
+
+	if err := repo.Storer.SetReference(plumbing.NewHashReference(backup, head.Hash())); err != nil {
+		return fmt.Errorf("unable to save backup ref %s: %w", backup, err)
+	}
+	// the squashed commit has the same files as HEAD, so only the branch needs to move
+	if err := repo.Storer.SetReference(plumbing.NewHashReference(head.Name(), hash)); err != nil {
+		return err
+	}
+
+	return writeResult(cmd.OutOrStdout(), squashResult{
+		Branch:   head.Name().Short(),
+		Commit:   hash.String(),
+		Squashed: len(commits),
+		Backup:   backup.String(),
+	})
+}
+
+// squashParent returns the parent of the squashed commit: where the branch diverged from the
+// target branch or, when the target branch was merged into it since, the last commit merged. The
+// squashed commit then only contains the changes made on the branch. Squashing is refused unless
+// the first commit of the branch starts on the target branch.
+func squashParent(repo *git.Repository, seed *object.Commit, tip *object.Commit, branch string) (plumbing.Hash, error) {
+	short := seed.Hash.String()[:7]
+	if len(seed.ParentHashes) == 0 {
+		return plumbing.ZeroHash, fmt.Errorf("%w: %s, the first commit of the branch, has no parent on %s to squash onto", ErrUsage, short, branch)
+	}
+	target, err := targetCommit(repo, branch)
+	if err != nil {
+		return plumbing.ZeroHash, err
+	}
+	// commits read by the exec backend can't walk their parents, so they are read again
+	parent, err := repo.CommitObject(seed.ParentHashes[0])
+	if err != nil {
+		return plumbing.ZeroHash, err
+	}
+	if onTarget, err := parent.IsAncestor(target); err != nil {
+		return plumbing.ZeroHash, err
+	} else if !onTarget {
+		return plumbing.ZeroHash, fmt.Errorf("%w: %s, the first commit of the branch, doesn't start on %s, rebase the branch first", ErrUsage, short, branch)
+	}
+
+	base, err := branchBase(repo, tip.Hash, branch)
+	if err != nil {
+		return plumbing.ZeroHash, err
+	}
+	return base.Hash, nil
+}
+
+// restoreBackup moves the branch back to the commit saved by the last squash. This is refused if
+// the files have changed since, as those commits would be lost.
+func restoreBackup(cmd *cobra.Command, repo *git.Repository, head *plumbing.Reference, backup plumbing.ReferenceName, dryRun bool) error {
+	ref, err := repo.Reference(backup, true)
+	if err != nil {
+		return fmt.Errorf("%w: no squash of %s to undo", review.ErrNotFound, head.Name().Short())
+	}
+
+	current, err := repo.CommitObject(head.Hash())
+	if err != nil {
+		return err
+	}
+	previous, err := repo.CommitObject(ref.Hash())
+	if err != nil {
+		return err
+	}
+	if current.TreeHash != previous.TreeHash {
+		return fmt.Errorf("%w: %s has changed since it was squashed, restore it with 'git reset --hard %s' if you are sure", review.ErrConflict, head.Name().Short(), backup)
+	}
+
+	if dryRun {
+		log.WithFields(log.Fields{"branch": head.Name().Short(), "commit": ref.Hash().String()}).Info("Dry Run")
+		return nil
+	}
+
+	if err := repo.Storer.SetReference(plumbing.NewHashReference(head.Name(), ref.Hash())); err != nil {
+		return err
+	}
+	if err := repo.Storer.RemoveReference(backup); err != nil {
+		return err
+	}
+	log.WithFields(log.Fields{"branch": head.Name().Short(), "commit": ref.Hash().String()}).Info("Restored branch")
+	return nil
+}
+
+// appendToBody adds the subjects as a list to the end of the message body, keeping any trailers
+// such as Change-Id last.
+func appendToBody(message string, subjects []string) string {
+	lines := strings.Split(strings.TrimRight(message, "\n"), "\n")
+
+	// the trailers are the last paragraph if every line in it is a trailer
+	start := len(lines)
+	for start > 0 && trailerPattern.MatchString(lines[start-1]) {
+		start--
+	}
+	if start == len(lines) || (start > 0 && lines[start-1] != "") {
+		start = len(lines)
+	}
+	body, trailers := lines[:start], lines[start:]
+	for len(body) > 0 && body[len(body)-1] == "" {
+		body = body[:len(body)-1]
+	}
+
+	var list []string
+	for _, s := range subjects {
+		list = append(list, "- "+s)
+	}
+
+	out := strings.Join(body, "\n") + "\n\n" + strings.Join(list, "\n")
+	if len(trailers) > 0 {
+		out += "\n\n" + strings.Join(trailers, "\n")
+	}
+	return out + "\n"
+}
+
+func firstLine(s string) string {
+	line, _, _ := strings.Cut(strings.TrimSpace(s), "\n")
+	return line
+}
+
+// squashResult describes the commit created by squash.
+type squashResult struct {
+	Branch   string `json:"branch" yaml:"branch"`
+	Commit   string `json:"commit" yaml:"commit"`
+	Squashed int    `json:"squashed" yaml:"squashed"`
+	Backup   string `json:"backup" yaml:"backup"`
+}
+
+func (r squashResult) message() string {
+	return "Squashed commits"
+}
+
+func (r squashResult) fields() log.Fields {
+	return log.Fields{"branch": r.Branch, "commit": r.Commit, "squashed": r.Squashed, "backup": r.Backup}
+}
@@ -0,0 +1,103 @@
+// Copyright © 2017 Adam Kunicki <kunickiaj@gmail.com>
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package cmd
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/spf13/viper"
+)
+
+// squashTestRepo prepares running squash in r on the current branch, onto main.
+func squashTestRepo(t *testing.T, r *testRepo, backend string) {
+	config.Git.Backend = backend
+	viper.Set("defaults.branch", plumbing.Main.Short())
+	t.Cleanup(func() { viper.Set("defaults.branch", nil) })
+	appendSubjects, undoSquash = true, false
+	t.Chdir(r.dir)
+}
+
+func TestSquashWithMergedTargetBranch(t *testing.T) {
+	for _, backend := range backends(t) {
+		t.Run(backend, func(t *testing.T) {
+			r := newMergedWorkBranch(t)
+			squashTestRepo(t, r, backend)
+			main, err := r.repo.Reference(plumbing.NewBranchReferenceName(plumbing.Main.Short()), true)
+			if err != nil {
+				t.Fatal(err)
+			}
+			before, err := r.repo.Head()
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			squashCmd.SetOut(&bytes.Buffer{})
+			if err := squash(squashCmd, nil); err != nil {
+				t.Fatal(err)
+			}
+
+			head, err := r.repo.Head()
+			if err != nil {
+				t.Fatal(err)
+			}
+			squashed, err := r.repo.CommitObject(head.Hash())
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(squashed.ParentHashes) != 1 || squashed.ParentHashes[0] != main.Hash() {
+				t.Errorf("squashed commit has parents %v, want the merged main %s", squashed.ParentHashes, main.Hash())
+			}
+			tip, err := r.repo.CommitObject(before.Hash())
+			if err != nil {
+				t.Fatal(err)
+			}
+			if squashed.TreeHash != tip.TreeHash {
+				t.Errorf("squashed commit has tree %s, want the tree of the branch %s", squashed.TreeHash, tip.TreeHash)
+			}
+			want := "PRJ-1. w1\n\n- PRJ-1. w2\n- PRJ-1. w3\n"
+			if squashed.Message != want {
+				t.Errorf("squashed commit message = %q, want %q", squashed.Message, want)
+			}
+		})
+	}
+}
+
+func TestSquashRefusesBranchWithoutBase(t *testing.T) {
+	for _, backend := range backends(t) {
+		t.Run(backend, func(t *testing.T) {
+			r := newTestRepo(t)
+			r.commit("PRJ-7. r1")
+			// a branch that doesn't start on main, so its first commit is a root commit
+			if err := r.repo.Storer.SetReference(plumbing.NewSymbolicReference(plumbing.HEAD, plumbing.NewBranchReferenceName("PRJ-2"))); err != nil {
+				t.Fatal(err)
+			}
+			r.commit("PRJ-2. w1")
+			tip := r.commit("PRJ-2. w2")
+			squashTestRepo(t, r, backend)
+
+			err := squash(squashCmd, nil)
+			if !errors.Is(err, ErrUsage) {
+				t.Errorf("squash() = %v, want a usage error", err)
+			}
+			if head, _ := r.repo.Head(); head.Hash() != tip {
+				t.Errorf("squash() moved the branch to %s", head.Hash())
+			}
+		})
+	}
+}
@@ -0,0 +1,228 @@
+// Copyright © 2017 Adam Kunicki <kunickiaj@gmail.com>
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	jira "github.com/andygrunwald/go-jira"
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+
+	"github.com/kunickiaj/beer/pkg/review"
+)
+
+// stashPrefix marks the stashes switch creates, followed by the branch they belong to.
+const stashPrefix = "beer switch "
+
+var switchCmd = &cobra.Command{
+	Use:   "switch [ISSUE]",
+	Short: "Switch to the branch of another issue you are working on.",
+	Long: `Checks out the work branch of an issue. Without an argument, the local issue branches are
+listed with their JIRA summary and status to pick from.
+
+Uncommitted changes are stashed before switching and restored when you switch back to their
+branch, so each issue keeps its own work in progress. Switching is refused while a rebase or
+merge is stopped.
+
+If the issue has a linked worktree created by 'brew --worktree', its path is printed instead
+so you can change into it with:
+
+	$ cd "$(beer switch PRJ-123)"`,
+	RunE: switchIssue,
+	Args: usageArgs(cobra.MaximumNArgs(1)),
+}
+
+func init() {
+	RootCmd.AddCommand(switchCmd)
+}
+
+func switchIssue(cmd *cobra.Command, args []string) error {
+	dryRun, _ := cmd.Flags().GetBool("dry-run")
+
+	repo, err := openRepo()
+	if err != nil {
+		return err
+	}
+
+	var key string
+	if len(args) > 0 {
+		key = strings.ToUpper(args[0])
+		if !issueKeyPattern.MatchString(key) {
+			return fmt.Errorf("%w: %q is not an issue key", ErrUsage, args[0])
+		}
+	} else {
+		issues, err := branchIssues(repo)
+		if err != nil {
+			return err
+		}
+		if !isInteractive() || len(issues.Issues) == 0 {
+			return writeResult(cmd.OutOrStdout(), issues)
+		}
+		idx, err := pick(os.Stdin, os.Stderr, "Select an issue to switch to", issues.lines())
+		if err != nil {
+			return err
+		}
+		key = issues.Issues[idx].Key
+	}
+
+	worktrees, err := listWorktrees()
+	if err != nil {
+		return err
+	}
+	current, err := runGit("rev-parse", "--show-toplevel")
+	if err != nil {
+		return err
+	}
+	if path, ok := worktrees[key]; ok && path != current {
+		return writeResult(cmd.OutOrStdout(), switchResult{Key: key, Worktree: path})
+	}
+
+	if operation, err := operationInProgress(); err != nil {
+		return err
+	} else if operation != "" {
+		return fmt.Errorf("%w: a %s is in progress, finish or abort it before switching", review.ErrConflict, operation)
+	}
+
+	head, err := repo.Head()
+	if err != nil {
+		return fmt.Errorf("unable to resolve HEAD: %w", err)
+	}
+	if head.Name().Short() == key {
+		log.WithField("branch", key).Info("Already on branch")
+		return nil
+	}
+	if _, err := repo.Reference(plumbing.NewBranchReferenceName(key), false); err != nil {
+		return fmt.Errorf("%w: there is no branch for %s, create one with 'beer brew %s'", review.ErrNotFound, key, key)
+	}
+
+	changes, err := runGit("status", "--porcelain")
+	if err != nil {
+		return err
+	}
+
+	if dryRun {
+		log.WithFields(log.Fields{"from": head.Name().Short(), "to": key, "stash": changes != ""}).Info("Dry Run")
+		return nil
+	}
+
+	result := switchResult{Key: key}
+	if changes != "" {
+		if !head.Name().IsBranch() {
+			return fmt.Errorf("%w: HEAD is detached and has uncommitted changes, commit or stash them first", ErrUsage)
+		}
+		if _, err := runGit("stash", "push", "--include-untracked", "--message", stashPrefix+head.Name().Short()); err != nil {
+			return err
+		}
+		result.Stashed = true
+	}
+
+	if _, err := runGit("checkout", "--quiet", key); err != nil {
+		if result.Stashed {
+			// put the changes back where they came from
+			if _, popErr := runGit("stash", "pop", "--index"); popErr != nil {
+				log.WithError(popErr).Warn("Unable to restore stashed changes, see 'git stash list'")
+			}
+		}
+		return err
+	}
+
+	if result.Restored, err = restoreStash(key); err != nil {
+		log.WithError(err).Warn("Unable to restore the branch's stashed changes, see 'git stash list'")
+	}
+	return writeResult(cmd.OutOrStdout(), result)
+}
+
+// restoreStash applies and drops the changes switch stashed for branch, if any.
+func restoreStash(branch string) (bool, error) {
+	out, err := runGit("stash", "list", "--format=%gd %gs")
+	if err != nil {
+		return false, err
+	}
+	for _, line := range strings.Split(out, "\n") {
+		ref, subject, _ := strings.Cut(line, " ")
+		// git prefixes the message with "On <branch>: "
+		if _, message, ok := strings.Cut(subject, ": "); ok && message == stashPrefix+branch {
+			if _, err := runGit("stash", "pop", "--index", ref); err != nil {
+				return false, err
+			}
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// branchIssues lists the issues of local branches, as far as JIRA knows them.
+func branchIssues(repo *git.Repository) (pourResult, error) {
+	result := pourResult{Issues: []issueSummary{}}
+	branches, err := issueBranches(repo)
+	if err != nil || len(branches) == 0 {
+		return result, err
+	}
+
+	jiraClient, err := newJiraClient()
+	if err != nil {
+		return result, err
+	}
+	for _, ref := range branches {
+		key := strings.ToUpper(ref.Name().Short())
+		issue, res, err := jiraClient.Issue.Get(key, &jira.GetQueryOptions{Fields: "summary,status,priority"})
+		if err != nil {
+			if err = jiraError(res, err, fmt.Sprintf("unable to fetch %s", key)); !errors.Is(err, review.ErrNotFound) {
+				return result, err
+			}
+			result.Issues = append(result.Issues, issueSummary{Key: key, Status: "missing"})
+			continue
+		}
+		result.Issues = append(result.Issues, summarizeIssue(issue))
+	}
+	return result, nil
+}
+
+// switchResult describes the branch or worktree switched to.
+type switchResult struct {
+	Key      string `json:"key" yaml:"key"`
+	Worktree string `json:"worktree,omitempty" yaml:"worktree,omitempty"` // Path to change into
+	Stashed  bool   `json:"stashed,omitempty" yaml:"stashed,omitempty"`   // Changes on the previous branch were stashed
+	Restored bool   `json:"restored,omitempty" yaml:"restored,omitempty"` // Changes stashed on this branch were restored
+}
+
+func (r switchResult) message() string {
+	return "Switched to " + r.Key
+}
+
+func (r switchResult) fields() log.Fields {
+	if r.Worktree != "" {
+		return log.Fields{"key": r.Key, "worktree": r.Worktree}
+	}
+	return log.Fields{"key": r.Key, "stashed": r.Stashed, "restored": r.Restored}
+}
+
+// writeText prints the bare path of a worktree so it can be used with cd.
+func (r switchResult) writeText(w io.Writer) error {
+	if r.Worktree == "" {
+		log.WithFields(r.fields()).Info(r.message())
+		return nil
+	}
+	_, err := fmt.Fprintln(w, r.Worktree)
+	return err
+}
@@ -0,0 +1,167 @@
+// Copyright © 2017 Adam Kunicki <kunickiaj@gmail.com>
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package cmd
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/go-git/go-git/v5/plumbing"
+
+	"github.com/kunickiaj/beer/pkg/gitbackend"
+	"github.com/kunickiaj/beer/pkg/review"
+)
+
+// newSwitchRepo creates a repository with the issue branches PRJ-1 and PRJ-2, PRJ-1 checked out
+// and the working directory changed to it.
+func newSwitchRepo(t *testing.T) *testRepo {
+	t.Helper()
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not found")
+	}
+	r := newTestRepo(t)
+	r.write("a.txt", "committed\n")
+	r.commit("PRJ-7. r1")
+	r.checkout("PRJ-2", true)
+	r.checkout("PRJ-1", true)
+	config.Git.Backend = gitbackend.ExecName
+	t.Chdir(r.dir)
+	return r
+}
+
+// gitOutput runs git in the working directory, failing the test on errors.
+func gitOutput(t *testing.T, args ...string) string {
+	t.Helper()
+	out, err := runGit(args...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return out
+}
+
+func TestSwitchIssueKeepsChangesWithTheirBranch(t *testing.T) {
+	r := newSwitchRepo(t)
+	write := func(name string, content string) {
+		t.Helper()
+		if err := os.WriteFile(filepath.Join(r.dir, name), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	cmd := switchCmd
+	cmd.SetOut(&bytes.Buffer{})
+
+	// staged, unstaged and untracked changes on PRJ-1
+	write("a.txt", "PRJ-1 change\n")
+	write("staged.txt", "staged\n")
+	gitOutput(t, "add", "staged.txt")
+	write("untracked.txt", "untracked\n")
+	prj1Status := gitOutput(t, "status", "--porcelain")
+
+	if err := switchIssue(cmd, []string{"prj-2"}); err != nil {
+		t.Fatal(err)
+	}
+	if branch := gitOutput(t, "branch", "--show-current"); branch != "PRJ-2" {
+		t.Errorf("switchIssue() checked out %s, want PRJ-2", branch)
+	}
+	if status := gitOutput(t, "status", "--porcelain"); status != "" {
+		t.Errorf("switchIssue() brought changes along: %s", status)
+	}
+	if stashes := gitOutput(t, "stash", "list", "--format=%gs"); stashes != "On PRJ-1: beer switch PRJ-1" {
+		t.Errorf("stashes = %q, want PRJ-1's changes", stashes)
+	}
+
+	// a stash of another branch whose name starts the same isn't restored
+	write("a.txt", "PRJ-10 change\n")
+	gitOutput(t, "stash", "push", "--message", stashPrefix+"PRJ-10")
+	write("a.txt", "PRJ-2 change\n")
+
+	if err := switchIssue(cmd, []string{"PRJ-1"}); err != nil {
+		t.Fatal(err)
+	}
+	if status := gitOutput(t, "status", "--porcelain"); status != prj1Status {
+		t.Errorf("status after switching back = %q, want %q with the index restored", status, prj1Status)
+	}
+	if data, err := os.ReadFile(filepath.Join(r.dir, "a.txt")); err != nil || string(data) != "PRJ-1 change\n" {
+		t.Errorf("a.txt = %q, %v, want PRJ-1's change", data, err)
+	}
+	if stashes := gitOutput(t, "stash", "list", "--format=%gs"); stashes != "On PRJ-2: beer switch PRJ-2\nOn PRJ-2: beer switch PRJ-10" {
+		t.Errorf("stashes = %q, want PRJ-2's changes and the unrelated stash", stashes)
+	}
+
+	if err := switchIssue(cmd, []string{"PRJ-2"}); err != nil {
+		t.Fatal(err)
+	}
+	if data, err := os.ReadFile(filepath.Join(r.dir, "a.txt")); err != nil || string(data) != "PRJ-2 change\n" {
+		t.Errorf("a.txt = %q, %v, want PRJ-2's change", data, err)
+	}
+}
+
+func TestSwitchIssueRestoresChangesWhenCheckoutFails(t *testing.T) {
+	r := newSwitchRepo(t)
+	// PRJ-3 exists but can't be checked out, as its commit is missing
+	if err := os.WriteFile(filepath.Join(r.dir, ".git", "refs", "heads", "PRJ-3"), []byte(strings.Repeat("1", 40)+"\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(r.dir, "a.txt"), []byte("PRJ-1 change\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	cmd := switchCmd
+	cmd.SetOut(&bytes.Buffer{})
+
+	if err := switchIssue(cmd, []string{"PRJ-3"}); err == nil {
+		t.Fatal("switchIssue() to a branch that can't be checked out succeeded")
+	}
+	if branch := gitOutput(t, "branch", "--show-current"); branch != "PRJ-1" {
+		t.Errorf("switchIssue() left %s checked out, want PRJ-1", branch)
+	}
+	if status := gitOutput(t, "status", "--porcelain"); status != "M a.txt" {
+		t.Errorf("status = %q, want the change to a.txt put back", status)
+	}
+	if stashes := gitOutput(t, "stash", "list"); stashes != "" {
+		t.Errorf("stashes = %q, want none", stashes)
+	}
+}
+
+func TestSwitchIssueRefusedDuringRebase(t *testing.T) {
+	r := newSwitchRepo(t)
+	if err := os.WriteFile(filepath.Join(r.dir, "a.txt"), []byte("PRJ-1 change\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Mkdir(filepath.Join(r.dir, ".git", "rebase-merge"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	cmd := switchCmd
+	cmd.SetOut(&bytes.Buffer{})
+
+	if err := switchIssue(cmd, []string{"PRJ-2"}); !errors.Is(err, review.ErrConflict) || !strings.Contains(err.Error(), "rebase") {
+		t.Errorf("switchIssue() during a rebase = %v, want a conflict naming the rebase", err)
+	}
+	head, err := r.repo.Head()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if head.Name() != plumbing.NewBranchReferenceName("PRJ-1") {
+		t.Errorf("switchIssue() checked out %s during a rebase", head.Name().Short())
+	}
+	if stashes := gitOutput(t, "stash", "list"); stashes != "" {
+		t.Errorf("switchIssue() stashed %q during a rebase", stashes)
+	}
+}
@@ -16,6 +16,9 @@
 package cmd
 
 import (
+	"fmt"
+
+	"github.com/pkg/errors"
 	"github.com/spf13/viper"
 
 	"github.com/kunickiaj/beer/pkg/review"
@@ -27,12 +30,13 @@ var tasteCmd = &cobra.Command{
 	Use:   "taste",
 	Short: "Push a review for the current branch, optionally specifying reviewers by email.",
 	Long:  ``,
-	Run:   taste,
-	Args:  cobra.ExactArgs(0),
+	RunE:  taste,
+	Args:  usageArgs(cobra.ExactArgs(0)),
 }
 
 var wip bool
 var reviewers []string
+var noVerify bool
 
 func init() {
 	RootCmd.AddCommand(tasteCmd)
@@ -40,39 +44,94 @@ func init() {
 	tasteCmd.Flags().BoolVar(&wip, "wip", false, "Setting this flag will post a WIP review")
 	tasteCmd.Flags().StringSliceVarP(&reviewers, "reviewers", "r", nil, "Comma separated list of email ids of reviewers to add")
 	tasteCmd.Flags().String("branch", "main", "Target branch for review")
+	tasteCmd.Flags().BoolVar(&noVerify, "no-verify", false, "Publish without checking the commit messages or running the pre-push hook")
 
 	_ = viper.BindPFlag("defaults.branch", tasteCmd.Flags().Lookup("branch"))
 }
 
-func taste(cmd *cobra.Command, args []string) {
+func taste(cmd *cobra.Command, args []string) error {
 	dryRun, _ := cmd.Flags().GetBool("dry-run")
-	isWIP, _ := cmd.Flags().GetBool("wip")
-	targetBranch := viper.GetString("defaults.branch")
+	// flags are read through their variables so other commands, e.g. rebase --taste, can run taste.
+	// The target branch is the running command's --branch, falling back to defaults.branch
+	isWIP := wip
+	targetBranch := targetBranch(cmd)
 	log.WithField("targetBranch", targetBranch).Debug("Determined target branch for comparison")
-	reviewers, err := cmd.Flags().GetStringSlice("reviewers")
 
+	log.WithField("reviewers", reviewers).Debug("Parsed reviewers")
+
+	repo, err := openRepo()
 	if err != nil {
-		log.WithError(err).Fatal("Could not parse reviewers")
+		return err
+	}
+	if !noVerify {
+		if err := verifyCommits(repo, targetBranch); err != nil {
+			return err
+		}
 	}
 
-	log.WithField("reviewers", reviewers).Debug("Parsed reviewers")
+	var target review.Target
+	if !dryRun {
+		// resolving credentials may ask for a passphrase, so it is skipped on dry runs
+		if target, err = reviewTarget(repo); err != nil {
+			return err
+		}
+	}
 
 	var r review.Review
 	switch config.ReviewTool.Normalize() {
 	case Gerrit:
-		r = review.NewGerritReview("title", "description", reviewers, targetBranch, isWIP)
+		r = review.NewGerritReview("title", "description", reviewers, targetBranch, isWIP, target, config.Gerrit.API())
 	case GitHub:
-		r = review.NewGitHubReview("title", "description", reviewers, targetBranch, isWIP)
+		r = review.NewGitHubReview("title", "description", reviewers, targetBranch, isWIP, target)
 	default:
-		log.WithField("reviewTool", config.ReviewTool).Fatal("This review tool is not yet supported")
+		return fmt.Errorf("%w: review tool %q is not yet supported", review.ErrNotImplemented, config.ReviewTool)
 	}
 
 	if dryRun {
-		return
+		return nil
 	}
 
-	err = r.Publish()
+	result, err := r.Publish()
+	if err != nil {
+		return errors.Wrap(err, "failed to publish review")
+	}
+
+	if config.Jira.Notify.Taste {
+		// the review is already published, so failing to record it in JIRA isn't fatal
+		if err := recordReview(result, isWIP); err != nil {
+			log.WithError(err).Warn("Unable to record review in JIRA")
+		}
+	}
+
+	return writeResult(cmd.OutOrStdout(), tasteResult{Result: result})
+}
+
+// tasteResult describes the review published by taste.
+type tasteResult struct {
+	*review.Result `yaml:",inline"`
+}
+
+func (r tasteResult) message() string {
+	return "Published review"
+}
+
+func (r tasteResult) fields() log.Fields {
+	return log.Fields{"url": r.URL, "number": r.Number, "patchset": r.Patchset}
+}
+
+// recordReview records a published review on the issue of the current branch.
+func recordReview(result *review.Result, isWIP bool) error {
+	repo, err := openRepo()
+	if err != nil {
+		return err
+	}
+	key, err := currentIssueKey(repo)
+	if err != nil {
+		return err
+	}
+	jiraClient, err := newJiraClient()
 	if err != nil {
-		log.WithError(err).Error("Failed to publish review")
+		return err
 	}
+	return notifyReview(jiraClient, key, result, isWIP)
 }
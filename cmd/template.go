@@ -0,0 +1,443 @@
+// Copyright © 2017 Adam Kunicki <kunickiaj@gmail.com>
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"text/template"
+	"time"
+
+	jira "github.com/andygrunwald/go-jira"
+	"github.com/go-git/go-git/v5"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"go.yaml.in/yaml/v3"
+
+	"github.com/kunickiaj/beer/pkg/review"
+)
+
+// templateDir is where issue templates are stored, relative to the repository root.
+const templateDir = ".beer/templates"
+
+var templateCmd = &cobra.Command{
+	Use:   "template",
+	Short: "List and validate issue templates used by 'brew --template'.",
+	Long: `Issue templates describe recurring issues so they can be created with 'beer brew --template NAME'.
+
+Templates are YAML files in the repository's .beer/templates directory, or entries under
+'templates' in the config file. A template in the repository takes precedence over one with
+the same name in the config file. For example:
+
+	project: PRJ
+	issueType: Bug
+	summary: "Flaky test: {{ .Summary }}"
+	description: |
+	  {{ .Description }}
+
+	  ### Failing build
+	components: [CI]
+	labels: [flaky-test]
+	fields:
+	  Severity: Minor
+
+Summary and description are Go templates. The summary and description given with -s and -d
+are available as {{ .Summary }} and {{ .Description }}, along with {{ .Project }},
+{{ .IssueType }}, {{ .User }} and {{ .Date }}.`,
+}
+
+var templateListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List available issue templates.",
+	RunE:  templateList,
+	Args:  usageArgs(cobra.ExactArgs(0)),
+}
+
+var templateValidateCmd = &cobra.Command{
+	Use:   "validate [NAME...]",
+	Short: "Check templates against the project's issue types, fields and allowed values.",
+	RunE:  templateValidate,
+}
+
+var templateName string
+
+func init() {
+	RootCmd.AddCommand(templateCmd)
+	templateCmd.AddCommand(templateListCmd)
+	templateCmd.AddCommand(templateValidateCmd)
+
+	brewCmd.Flags().StringVar(&templateName, "template", "", "Name of an issue template to create the issue from. See 'beer template --help'")
+}
+
+// issueTemplate describes an issue brew can create repeatedly.
+type issueTemplate struct {
+	Project     string            `yaml:"project,omitempty" mapstructure:"project"`
+	IssueType   string            `yaml:"issueType,omitempty" mapstructure:"issueType"`
+	Summary     string            `yaml:"summary,omitempty" mapstructure:"summary"`
+	Description string            `yaml:"description,omitempty" mapstructure:"description"`
+	Components  []string          `yaml:"components,omitempty" mapstructure:"components"`
+	Labels      []string          `yaml:"labels,omitempty" mapstructure:"labels"`
+	Fields      map[string]string `yaml:"fields,omitempty" mapstructure:"fields"`
+}
+
+// templateData is available to the summary and description of a template.
+type templateData struct {
+	Summary     string
+	Description string
+	Project     string
+	IssueType   string
+	User        string
+	Date        string
+}
+
+// templatesPath returns the template directory of the repository.
+func templatesPath(repo *git.Repository) (string, error) {
+	w, err := repo.Worktree()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(w.Filesystem.Root(), templateDir), nil
+}
+
+// checkTemplateName rejects template names that aren't plain file names, so a template can't be
+// saved outside of the template directory.
+func checkTemplateName(name string) error {
+	if name == "" || strings.ContainsAny(name, `/\`) || strings.Contains(name, "..") {
+		return fmt.Errorf("%w: invalid template name %q, it can't be empty or contain path separators or ..", ErrUsage, name)
+	}
+	return nil
+}
+
+// saveTemplate writes t to the repository's template directory and returns the file path.
+func saveTemplate(repo *git.Repository, name string, t issueTemplate) (string, error) {
+	if err := checkTemplateName(name); err != nil {
+		return "", err
+	}
+	dir, err := templatesPath(repo)
+	if err != nil {
+		return "", err
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return "", err
+	}
+
+	data, err := yaml.Marshal(t)
+	if err != nil {
+		return "", err
+	}
+
+	file := filepath.Join(dir, name+".yaml")
+	return file, os.WriteFile(file, data, 0o644)
+}
+
+// loadTemplates returns all templates keyed by name, from the config file and the repository.
+func loadTemplates(repo *git.Repository) (map[string]issueTemplate, error) {
+	templates := map[string]issueTemplate{}
+	for name, t := range config.Templates {
+		templates[name] = t
+	}
+
+	dir, err := templatesPath(repo)
+	if err != nil {
+		return nil, err
+	}
+
+	entries, err := os.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return templates, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	for _, e := range entries {
+		ext := filepath.Ext(e.Name())
+		if e.IsDir() || (ext != ".yaml" && ext != ".yml") {
+			continue
+		}
+
+		data, err := os.ReadFile(filepath.Join(dir, e.Name()))
+		if err != nil {
+			return nil, err
+		}
+
+		var t issueTemplate
+		if err := yaml.Unmarshal(data, &t); err != nil {
+			return nil, fmt.Errorf("%w: invalid template %s: %w", ErrUsage, e.Name(), err)
+		}
+		templates[strings.TrimSuffix(e.Name(), ext)] = t
+	}
+	return templates, nil
+}
+
+// loadTemplate returns the template with the given name.
+func loadTemplate(repo *git.Repository, name string) (issueTemplate, error) {
+	templates, err := loadTemplates(repo)
+	if err != nil {
+		return issueTemplate{}, err
+	}
+
+	if t, ok := templates[name]; ok {
+		return t, nil
+	}
+	// viper lower cases the names of templates from the config file
+	if t, ok := templates[strings.ToLower(name)]; ok {
+		return t, nil
+	}
+	return issueTemplate{}, fmt.Errorf("%w: no template named %q", review.ErrNotFound, name)
+}
+
+// applyTemplate fills in brew's flag variables from a template. Flags given on the command line
+// take precedence over the template.
+func applyTemplate(cmd *cobra.Command, repo *git.Repository, name string) error {
+	t, err := loadTemplate(repo, name)
+	if err != nil {
+		return err
+	}
+	log.WithFields(log.Fields{"template": name, "values": t}).Debug("Applying template")
+
+	if projectKey == "" {
+		projectKey = t.Project
+	}
+	// set through the flag so the issue type counts as chosen, like one given on the command line
+	if !cmd.Flags().Changed("issue-type") && t.IssueType != "" {
+		if err := cmd.Flags().Set("issue-type", t.IssueType); err != nil {
+			return err
+		}
+	}
+	components = append(t.Components, components...)
+	labels = append(t.Labels, labels...)
+
+	// merged with --field by field ID once the issue type's fields are known
+	templateFields = t.Fields
+
+	data := templateData{
+		Summary:     summary,
+		Description: description,
+		Project:     projectKey,
+		IssueType:   issueType,
+		User:        config.Jira.Username,
+		Date:        time.Now().Format("2006-01-02"),
+	}
+
+	if t.Summary != "" {
+		if summary, err = renderTemplate("summary", t.Summary, data); err != nil {
+			return err
+		}
+	}
+	if t.Description != "" {
+		if description, err = renderTemplate("description", t.Description, data); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func renderTemplate(name string, text string, data templateData) (string, error) {
+	tmpl, err := template.New(name).Option("missingkey=error").Parse(text)
+	if err != nil {
+		return "", fmt.Errorf("%w: invalid %s template: %w", ErrUsage, name, err)
+	}
+
+	var sb strings.Builder
+	if err := tmpl.Execute(&sb, data); err != nil {
+		return "", fmt.Errorf("%w: invalid %s template: %w", ErrUsage, name, err)
+	}
+	return strings.TrimSpace(sb.String()), nil
+}
+
+// validateTemplate checks a template's summary and description render and that its project,
+// issue type, components and fields exist and use allowed values.
+func validateTemplate(jiraClient *jira.Client, t issueTemplate, cloud bool) []string {
+	var problems []string
+	sample := templateData{Summary: "summary", Description: "description", Project: t.Project, IssueType: t.IssueType}
+	for name, text := range map[string]string{"summary": t.Summary, "description": t.Description} {
+		if _, err := renderTemplate(name, text, sample); err != nil {
+			problems = append(problems, err.Error())
+		}
+	}
+
+	if t.Project == "" || t.IssueType == "" {
+		return append(problems, "project and issueType are required to validate fields")
+	}
+
+	metaProject, err := createMetaProject(jiraClient, t.Project)
+	if err != nil {
+		return append(problems, err.Error())
+	}
+	metaIssueType, err := createMetaIssueType(jiraClient, metaProject, t.IssueType)
+	if err != nil {
+		return append(problems, err.Error())
+	}
+	fields, err := metaFields(metaIssueType)
+	if err != nil {
+		return append(problems, err.Error())
+	}
+
+	if err := validateComponents(jiraClient, t.Project, t.Components); err != nil {
+		problems = append(problems, err.Error())
+	}
+
+	issue := &jira.Issue{Fields: &jira.IssueFields{Unknowns: map[string]interface{}{}}}
+	for name, value := range t.Fields {
+		if err := applyCustomFields(issue, fields, map[string]string{name: value}, cloud); err != nil {
+			problems = append(problems, err.Error())
+		}
+	}
+
+	for _, f := range fields {
+		if !f.Required || f.HasDefault || promptedFields[f.Name] {
+			continue
+		}
+		if _, ok := lookupValue(t.Fields, &f); !ok {
+			problems = append(problems, fmt.Sprintf("required field %q has no value", f.Name))
+		}
+	}
+	return problems
+}
+
+func templateList(cmd *cobra.Command, args []string) error {
+	repo, err := openRepo()
+	if err != nil {
+		return err
+	}
+
+	templates, err := loadTemplates(repo)
+	if err != nil {
+		return err
+	}
+
+	result := templateListResult{}
+	for name, t := range templates {
+		result.Templates = append(result.Templates, templateSummary{Name: name, Project: t.Project, IssueType: t.IssueType, Summary: t.Summary})
+	}
+	sort.Slice(result.Templates, func(i, j int) bool { return result.Templates[i].Name < result.Templates[j].Name })
+	return writeResult(cmd.OutOrStdout(), result)
+}
+
+func templateValidate(cmd *cobra.Command, args []string) error {
+	repo, err := openRepo()
+	if err != nil {
+		return err
+	}
+
+	templates, err := loadTemplates(repo)
+	if err != nil {
+		return err
+	}
+
+	names := args
+	if len(names) == 0 {
+		for name := range templates {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+	}
+
+	jiraClient, err := newJiraClient()
+	if err != nil {
+		return err
+	}
+	jiraUser, err := currentUser(jiraClient)
+	if err != nil {
+		return err
+	}
+
+	result := templateValidateResult{}
+	for _, name := range names {
+		t, err := loadTemplate(repo, name)
+		if err != nil {
+			return err
+		}
+		result.Templates = append(result.Templates, templateProblems{
+			Name:     name,
+			Problems: validateTemplate(jiraClient, t, len(jiraUser.AccountID) > 0),
+		})
+	}
+
+	if err := writeResult(cmd.OutOrStdout(), result); err != nil {
+		return err
+	}
+	for _, t := range result.Templates {
+		if len(t.Problems) > 0 {
+			return fmt.Errorf("%w: template %q is invalid", ErrUsage, t.Name)
+		}
+	}
+	return nil
+}
+
+type templateSummary struct {
+	Name      string `json:"name" yaml:"name"`
+	Project   string `json:"project,omitempty" yaml:"project,omitempty"`
+	IssueType string `json:"issueType,omitempty" yaml:"issueType,omitempty"`
+	Summary   string `json:"summary,omitempty" yaml:"summary,omitempty"`
+}
+
+// templateListResult lists the available templates.
+type templateListResult struct {
+	Templates []templateSummary `json:"templates" yaml:"templates"`
+}
+
+func (r templateListResult) message() string {
+	return "Found templates"
+}
+
+func (r templateListResult) fields() log.Fields {
+	return log.Fields{"count": len(r.Templates)}
+}
+
+func (r templateListResult) writeText(w io.Writer) error {
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	for _, t := range r.Templates {
+		_, _ = fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n", t.Name, t.Project, t.IssueType, t.Summary)
+	}
+	return tw.Flush()
+}
+
+type templateProblems struct {
+	Name     string   `json:"name" yaml:"name"`
+	Problems []string `json:"problems" yaml:"problems"`
+}
+
+// templateValidateResult lists the problems found in each validated template.
+type templateValidateResult struct {
+	Templates []templateProblems `json:"templates" yaml:"templates"`
+}
+
+func (r templateValidateResult) message() string {
+	return "Validated templates"
+}
+
+func (r templateValidateResult) fields() log.Fields {
+	return log.Fields{"count": len(r.Templates)}
+}
+
+func (r templateValidateResult) writeText(w io.Writer) error {
+	for _, t := range r.Templates {
+		if len(t.Problems) == 0 {
+			log.WithField("template", t.Name).Info("Template is valid")
+			continue
+		}
+		for _, p := range t.Problems {
+			log.WithField("template", t.Name).Warn(p)
+		}
+	}
+	return nil
+}
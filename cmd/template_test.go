@@ -0,0 +1,103 @@
+// Copyright © 2017 Adam Kunicki <kunickiaj@gmail.com>
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package cmd
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/spf13/cobra"
+)
+
+func TestSaveTemplate(t *testing.T) {
+	tests := []struct {
+		name    string
+		wantErr bool
+	}{
+		{name: "flaky-test"},
+		{name: "flaky.test"},
+		{name: "", wantErr: true},
+		{name: "../flaky-test", wantErr: true},
+		{name: "..", wantErr: true},
+		{name: "nested/flaky-test", wantErr: true},
+		{name: `nested\flaky-test`, wantErr: true},
+		{name: "/tmp/flaky-test", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := newTestRepo(t)
+			file, err := saveTemplate(r.repo, tt.name, issueTemplate{Project: "PRJ", Summary: "Flaky test"})
+			if tt.wantErr {
+				if !errors.Is(err, ErrUsage) {
+					t.Errorf("saveTemplate() = %s, %v, want a usage error", file, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if want := filepath.Join(r.dir, templateDir, tt.name+".yaml"); file != want {
+				t.Errorf("saveTemplate() = %s, want %s", file, want)
+			}
+			if _, err := os.Stat(file); err != nil {
+				t.Error(err)
+			}
+			templates, err := loadTemplates(r.repo)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if templates[tt.name].Summary != "Flaky test" {
+				t.Errorf("loadTemplates() = %+v, want the saved template", templates)
+			}
+		})
+	}
+}
+
+func TestApplyTemplateFieldPrecedence(t *testing.T) {
+	r := newTestRepo(t)
+	if _, err := saveTemplate(r.repo, "estimate", issueTemplate{Fields: map[string]string{"story points": "3", "Team": "Platform"}}); err != nil {
+		t.Fatal(err)
+	}
+	savedType := issueType
+	t.Cleanup(func() { customFields, templateFields, projectKey, issueType = nil, nil, "", savedType })
+	customFields = map[string]string{"Story Points": "5"}
+
+	cmd := &cobra.Command{}
+	cmd.Flags().StringVarP(&issueType, "issue-type", "t", "Bug", "")
+	cmd.Flags().Bool("testing-status", false, "")
+	cmd.Flags().Bool("doc-impact", false, "")
+	if err := applyTemplate(cmd, r.repo, "estimate"); err != nil {
+		t.Fatal(err)
+	}
+
+	fields := []metaField{
+		{ID: "customfield_1", Name: "Story Points", Type: "number"},
+		{ID: "customfield_3", Name: "Team", Type: "string"},
+	}
+	// repeated to catch results depending on map iteration order
+	for i := 0; i < 20; i++ {
+		got, err := resolveFieldValues(cmd, fields, "PRJ", "Bug", templateFields, customFields)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if want := map[string]string{"customfield_1": "5", "customfield_3": "Platform"}; !reflect.DeepEqual(got, want) {
+			t.Fatalf("resolveFieldValues() = %v, want %v", got, want)
+		}
+	}
+}
@@ -0,0 +1,215 @@
+// Copyright © 2017 Adam Kunicki <kunickiaj@gmail.com>
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package cmd
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+
+	"github.com/kunickiaj/beer/pkg/review"
+	"github.com/kunickiaj/beer/pkg/state"
+)
+
+// timersState is the name timers are saved under in the state directory.
+const timersState = "timers"
+
+var timerCmd = &cobra.Command{
+	Use:   "timer",
+	Short: "Time work on an issue and log it when the timer is stopped.",
+	Long: `Timers measure the time spent on an issue and post it as a worklog when stopped. They are
+kept in beer's directory under $XDG_STATE_HOME (~/.local/state), or state.dir if configured,
+so they keep running across terminals and reboots. Each issue can have one running timer.`,
+}
+
+var timerStartCmd = &cobra.Command{
+	Use:   "start [ISSUE]",
+	Short: "Start timing work on an issue, by default the current branch's.",
+	RunE:  timerStart,
+	Args:  usageArgs(cobra.MaximumNArgs(1)),
+}
+
+var timerStopCmd = &cobra.Command{
+	Use:   "stop [ISSUE]",
+	Short: "Stop a timer and log the time spent on the issue.",
+	RunE:  timerStop,
+	Args:  usageArgs(cobra.MaximumNArgs(1)),
+}
+
+var timerStatusCmd = &cobra.Command{
+	Use:   "status",
+	Short: "List running timers.",
+	RunE:  timerStatus,
+	Args:  usageArgs(cobra.ExactArgs(0)),
+}
+
+var timerMessage string
+var discardTimer bool
+
+func init() {
+	RootCmd.AddCommand(timerCmd)
+	timerCmd.AddCommand(timerStartCmd)
+	timerCmd.AddCommand(timerStopCmd)
+	timerCmd.AddCommand(timerStatusCmd)
+
+	timerStopCmd.Flags().StringVarP(&timerMessage, "message", "m", "", "Worklog comment")
+	timerStopCmd.Flags().BoolVar(&discardTimer, "discard", false, "Stop the timer without logging work")
+}
+
+// loadTimers returns the running timers keyed by issue.
+func loadTimers() (*state.Store, map[string]time.Time, error) {
+	store, err := state.New(config.State.Dir)
+	if err != nil {
+		return nil, nil, err
+	}
+	timers := map[string]time.Time{}
+	if err := store.Load(timersState, &timers); err != nil {
+		return nil, nil, fmt.Errorf("unable to read timers from %s: %w", store.Dir, err)
+	}
+	return store, timers, nil
+}
+
+func timerStart(cmd *cobra.Command, args []string) error {
+	dryRun, _ := cmd.Flags().GetBool("dry-run")
+
+	key, err := issueKeyOrBranch(firstArg(args))
+	if err != nil {
+		return err
+	}
+
+	store, timers, err := loadTimers()
+	if err != nil {
+		return err
+	}
+	if started, ok := timers[key]; ok {
+		return fmt.Errorf("%w: a timer for %s has been running since %s", review.ErrConflict, key, started.Format("2006-01-02 15:04"))
+	}
+
+	now := time.Now()
+	if dryRun {
+		log.WithField("issue", key).Info("Dry Run")
+		return nil
+	}
+
+	timers[key] = now
+	if err := store.Save(timersState, timers); err != nil {
+		return err
+	}
+	return writeResult(cmd.OutOrStdout(), timerResult{Timers: []runningTimer{newRunningTimer(key, now)}})
+}
+
+func timerStop(cmd *cobra.Command, args []string) error {
+	dryRun, _ := cmd.Flags().GetBool("dry-run")
+
+	store, timers, err := loadTimers()
+	if err != nil {
+		return err
+	}
+
+	key := firstArg(args)
+	if key == "" && len(timers) == 1 {
+		// with a single timer running there is no need to be on its branch
+		for k := range timers {
+			key = k
+		}
+	}
+	if key, err = issueKeyOrBranch(key); err != nil {
+		return err
+	}
+
+	started, ok := timers[key]
+	if !ok {
+		return fmt.Errorf("%w: no timer is running for %s", review.ErrNotFound, key)
+	}
+
+	if !discardTimer {
+		spent := time.Since(started).Round(time.Minute)
+		if spent < time.Minute {
+			spent = time.Minute
+		}
+		// the timer is only removed once the work is logged so a failed stop can be retried
+		if err := logWork(cmd, key, started, spent, timerMessage, dryRun); err != nil {
+			return err
+		}
+	}
+	if dryRun {
+		return nil
+	}
+
+	delete(timers, key)
+	if err := store.Save(timersState, timers); err != nil {
+		return err
+	}
+	if discardTimer {
+		log.WithField("issue", key).Info("Discarded timer")
+	}
+	return nil
+}
+
+func timerStatus(cmd *cobra.Command, args []string) error {
+	_, timers, err := loadTimers()
+	if err != nil {
+		return err
+	}
+
+	result := timerResult{Timers: []runningTimer{}}
+	for key, started := range timers {
+		result.Timers = append(result.Timers, newRunningTimer(key, started))
+	}
+	sort.Slice(result.Timers, func(i, j int) bool { return result.Timers[i].Key < result.Timers[j].Key })
+	return writeResult(cmd.OutOrStdout(), result)
+}
+
+func firstArg(args []string) string {
+	if len(args) == 0 {
+		return ""
+	}
+	return args[0]
+}
+
+// runningTimer describes a running timer.
+type runningTimer struct {
+	Key     string `json:"key" yaml:"key"`
+	Started string `json:"started" yaml:"started"`
+	Elapsed string `json:"elapsed" yaml:"elapsed"`
+}
+
+func newRunningTimer(key string, started time.Time) runningTimer {
+	return runningTimer{Key: key, Started: started.Format(time.RFC3339), Elapsed: jiraDuration(time.Since(started))}
+}
+
+// timerResult lists running timers.
+type timerResult struct {
+	Timers []runningTimer `json:"timers" yaml:"timers"`
+}
+
+func (r timerResult) message() string {
+	if len(r.Timers) == 0 {
+		return "No timers running"
+	}
+	return "Running timers"
+}
+
+func (r timerResult) fields() log.Fields {
+	f := log.Fields{}
+	for _, t := range r.Timers {
+		f[t.Key] = fmt.Sprintf("%s (since %s)", t.Elapsed, t.Started)
+	}
+	return f
+}
@@ -0,0 +1,123 @@
+// Copyright © 2017 Adam Kunicki <kunickiaj@gmail.com>
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/kunickiaj/beer/pkg/review"
+	"github.com/kunickiaj/beer/pkg/state"
+)
+
+func TestTimer(t *testing.T) {
+	var worklogs []map[string]interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if req.Method != http.MethodPost || req.URL.Path != "/rest/api/2/issue/PRJ-1/worklog" {
+			http.NotFound(w, req)
+			return
+		}
+		var worklog map[string]interface{}
+		data, _ := io.ReadAll(req.Body)
+		if err := json.Unmarshal(data, &worklog); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		worklogs = append(worklogs, worklog)
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		_, _ = io.WriteString(w, `{"id": "10100"}`)
+	}))
+	t.Cleanup(server.Close)
+
+	saved, savedMessage, savedDiscard := config, timerMessage, discardTimer
+	t.Cleanup(func() { config, timerMessage, discardTimer = saved, savedMessage, savedDiscard })
+	config = Config{}
+	config.Jira.URL = server.URL
+	config.State.Dir = t.TempDir()
+	timerMessage, discardTimer = "", false
+
+	cmd := &cobra.Command{}
+	cmd.Flags().Bool("dry-run", false, "")
+	cmd.SetOut(io.Discard)
+	running := func() map[string]time.Time {
+		t.Helper()
+		_, timers, err := loadTimers()
+		if err != nil {
+			t.Fatal(err)
+		}
+		return timers
+	}
+
+	if err := timerStart(cmd, []string{"prj-1"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := running()["PRJ-1"]; !ok {
+		t.Fatalf("timerStart() saved %v, want a timer for PRJ-1", running())
+	}
+	if err := timerStart(cmd, []string{"PRJ-1"}); !errors.Is(err, review.ErrConflict) {
+		t.Errorf("timerStart() of a running timer = %v, want ErrConflict", err)
+	}
+
+	// the only running timer is stopped without naming it, logging the time since it started
+	store, err := state.New(config.State.Dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	started := time.Now().Add(-90 * time.Minute)
+	if err := store.Save(timersState, map[string]time.Time{"PRJ-1": started}); err != nil {
+		t.Fatal(err)
+	}
+	timerMessage = "Reproduced the bug"
+	if err := timerStop(cmd, nil); err != nil {
+		t.Fatal(err)
+	}
+	if len(worklogs) != 1 || worklogs[0]["timeSpentSeconds"] != float64(90*60) || worklogs[0]["comment"] != "Reproduced the bug" {
+		t.Errorf("timerStop() logged %v, want 1h 30m with the comment", worklogs)
+	}
+	if timers := running(); len(timers) != 0 {
+		t.Errorf("timerStop() kept %v", timers)
+	}
+	if err := timerStop(cmd, []string{"PRJ-1"}); !errors.Is(err, review.ErrNotFound) {
+		t.Errorf("timerStop() without a timer = %v, want ErrNotFound", err)
+	}
+
+	// a timer whose work can't be logged keeps running
+	if err := timerStart(cmd, []string{"PRJ-2"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := timerStop(cmd, []string{"PRJ-2"}); err == nil {
+		t.Error("timerStop() succeeded although the worklog wasn't created")
+	}
+	if _, ok := running()["PRJ-2"]; !ok {
+		t.Error("timerStop() removed the timer although the worklog wasn't created")
+	}
+
+	discardTimer = true
+	if err := timerStop(cmd, []string{"PRJ-2"}); err != nil {
+		t.Fatal(err)
+	}
+	if timers := running(); len(timers) != 0 || len(worklogs) != 1 {
+		t.Errorf("timerStop() with --discard kept %v and logged %v", timers, worklogs)
+	}
+}
@@ -0,0 +1,143 @@
+// Copyright © 2017 Adam Kunicki <kunickiaj@gmail.com>
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+
+	jira "github.com/andygrunwald/go-jira"
+	"github.com/go-git/go-git/v5"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/viper"
+
+	"github.com/kunickiaj/beer/pkg/review"
+)
+
+const (
+	verifyWarn  = "warn"
+	verifyBlock = "block"
+	verifyOff   = "off"
+)
+
+// commitKeyPattern matches the issue key brew puts at the start of a commit message.
+var commitKeyPattern = regexp.MustCompile(`^([A-Za-z][A-Za-z0-9_]*-[0-9]+)\b`)
+
+func init() {
+	viper.SetDefault("verify.mode", verifyWarn)
+	viper.SetDefault("verify.subjectLength", 72)
+}
+
+// verifyCommits checks the commits about to be published: each must start with the key of an
+// open issue, the branch's issue if it is named after one, have a short enough subject and not
+// be a fixup! or squash! commit. Merge commits are skipped. Problems are logged, and returned as
+// an error in block mode.
+func verifyCommits(repo *git.Repository, branch string) error {
+	mode := strings.ToLower(config.Verify.Mode)
+	switch mode {
+	case verifyOff:
+		return nil
+	case verifyWarn, verifyBlock:
+	default:
+		return fmt.Errorf("%w: invalid verify.mode %q, expected warn, block or off", ErrUsage, config.Verify.Mode)
+	}
+
+	problems, err := commitProblems(repo, branch)
+	for _, p := range problems {
+		log.Warn(p)
+	}
+	if err != nil {
+		if mode == verifyBlock {
+			return fmt.Errorf("unable to verify commits: %w", err)
+		}
+		log.WithError(err).Warn("Unable to verify commits")
+		return nil
+	}
+	if len(problems) > 0 && mode == verifyBlock {
+		return fmt.Errorf("%w: %d problem(s) with the commits to publish, fix them or pass --no-verify", ErrUsage, len(problems))
+	}
+	return nil
+}
+
+// commitProblems describes what is wrong with each commit between branch and HEAD. The problems
+// found so far are returned along with any error looking up the issues.
+func commitProblems(repo *git.Repository, branch string) ([]string, error) {
+	commits, err := branchCommits(repo, branch)
+	if err != nil {
+		return nil, err
+	}
+	if len(commits) == 0 {
+		return []string{fmt.Sprintf("there are no commits on top of %s to publish", branch)}, nil
+	}
+
+	// a branch created by brew is named after its issue
+	branchKey, _ := currentIssueKey(repo)
+
+	var problems []string
+	keys := map[string][]string{}
+	for _, c := range commits {
+		// merges, such as the target branch merged into the work branch, are named by git
+		if c.NumParents() > 1 {
+			continue
+		}
+		subject, _, _ := strings.Cut(strings.TrimSpace(c.Message), "\n")
+		short := c.Hash.String()[:7]
+
+		if strings.HasPrefix(subject, "fixup!") || strings.HasPrefix(subject, "squash!") {
+			problems = append(problems, fmt.Sprintf("commit %s is a %s commit, squash it first", short, strings.SplitN(subject, " ", 2)[0]))
+		}
+		if limit := config.Verify.SubjectLength; limit > 0 && len(subject) > limit {
+			problems = append(problems, fmt.Sprintf("commit %s has a %d character subject, the limit is %d", short, len(subject), limit))
+		}
+
+		match := commitKeyPattern.FindStringSubmatch(subject)
+		if match == nil {
+			problems = append(problems, fmt.Sprintf("commit %s doesn't start with an issue key: %q", short, subject))
+			continue
+		}
+		key := strings.ToUpper(match[1])
+		if branchKey != "" && key != branchKey {
+			problems = append(problems, fmt.Sprintf("commit %s references %s but the branch is for %s", short, key, branchKey))
+		}
+		keys[key] = append(keys[key], short)
+	}
+
+	if len(keys) == 0 {
+		return problems, nil
+	}
+
+	jiraClient, err := newJiraClient()
+	if err != nil {
+		return problems, err
+	}
+	for key, shas := range keys {
+		issue, res, err := jiraClient.Issue.Get(key, &jira.GetQueryOptions{Fields: "status"})
+		if err != nil {
+			err = jiraError(res, err, fmt.Sprintf("unable to fetch %s", key))
+			if errors.Is(err, review.ErrNotFound) {
+				problems = append(problems, fmt.Sprintf("commit(s) %s reference %s, which doesn't exist", strings.Join(shas, ", "), key))
+				continue
+			}
+			return problems, err
+		}
+		if status := issue.Fields.Status; status != nil && status.StatusCategory.Key == jira.StatusCategoryComplete {
+			problems = append(problems, fmt.Sprintf("commit(s) %s reference %s, which is %s", strings.Join(shas, ", "), key, status.Name))
+		}
+	}
+	return problems, nil
+}
@@ -0,0 +1,70 @@
+// Copyright © 2017 Adam Kunicki <kunickiaj@gmail.com>
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package cmd
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"path"
+	"sync"
+	"testing"
+
+	"github.com/go-git/go-git/v5/plumbing"
+)
+
+// newJiraServer serves issues whose status category is given by status, keyed by issue key.
+// Other issues don't exist. It records the issues requested.
+func newJiraServer(t *testing.T, status map[string]string) *[]string {
+	var mu sync.Mutex
+	var requested []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		key := path.Base(req.URL.Path)
+		mu.Lock()
+		requested = append(requested, key)
+		mu.Unlock()
+		category, ok := status[key]
+		if !ok {
+			http.NotFound(w, req)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = fmt.Fprintf(w, `{"key": %q, "fields": {"status": {"name": %q, "statusCategory": {"key": %q}}}}`, key, category, category)
+	}))
+	t.Cleanup(server.Close)
+	config.Jira.URL = server.URL
+	return &requested
+}
+
+func TestVerifyCommitsWithMergedTargetBranch(t *testing.T) {
+	for _, backend := range backends(t) {
+		t.Run(backend, func(t *testing.T) {
+			r := newMergedWorkBranch(t)
+			config.Git.Backend = backend
+			config.Verify.Mode = verifyBlock
+			config.Verify.SubjectLength = 72
+			// the issues of the commits on main are done, which must not block the work branch
+			requested := newJiraServer(t, map[string]string{"PRJ-1": "indeterminate", "PRJ-7": "done", "PRJ-8": "done", "PRJ-9": "done"})
+
+			if err := verifyCommits(r.repo, plumbing.Main.Short()); err != nil {
+				t.Errorf("verifyCommits() = %v, want no error", err)
+			}
+			if len(*requested) != 1 || (*requested)[0] != "PRJ-1" {
+				t.Errorf("fetched issues %v, want only PRJ-1", *requested)
+			}
+		})
+	}
+}
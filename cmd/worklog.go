@@ -0,0 +1,241 @@
+// Copyright © 2017 Adam Kunicki <kunickiaj@gmail.com>
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package cmd
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/go-git/go-git/v5/plumbing/object"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+var worklogCmd = &cobra.Command{
+	Use:   "worklog [DURATION]",
+	Short: "Log time spent on the JIRA issue of the current branch.",
+	Long: `Logs work on the issue the current work branch was brewed for, e.g.
+
+	$ beer worklog 1h30m -m "Reproduced and fixed the race"
+
+Durations are given in hours and minutes, e.g. 45m, 2h or '1h 30m'.
+
+With --suggest, the time spent is estimated from the commits on the branch since it was brewed.
+Commits closer together than worklog.sessionGap count as continuous work and each session
+starts with worklog.sessionStart of work before its first commit. When run interactively the
+estimate can be adjusted before it is logged, otherwise it is only printed.`,
+	RunE: worklog,
+	Args: usageArgs(cobra.MaximumNArgs(1)),
+}
+
+var worklogMessage string
+var worklogIssue string
+var worklogStarted string
+var suggestWorklog bool
+
+func init() {
+	RootCmd.AddCommand(worklogCmd)
+
+	worklogCmd.Flags().StringVarP(&worklogMessage, "message", "m", "", "Worklog comment")
+	worklogCmd.Flags().StringVarP(&worklogIssue, "issue", "i", "", "Log work on the given issue instead of the current branch's")
+	worklogCmd.Flags().StringVar(&worklogStarted, "started", "", "When the work started, formatted as YYYY-MM-DD HH:MM. Defaults to the duration before now")
+	worklogCmd.Flags().BoolVar(&suggestWorklog, "suggest", false, "Estimate the time spent from the branch's commits")
+
+	viper.SetDefault("worklog.sessionGap", 2*time.Hour)
+	viper.SetDefault("worklog.sessionStart", 30*time.Minute)
+}
+
+func worklog(cmd *cobra.Command, args []string) error {
+	dryRun, _ := cmd.Flags().GetBool("dry-run")
+
+	key, err := issueKeyOrBranch(worklogIssue)
+	if err != nil {
+		return err
+	}
+
+	var spent time.Duration
+	if len(args) > 0 {
+		if spent, err = parseWorkDuration(args[0]); err != nil {
+			return err
+		}
+	}
+
+	if suggestWorklog {
+		suggested, err := suggestWork(viper.GetString("defaults.branch"))
+		if err != nil {
+			return err
+		}
+		if !isInteractive() {
+			return writeResult(cmd.OutOrStdout(), worklogResult{Key: key, TimeSpent: jiraDuration(suggested), Suggested: true})
+		}
+		answer, err := prompt(bufio.NewReader(os.Stdin), os.Stderr, fmt.Sprintf("Time spent on %s", key), jiraDuration(suggested))
+		if err != nil {
+			return err
+		}
+		if spent, err = parseWorkDuration(answer); err != nil {
+			return err
+		}
+	}
+
+	if spent == 0 {
+		return fmt.Errorf("%w: a duration such as 1h30m or --suggest is required", ErrUsage)
+	}
+
+	started := time.Now().Add(-spent)
+	if worklogStarted != "" {
+		if started, err = time.ParseInLocation("2006-01-02 15:04", worklogStarted, time.Local); err != nil {
+			return fmt.Errorf("%w: invalid --started %q, expected YYYY-MM-DD HH:MM", ErrUsage, worklogStarted)
+		}
+	}
+
+	return logWork(cmd, key, started, spent, worklogMessage, dryRun)
+}
+
+// logWork posts a worklog and writes the result.
+func logWork(cmd *cobra.Command, key string, started time.Time, spent time.Duration, message string, dryRun bool) error {
+	if dryRun {
+		log.WithFields(log.Fields{"issue": key, "started": started, "timeSpent": jiraDuration(spent), "comment": message}).Info("Dry Run")
+		return nil
+	}
+
+	jiraClient, err := newJiraClient()
+	if err != nil {
+		return err
+	}
+
+	record, res, err := addWorklog(jiraClient, key, started, spent, message)
+	if err != nil {
+		return jiraError(res, err, fmt.Sprintf("unable to log work on %s", key))
+	}
+
+	return writeResult(cmd.OutOrStdout(), worklogResult{
+		Key:       key,
+		ID:        record.ID,
+		TimeSpent: jiraDuration(spent),
+		Started:   started.Format(time.RFC3339),
+	})
+}
+
+// issueKeyOrBranch returns key if given, otherwise the issue key of the current branch.
+func issueKeyOrBranch(key string) (string, error) {
+	if key != "" {
+		if !issueKeyPattern.MatchString(key) {
+			return "", fmt.Errorf("%w: %q is not an issue key", ErrUsage, key)
+		}
+		return strings.ToUpper(key), nil
+	}
+
+	repo, err := openRepo()
+	if err != nil {
+		return "", err
+	}
+	return currentIssueKey(repo)
+}
+
+// parseWorkDuration parses durations such as 45m, 2h or '1h 30m', rounded to the minute.
+func parseWorkDuration(s string) (time.Duration, error) {
+	d, err := time.ParseDuration(strings.ReplaceAll(s, " ", ""))
+	if err != nil || d < time.Minute {
+		return 0, fmt.Errorf("%w: invalid duration %q, expected e.g. 45m, 2h or 1h30m", ErrUsage, s)
+	}
+	return d.Round(time.Minute), nil
+}
+
+// jiraDuration formats a duration the way JIRA displays time spent, e.g. "1h 30m".
+func jiraDuration(d time.Duration) string {
+	d = d.Round(time.Minute)
+	hours, minutes := int(d.Hours()), int(d.Minutes())%60
+	switch {
+	case hours == 0:
+		return fmt.Sprintf("%dm", minutes)
+	case minutes == 0:
+		return fmt.Sprintf("%dh", hours)
+	}
+	return fmt.Sprintf("%dh %dm", hours, minutes)
+}
+
+// suggestWork estimates the time spent on the current branch from its commits.
+func suggestWork(branch string) (time.Duration, error) {
+	repo, err := openRepo()
+	if err != nil {
+		return 0, err
+	}
+	commits, err := branchCommits(repo, branch)
+	if err != nil {
+		return 0, err
+	}
+	if len(commits) == 0 {
+		return 0, fmt.Errorf("%w: no commits on the branch since %s to estimate from", ErrUsage, branch)
+	}
+
+	estimate := estimateWork(commits, config.Worklog.SessionGap, config.Worklog.SessionStart)
+	log.WithFields(log.Fields{"commits": len(commits), "estimate": jiraDuration(estimate)}).Debug("Estimated work from commits")
+	return estimate, nil
+}
+
+// estimateWork adds up the time between commit activity, splitting it into sessions wherever
+// there is more than gap between two commits. Amending a commit updates its committer time, so
+// both author and committer times count as activity.
+func estimateWork(commits []*object.Commit, gap time.Duration, sessionStart time.Duration) time.Duration {
+	var times []time.Time
+	for _, c := range commits {
+		times = append(times, c.Author.When, c.Committer.When)
+	}
+	sort.Slice(times, func(i, j int) bool { return times[i].Before(times[j]) })
+
+	total := sessionStart
+	for i := 1; i < len(times); i++ {
+		if d := times[i].Sub(times[i-1]); d <= gap {
+			total += d
+		} else {
+			total += sessionStart
+		}
+	}
+
+	const quarter = 15 * time.Minute
+	if total = total.Round(quarter); total < quarter {
+		total = quarter
+	}
+	return total
+}
+
+// worklogResult describes time logged, or suggested, by worklog.
+type worklogResult struct {
+	Key       string `json:"key" yaml:"key"`
+	ID        string `json:"id,omitempty" yaml:"id,omitempty"`
+	TimeSpent string `json:"timeSpent" yaml:"timeSpent"`
+	Started   string `json:"started,omitempty" yaml:"started,omitempty"`
+	Suggested bool   `json:"suggested,omitempty" yaml:"suggested,omitempty"`
+}
+
+func (r worklogResult) message() string {
+	if r.Suggested {
+		return "Suggested worklog"
+	}
+	return "Logged work"
+}
+
+func (r worklogResult) fields() log.Fields {
+	if r.Suggested {
+		return log.Fields{"key": r.Key, "timeSpent": r.TimeSpent}
+	}
+	return log.Fields{"key": r.Key, "id": r.ID, "timeSpent": r.TimeSpent, "started": r.Started}
+}
@@ -0,0 +1,115 @@
+// Copyright © 2017 Adam Kunicki <kunickiaj@gmail.com>
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package cmd
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/go-git/go-git/v5/plumbing/object"
+)
+
+func TestParseWorkDuration(t *testing.T) {
+	tests := []struct {
+		input   string
+		want    time.Duration
+		wantErr bool
+	}{
+		{input: "45m", want: 45 * time.Minute},
+		{input: "2h", want: 2 * time.Hour},
+		{input: "1h 30m", want: 90 * time.Minute},
+		{input: "1h30m", want: 90 * time.Minute},
+		{input: "90s", want: 2 * time.Minute},
+		{input: "59s", wantErr: true},
+		{input: "-1h", wantErr: true},
+		{input: "1d", wantErr: true},
+		{input: "", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			got, err := parseWorkDuration(tt.input)
+			if tt.wantErr {
+				if !errors.Is(err, ErrUsage) {
+					t.Errorf("parseWorkDuration() = %s, %v, want a usage error", got, err)
+				}
+				return
+			}
+			if err != nil || got != tt.want {
+				t.Errorf("parseWorkDuration() = %s, %v, want %s", got, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestJiraDuration(t *testing.T) {
+	tests := []struct {
+		d    time.Duration
+		want string
+	}{
+		{d: 0, want: "0m"},
+		{d: 45 * time.Minute, want: "45m"},
+		{d: 2 * time.Hour, want: "2h"},
+		{d: 90 * time.Minute, want: "1h 30m"},
+		{d: 26*time.Hour + 5*time.Minute, want: "26h 5m"},
+		{d: 89*time.Minute + 40*time.Second, want: "1h 30m"},
+	}
+	for _, tt := range tests {
+		if got := jiraDuration(tt.d); got != tt.want {
+			t.Errorf("jiraDuration(%s) = %s, want %s", tt.d, got, tt.want)
+		}
+		if parsed, err := parseWorkDuration(tt.want); tt.d >= time.Minute && (err != nil || parsed != tt.d.Round(time.Minute)) {
+			t.Errorf("parseWorkDuration(%s) = %s, %v, want %s", tt.want, parsed, err, tt.d.Round(time.Minute))
+		}
+	}
+}
+
+func TestEstimateWork(t *testing.T) {
+	start := time.Date(2024, 1, 1, 9, 0, 0, 0, time.UTC)
+	at := func(minutes ...int) *object.Commit {
+		authored := start.Add(time.Duration(minutes[0]) * time.Minute)
+		committed := authored
+		if len(minutes) > 1 {
+			committed = start.Add(time.Duration(minutes[1]) * time.Minute)
+		}
+		return &object.Commit{Author: object.Signature{When: authored}, Committer: object.Signature{When: committed}}
+	}
+	const gap, sessionStart = 2 * time.Hour, 30 * time.Minute
+
+	tests := []struct {
+		name    string
+		commits []*object.Commit
+		want    time.Duration
+	}{
+		{name: "single commit", commits: []*object.Commit{at(0)}, want: 30 * time.Minute},
+		{name: "one session", commits: []*object.Commit{at(0), at(40), at(100)}, want: 2*time.Hour + 15*time.Minute},
+		{name: "commits in any order", commits: []*object.Commit{at(100), at(0), at(40)}, want: 2*time.Hour + 15*time.Minute},
+		{name: "two sessions", commits: []*object.Commit{at(0), at(60), at(600), at(630)}, want: 2*time.Hour + 30*time.Minute},
+		{name: "amended commit counts its committer time", commits: []*object.Commit{at(0, 90)}, want: 2 * time.Hour},
+		{name: "rounded to a quarter", commits: []*object.Commit{at(0), at(8)}, want: 45 * time.Minute},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := estimateWork(tt.commits, gap, sessionStart); got != tt.want {
+				t.Errorf("estimateWork() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+
+	if got := estimateWork([]*object.Commit{at(0)}, gap, 0); got != 15*time.Minute {
+		t.Errorf("estimateWork() without a session start = %s, want at least a quarter hour", got)
+	}
+}
@@ -0,0 +1,140 @@
+// Copyright © 2017 Adam Kunicki <kunickiaj@gmail.com>
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package cmd
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	jira "github.com/andygrunwald/go-jira"
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/mitchellh/go-homedir"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+// useWorktrees reports whether brew should create a linked worktree per issue, from --worktree
+// or defaults.worktrees.
+func useWorktrees(cmd *cobra.Command) bool {
+	if f := cmd.Flags().Lookup("worktree"); f != nil && f.Changed {
+		return f.Value.String() == "true"
+	}
+	return viper.GetBool("defaults.worktrees")
+}
+
+// mainWorktree returns the root of the repository's main working tree, also when run from a
+// linked worktree.
+func mainWorktree() (string, error) {
+	commonDir, err := runGit("rev-parse", "--path-format=absolute", "--git-common-dir")
+	if err != nil {
+		return "", err
+	}
+	return filepath.Dir(commonDir), nil
+}
+
+// worktreeDir returns the directory linked worktrees are created in: defaults.worktreeDir,
+// relative to the main working tree, or <REPO>.worktrees next to it.
+func worktreeDir() (string, error) {
+	root, err := mainWorktree()
+	if err != nil {
+		return "", err
+	}
+
+	dir := viper.GetString("defaults.worktreeDir")
+	if dir == "" {
+		return filepath.Join(filepath.Dir(root), filepath.Base(root)+".worktrees"), nil
+	}
+	if dir, err = homedir.Expand(dir); err != nil {
+		return "", err
+	}
+	if !filepath.IsAbs(dir) {
+		dir = filepath.Join(root, dir)
+	}
+	return dir, nil
+}
+
+// listWorktrees returns the paths of the linked and main worktrees keyed by checked out branch.
+func listWorktrees() (map[string]string, error) {
+	out, err := runGit("worktree", "list", "--porcelain")
+	if err != nil {
+		return nil, err
+	}
+
+	worktrees := map[string]string{}
+	var path string
+	for _, line := range strings.Split(out, "\n") {
+		switch {
+		case strings.HasPrefix(line, "worktree "):
+			path = strings.TrimPrefix(line, "worktree ")
+		case strings.HasPrefix(line, "branch "):
+			worktrees[plumbing.ReferenceName(strings.TrimPrefix(line, "branch ")).Short()] = path
+		}
+	}
+	return worktrees, nil
+}
+
+// checkoutWorktree is the worktree mode counterpart of checkout. It creates a linked worktree for
+// the issue's branch, creating the branch along with its seed commit if needed, and returns the
+// worktree's path and the commit the branch points to.
+func checkoutWorktree(repo *git.Repository, issue *jira.Issue) (string, plumbing.Hash, error) {
+	worktrees, err := listWorktrees()
+	if err != nil {
+		return "", plumbing.ZeroHash, err
+	}
+
+	branch := plumbing.NewBranchReferenceName(issue.Key)
+	ref, refErr := repo.Reference(branch, true)
+	if path, ok := worktrees[issue.Key]; ok && refErr == nil {
+		log.WithField("worktree", path).Debug("Using existing worktree")
+		return path, ref.Hash(), nil
+	}
+
+	dir, err := worktreeDir()
+	if err != nil {
+		return "", plumbing.ZeroHash, err
+	}
+	path := filepath.Join(dir, issue.Key)
+
+	if refErr == nil {
+		if _, err := runGit("worktree", "add", "--quiet", path, issue.Key); err != nil {
+			return "", plumbing.ZeroHash, err
+		}
+		return path, ref.Hash(), nil
+	}
+
+	if _, err := runGit("worktree", "add", "--quiet", "-b", issue.Key, path, "HEAD"); err != nil {
+		return "", plumbing.ZeroHash, err
+	}
+	linked, err := git.PlainOpenWithOptions(path, &git.PlainOpenOptions{EnableDotGitCommonDir: true})
+	if err != nil {
+		return "", plumbing.ZeroHash, fmt.Errorf("unable to open worktree %s: %w", path, err)
+	}
+	backend, err := gitBackend(linked)
+	if err != nil {
+		return "", plumbing.ZeroHash, err
+	}
+	hash, err := seedCommit(linked, backend, issue)
+	return path, hash, err
+}
+
+// removeWorktree removes a linked worktree. git refuses if it has uncommitted changes.
+func removeWorktree(path string) error {
+	_, err := runGit("worktree", "remove", path)
+	return err
+}
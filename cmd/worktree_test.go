@@ -0,0 +1,213 @@
+// Copyright © 2017 Adam Kunicki <kunickiaj@gmail.com>
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package cmd
+
+import (
+	"bytes"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	jira "github.com/andygrunwald/go-jira"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/mitchellh/go-homedir"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+// newWorktreeRepo creates a repository with a commit on main and changes the working directory
+// to it. The returned path is the repository's directory with symlinks resolved, as git reports
+// it.
+func newWorktreeRepo(t *testing.T) (*testRepo, string) {
+	t.Helper()
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not found")
+	}
+	r := newTestRepo(t)
+	r.commit("PRJ-7. r1")
+	root, err := filepath.EvalSymlinks(r.dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Chdir(root)
+	return r, root
+}
+
+func TestWorktreeDir(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	homedir.DisableCache = true
+	t.Cleanup(func() { homedir.DisableCache = false })
+
+	tests := []struct {
+		name string
+		dir  string // defaults.worktreeDir
+		want func(root string) string
+	}{
+		{name: "next to the repository", want: func(root string) string { return root + ".worktrees" }},
+		{name: "relative to the repository", dir: "../trees", want: func(root string) string { return filepath.Join(filepath.Dir(root), "trees") }},
+		{name: "in the home directory", dir: "~/trees", want: func(string) string { return filepath.Join(home, "trees") }},
+		{name: "absolute", dir: "/srv/trees", want: func(string) string { return "/srv/trees" }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, root := newWorktreeRepo(t)
+			if tt.dir != "" {
+				viper.Set("defaults.worktreeDir", tt.dir)
+				t.Cleanup(func() { viper.Set("defaults.worktreeDir", nil) })
+			}
+			got, err := worktreeDir()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if want := tt.want(root); got != want {
+				t.Errorf("worktreeDir() = %s, want %s", got, want)
+			}
+
+			// linked worktrees are found in the same place from within a linked worktree
+			linked := filepath.Join(t.TempDir(), "linked")
+			gitOutput(t, "worktree", "add", "--quiet", "-b", "PRJ-9", linked)
+			t.Chdir(linked)
+			if got, err := worktreeDir(); err != nil || got != tt.want(root) {
+				t.Errorf("worktreeDir() in a linked worktree = %s, %v, want %s", got, err, tt.want(root))
+			}
+		})
+	}
+}
+
+func TestUseWorktrees(t *testing.T) {
+	tests := []struct {
+		name    string
+		flags   []string
+		enabled bool // defaults.worktrees
+		want    bool
+	}{
+		{name: "off by default"},
+		{name: "defaults.worktrees", enabled: true, want: true},
+		{name: "flag", flags: []string{"--worktree"}, want: true},
+		{name: "flag overrides defaults.worktrees", flags: []string{"--worktree=false"}, enabled: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			viper.Set("defaults.worktrees", tt.enabled)
+			t.Cleanup(func() { viper.Set("defaults.worktrees", nil) })
+			cmd := &cobra.Command{}
+			cmd.Flags().Bool("worktree", false, "")
+			if err := cmd.Flags().Parse(tt.flags); err != nil {
+				t.Fatal(err)
+			}
+			if got := useWorktrees(cmd); got != tt.want {
+				t.Errorf("useWorktrees() = %t, want %t", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCheckoutWorktree(t *testing.T) {
+	for _, backend := range backends(t) {
+		t.Run(backend, func(t *testing.T) {
+			r, root := newWorktreeRepo(t)
+			config.Git.Backend = backend
+			main, err := r.repo.Head()
+			if err != nil {
+				t.Fatal(err)
+			}
+			issue := &jira.Issue{Key: "PRJ-1", Fields: &jira.IssueFields{Summary: "Fix login", Description: "Fix login"}}
+
+			path, hash, err := checkoutWorktree(r.repo, issue)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if want := filepath.Join(root+".worktrees", "PRJ-1"); path != want {
+				t.Errorf("checkoutWorktree() = %s, want %s", path, want)
+			}
+			seed, err := r.repo.CommitObject(hash)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if strings.TrimSpace(seed.Message) != "PRJ-1. Fix login" || len(seed.ParentHashes) != 1 || seed.ParentHashes[0] != main.Hash() {
+				t.Errorf("seed commit = %q with parents %v, want PRJ-1's seed on top of main", seed.Message, seed.ParentHashes)
+			}
+			if ref, err := r.repo.Reference(plumbing.NewBranchReferenceName("PRJ-1"), true); err != nil || ref.Hash() != hash {
+				t.Errorf("PRJ-1 = %v, %v, want the seed commit", ref, err)
+			}
+			if head, err := r.repo.Head(); err != nil || head.Name() != main.Name() {
+				t.Errorf("main worktree switched to %v, %v", head, err)
+			}
+			if worktrees, err := listWorktrees(); err != nil || worktrees["PRJ-1"] != path || worktrees[plumbing.Main.Short()] != root {
+				t.Errorf("listWorktrees() = %v, %v, want PRJ-1 in %s", worktrees, err, path)
+			}
+
+			// an existing worktree is reused
+			again, againHash, err := checkoutWorktree(r.repo, issue)
+			if err != nil || again != path || againHash != hash {
+				t.Errorf("checkoutWorktree() again = %s, %s, %v, want %s, %s", again, againHash, err, path, hash)
+			}
+
+			// an existing branch gets a worktree without a new seed commit
+			gitOutput(t, "branch", "PRJ-2")
+			path, hash, err = checkoutWorktree(r.repo, &jira.Issue{Key: "PRJ-2", Fields: &jira.IssueFields{Summary: "Other"}})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if path != filepath.Join(root+".worktrees", "PRJ-2") || hash != main.Hash() {
+				t.Errorf("checkoutWorktree() of an existing branch = %s, %s, want it at %s", path, hash, main.Hash())
+			}
+		})
+	}
+}
+
+func TestCleanupRemovesWorktrees(t *testing.T) {
+	r, root := newWorktreeRepo(t)
+	for _, key := range []string{"PRJ-1", "PRJ-2"} {
+		gitOutput(t, "worktree", "add", "--quiet", "-b", key, filepath.Join(root+".worktrees", key))
+	}
+	// git refuses to remove a worktree with uncommitted changes
+	if err := os.WriteFile(filepath.Join(root+".worktrees", "PRJ-2", "wip.txt"), []byte("wip\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	config.ReviewTool = Gerrit
+	// the branches' commits have no Change-Id, so Gerrit isn't asked
+	config.Gerrit.WebURL = "https://gerrit.invalid"
+	newJiraServer(t, map[string]string{"PRJ-1": "done", "PRJ-2": "done"})
+	assumeYes = true
+	t.Cleanup(func() { assumeYes = false })
+	cmd := &cobra.Command{}
+	cmd.Flags().Bool("dry-run", false, "")
+	cmd.SetOut(&bytes.Buffer{})
+
+	if err := cleanup(cmd, nil); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(filepath.Join(root+".worktrees", "PRJ-1")); !os.IsNotExist(err) {
+		t.Errorf("cleanup() kept PRJ-1's worktree: %v", err)
+	}
+	if _, err := r.repo.Reference(plumbing.NewBranchReferenceName("PRJ-1"), false); err == nil {
+		t.Error("cleanup() kept PRJ-1")
+	}
+	if _, err := os.Stat(filepath.Join(root+".worktrees", "PRJ-2", "wip.txt")); err != nil {
+		t.Errorf("cleanup() removed PRJ-2's worktree with uncommitted changes: %v", err)
+	}
+	if _, err := r.repo.Reference(plumbing.NewBranchReferenceName("PRJ-2"), false); err != nil {
+		t.Errorf("cleanup() deleted PRJ-2 although its worktree was kept: %v", err)
+	}
+	if worktrees, err := listWorktrees(); err != nil || len(worktrees) != 2 || worktrees["PRJ-1"] != "" {
+		t.Errorf("listWorktrees() = %v, %v, want main and PRJ-2", worktrees, err)
+	}
+}
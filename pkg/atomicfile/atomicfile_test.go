@@ -0,0 +1,42 @@
+package atomicfile
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestWriteFile(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "timers.json")
+	for _, content := range []string{"first", "second"} {
+		if err := WriteFile(file, []byte(content)); err != nil {
+			t.Fatal(err)
+		}
+		data, err := os.ReadFile(file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(data) != content {
+			t.Errorf("ReadFile() = %q, want %q", data, content)
+		}
+	}
+
+	// a failed write leaves neither a temporary file nor a changed file behind
+	if err := WriteFile(filepath.Join(dir, "missing", "timers.json"), []byte("third")); err == nil {
+		t.Error("WriteFile() into a missing directory succeeded")
+	}
+	if err := os.Mkdir(filepath.Join(dir, "dir.json"), 0o700); err != nil {
+		t.Fatal(err)
+	}
+	if err := WriteFile(filepath.Join(dir, "dir.json"), []byte("third")); err == nil {
+		t.Error("WriteFile() over a directory succeeded")
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 2 {
+		t.Errorf("directory has %d entries, want only timers.json and dir.json", len(entries))
+	}
+}
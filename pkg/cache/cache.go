@@ -0,0 +1,91 @@
+package cache
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/kunickiaj/beer/pkg/atomicfile"
+)
+
+// unsafeChars matches characters that shouldn't appear in cache file names.
+var unsafeChars = regexp.MustCompile(`[^a-zA-Z0-9._-]+`)
+
+// Cache stores JSON encoded values as files in a directory. Keys are slash separated paths,
+// e.g. createmeta/PRJ/Bug.
+type Cache struct {
+	Dir string
+}
+
+// ErrMiss is returned when a key isn't in the cache.
+var ErrMiss = errors.New("cache miss")
+
+// New returns a cache in beer's directory under dir, or under the user's cache directory
+// ($XDG_CACHE_HOME on Linux) when dir is empty. The cache always has a directory of its own so
+// that Clear never removes anything else.
+func New(dir string) (*Cache, error) {
+	if dir == "" {
+		base, err := os.UserCacheDir()
+		if err != nil {
+			return nil, err
+		}
+		dir = base
+	}
+	return &Cache{Dir: filepath.Join(dir, "beer")}, nil
+}
+
+// Get decodes the value stored under key into v and reports whether it is younger than ttl.
+// ErrMiss is returned if nothing is stored under key.
+func (c *Cache) Get(key string, ttl time.Duration, v interface{}) (fresh bool, err error) {
+	file := c.path(key)
+	info, err := os.Stat(file)
+	if os.IsNotExist(err) {
+		return false, ErrMiss
+	} else if err != nil {
+		return false, err
+	}
+
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return false, err
+	}
+	if err := json.Unmarshal(data, v); err != nil {
+		return false, err
+	}
+	return time.Since(info.ModTime()) < ttl, nil
+}
+
+// Put stores v under key.
+func (c *Cache) Put(key string, v interface{}) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+
+	file := c.path(key)
+	if err := os.MkdirAll(filepath.Dir(file), 0o700); err != nil {
+		return err
+	}
+
+	return atomicfile.WriteFile(file, data)
+}
+
+// Clear removes everything in the cache, which is beer's own directory.
+func (c *Cache) Clear() error {
+	return os.RemoveAll(c.Dir)
+}
+
+func (c *Cache) path(key string) string {
+	parts := strings.Split(key, "/")
+	for i, p := range parts {
+		parts[i] = unsafeChars.ReplaceAllString(p, "_")
+		if parts[i] == "." || parts[i] == ".." {
+			parts[i] = "_"
+		}
+	}
+	return filepath.Join(c.Dir, filepath.Join(parts...)+".json")
+}
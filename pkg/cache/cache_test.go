@@ -0,0 +1,56 @@
+package cache
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestCache(t *testing.T) {
+	dir := t.TempDir()
+	c, err := New(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.Dir != filepath.Join(dir, "beer") {
+		t.Errorf("New(%s).Dir = %s, want beer's own directory", dir, c.Dir)
+	}
+
+	var value []string
+	if _, err := c.Get("createmeta/PRJ/Bug", time.Hour, &value); !errors.Is(err, ErrMiss) {
+		t.Errorf("Get() of a missing key = %v, want ErrMiss", err)
+	}
+	if err := c.Put("createmeta/PRJ/Bug", []string{"Summary"}); err != nil {
+		t.Fatal(err)
+	}
+	fresh, err := c.Get("createmeta/PRJ/Bug", time.Hour, &value)
+	if err != nil || !fresh || len(value) != 1 || value[0] != "Summary" {
+		t.Errorf("Get() = %v, %t, %v, want fresh [Summary]", value, fresh, err)
+	}
+	if fresh, err := c.Get("createmeta/PRJ/Bug", 0, &value); err != nil || fresh {
+		t.Errorf("Get() with no ttl = %t, %v, want an expired value", fresh, err)
+	}
+	// keys can't escape the cache directory
+	if err := c.Put("../../escaped", "x"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(filepath.Join(c.Dir, "_", "_", "escaped.json")); err != nil {
+		t.Errorf("Put() didn't keep the key in the cache directory: %v", err)
+	}
+
+	other := filepath.Join(dir, "other.json")
+	if err := os.WriteFile(other, []byte("{}"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Clear(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.Get("createmeta/PRJ/Bug", time.Hour, &value); !errors.Is(err, ErrMiss) {
+		t.Errorf("Get() after Clear() = %v, want ErrMiss", err)
+	}
+	if _, err := os.Stat(other); err != nil {
+		t.Errorf("Clear() removed a file it doesn't own: %v", err)
+	}
+}
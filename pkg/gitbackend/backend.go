@@ -0,0 +1,64 @@
+package gitbackend
+
+import (
+	"io"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/go-git/go-git/v5/plumbing/transport"
+)
+
+// Names of the backends selected by git.backend
+const (
+	GoGitName = "gogit"
+	ExecName  = "exec"
+)
+
+// Backend performs the git operations that change a repository or talk to remotes. GoGit runs
+// them in process while Exec runs the git command line tool, which supports the user's full SSH
+// configuration, proxies and hooks.
+type Backend interface {
+	// Open finds the repository containing dir, which the backend then operates on. The
+	// repository is read with go-git either way.
+	Open(dir string) (*git.Repository, error)
+	// Checkout switches the working tree to a local branch, creating it from HEAD if create is set.
+	// Uncommitted changes are kept.
+	Checkout(branch string, create bool) error
+	// Commit records the staged changes and returns the new commit.
+	Commit(message string, opts CommitOptions) (plumbing.Hash, error)
+	// Push updates refs on a remote.
+	Push(opts PushOptions) error
+	// Fetch updates refs from a remote. It isn't an error if they are already up to date.
+	Fetch(opts FetchOptions) error
+	// Log returns the commits on the first parent history of from down to where it diverged
+	// from base, newest first.
+	Log(from plumbing.Hash, base plumbing.Hash) ([]*object.Commit, error)
+}
+
+// CommitOptions describes how a commit is created.
+type CommitOptions struct {
+	Author     *object.Signature
+	Committer  *object.Signature
+	AllowEmpty bool // Create the commit even if nothing is staged
+	NoSign     bool // Don't sign the commit even if commit.gpgsign is set
+	NoVerify   bool // Skip the pre-commit and commit-msg hooks
+}
+
+// PushOptions describes what is pushed where.
+type PushOptions struct {
+	Remote   string               // Name of the remote
+	URL      string               // URL to push to, used by GoGit since go-git doesn't apply pushurl or insteadOf
+	RefSpecs []string             // e.g. HEAD:refs/for/main
+	Auth     transport.AuthMethod // Credentials used by GoGit, Exec lets git find its own
+	Progress io.Writer            // Receives the messages sent back by the remote, may be nil
+	NoVerify bool                 // Skip the pre-push hook
+}
+
+// FetchOptions describes what is fetched from where.
+type FetchOptions struct {
+	Remote   string               // Name of the remote
+	URL      string               // URL to fetch from, used by GoGit
+	RefSpecs []string             // e.g. +refs/heads/main:refs/remotes/origin/main
+	Auth     transport.AuthMethod // Credentials used by GoGit, Exec lets git find its own
+}
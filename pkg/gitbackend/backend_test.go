@@ -0,0 +1,208 @@
+package gitbackend
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/config"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+)
+
+// fixture is a repository with a work branch that had main merged into it:
+//
+//	main:  r1 - r2 - r3
+//	         \         \
+//	PRJ-1:    w1 - w2 - merge - w3
+type fixture struct {
+	dir     string
+	commits map[string]plumbing.Hash
+}
+
+var testSignature = object.Signature{Name: "Test", Email: "test@example.com", When: time.Date(2024, 1, 1, 9, 0, 0, 0, time.UTC)}
+
+func newFixture(t *testing.T) *fixture {
+	t.Helper()
+	t.Setenv("GIT_CONFIG_GLOBAL", os.DevNull)
+	t.Setenv("GIT_CONFIG_NOSYSTEM", "1")
+
+	f := &fixture{dir: t.TempDir(), commits: map[string]plumbing.Hash{}}
+	repo, err := git.PlainInitWithOptions(f.dir, &git.PlainInitOptions{InitOptions: git.InitOptions{DefaultBranch: plumbing.Main}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	workTree, err := repo.Worktree()
+	if err != nil {
+		t.Fatal(err)
+	}
+	sig := testSignature
+	commit := func(name string, parents ...string) {
+		sig.When = sig.When.Add(time.Minute)
+		opts := &git.CommitOptions{Author: &sig, Committer: &sig, AllowEmptyCommits: true}
+		for _, p := range parents {
+			opts.Parents = append(opts.Parents, f.commits[p])
+		}
+		if f.commits[name], err = workTree.Commit(name, opts); err != nil {
+			t.Fatal(err)
+		}
+	}
+	checkout := func(branch string, create bool) {
+		if err := workTree.Checkout(&git.CheckoutOptions{Branch: plumbing.NewBranchReferenceName(branch), Create: create}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	commit("r1")
+	checkout("PRJ-1", true)
+	commit("w1")
+	commit("w2")
+	checkout("main", false)
+	commit("r2")
+	commit("r3")
+	checkout("PRJ-1", false)
+	commit("merge", "w2", "r3")
+	commit("w3")
+
+	if err := os.Mkdir(filepath.Join(f.dir, "sub"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	return f
+}
+
+// backends returns a new instance of each backend whose prerequisites are available.
+func backends(t *testing.T) map[string]func() Backend {
+	all := map[string]func() Backend{GoGitName: func() Backend { return &GoGit{} }}
+	if _, err := exec.LookPath("git"); err == nil {
+		all[ExecName] = func() Backend { return &Exec{} }
+	} else {
+		t.Log("git not found, skipping the exec backend")
+	}
+	return all
+}
+
+func TestLog(t *testing.T) {
+	tests := []struct {
+		name string
+		from string
+		base string
+		want string
+	}{
+		{name: "merged target branch", from: "w3", base: "r3", want: "w3 merge w2 w1"},
+		{name: "target branch moved on", from: "w2", base: "r3", want: "w2 w1"},
+		{name: "stale target branch", from: "w3", base: "r1", want: "w3 merge w2 w1"},
+		{name: "up to date", from: "r3", base: "r3", want: ""},
+		{name: "behind target branch", from: "r1", base: "r3", want: ""},
+	}
+
+	for name, newBackend := range backends(t) {
+		t.Run(name, func(t *testing.T) {
+			f := newFixture(t)
+			backend := newBackend()
+			if _, err := backend.Open(f.dir); err != nil {
+				t.Fatal(err)
+			}
+			for _, tt := range tests {
+				t.Run(tt.name, func(t *testing.T) {
+					commits, err := backend.Log(f.commits[tt.from], f.commits[tt.base])
+					if err != nil {
+						t.Fatal(err)
+					}
+					var got []string
+					for _, c := range commits {
+						got = append(got, strings.TrimSpace(c.Message))
+					}
+					if strings.Join(got, " ") != tt.want {
+						t.Errorf("Log(%s, %s) = %v, want %s", tt.from, tt.base, got, tt.want)
+					}
+				})
+			}
+		})
+	}
+}
+
+func TestOperations(t *testing.T) {
+	for name, newBackend := range backends(t) {
+		t.Run(name, func(t *testing.T) {
+			f := newFixture(t)
+			backend := newBackend()
+
+			repo, err := backend.Open(filepath.Join(f.dir, "sub"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			head, err := repo.Head()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if head.Hash() != f.commits["w3"] {
+				t.Errorf("Open() found HEAD at %s, want w3 %s", head.Hash(), f.commits["w3"])
+			}
+
+			if err := backend.Checkout("PRJ-2", true); err != nil {
+				t.Fatal(err)
+			}
+			sig := testSignature
+			hash, err := backend.Commit("PRJ-2. Seed\n", CommitOptions{Author: &sig, Committer: &sig, AllowEmpty: true, NoSign: true})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if head, err = repo.Head(); err != nil {
+				t.Fatal(err)
+			}
+			if head.Name().Short() != "PRJ-2" || head.Hash() != hash {
+				t.Errorf("HEAD is %s at %s, want PRJ-2 at %s", head.Name().Short(), head.Hash(), hash)
+			}
+			commit, err := repo.CommitObject(hash)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if commit.Message != "PRJ-2. Seed\n" || commit.Author.Email != sig.Email || len(commit.ParentHashes) != 1 || commit.ParentHashes[0] != f.commits["w3"] {
+				t.Errorf("Commit() created %+v", commit)
+			}
+
+			if _, err := exec.LookPath("git"); err != nil {
+				t.Skip("git not found, which local remotes need")
+			}
+			remoteDir := t.TempDir()
+			if _, err := git.PlainInit(remoteDir, true); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := repo.CreateRemote(&config.RemoteConfig{Name: "origin", URLs: []string{remoteDir}}); err != nil {
+				t.Fatal(err)
+			}
+
+			err = backend.Push(PushOptions{Remote: "origin", URL: remoteDir, RefSpecs: []string{"refs/heads/PRJ-2:refs/heads/PRJ-2", "refs/heads/main:refs/heads/main"}})
+			if err != nil {
+				t.Fatal(err)
+			}
+			remote, err := git.PlainOpen(remoteDir)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if ref, err := remote.Reference(plumbing.NewBranchReferenceName("PRJ-2"), true); err != nil || ref.Hash() != hash {
+				t.Errorf("Push() left PRJ-2 on the remote at %v, %v, want %s", ref, err, hash)
+			}
+			// pushing again isn't an error
+			if err := backend.Push(PushOptions{Remote: "origin", URL: remoteDir, RefSpecs: []string{"refs/heads/PRJ-2:refs/heads/PRJ-2"}}); err != nil {
+				t.Errorf("Push() of up to date refs = %v", err)
+			}
+
+			err = backend.Fetch(FetchOptions{Remote: "origin", URL: remoteDir, RefSpecs: []string{"+refs/heads/main:refs/remotes/origin/main"}})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if ref, err := repo.Reference(plumbing.NewRemoteReferenceName("origin", "main"), true); err != nil || ref.Hash() != f.commits["r3"] {
+				t.Errorf("Fetch() left origin/main at %v, %v, want r3 %s", ref, err, f.commits["r3"])
+			}
+			// fetching again isn't an error
+			if err := backend.Fetch(FetchOptions{Remote: "origin", URL: remoteDir, RefSpecs: []string{"+refs/heads/main:refs/remotes/origin/main"}}); err != nil {
+				t.Errorf("Fetch() of up to date refs = %v", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,199 @@
+package gitbackend
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	log "github.com/sirupsen/logrus"
+)
+
+// logFormat separates the fields of each commit printed by git log with NUL bytes. With -z the
+// commits themselves are NUL terminated too, so the body, which may contain anything else, comes
+// last.
+const logFormat = "%H%x00%T%x00%P%x00%an%x00%ae%x00%aI%x00%cn%x00%ce%x00%cI%x00%B"
+
+// logFields is the number of fields in logFormat
+const logFields = 10
+
+// Exec runs git operations with the git command line tool in the working tree Dir.
+type Exec struct {
+	Dir string
+}
+
+// NewExec returns a backend running git in the working tree at dir.
+func NewExec(dir string) *Exec {
+	return &Exec{Dir: dir}
+}
+
+func (e *Exec) Open(dir string) (*git.Repository, error) {
+	// git finds the working tree, honouring GIT_DIR, GIT_WORK_TREE and safe.directory
+	e.Dir = dir
+	top, err := e.run(nil, nil, "rev-parse", "--show-toplevel")
+	if err != nil {
+		return nil, err
+	}
+	e.Dir = strings.TrimSpace(top)
+	return git.PlainOpenWithOptions(e.Dir, &git.PlainOpenOptions{EnableDotGitCommonDir: true})
+}
+
+func (e *Exec) Checkout(branch string, create bool) error {
+	args := []string{"checkout", "--quiet"}
+	if create {
+		args = append(args, "-b")
+	}
+	_, err := e.run(nil, nil, append(args, branch)...)
+	return err
+}
+
+func (e *Exec) Commit(message string, opts CommitOptions) (plumbing.Hash, error) {
+	args := []string{"commit", "--quiet", "--file", "-"}
+	if opts.AllowEmpty {
+		args = append(args, "--allow-empty")
+	}
+	if opts.NoSign {
+		args = append(args, "--no-gpg-sign")
+	}
+	if opts.NoVerify {
+		args = append(args, "--no-verify")
+	}
+	env := append(identityEnv("AUTHOR", opts.Author), identityEnv("COMMITTER", opts.Committer)...)
+	if _, err := e.run(strings.NewReader(message), env, args...); err != nil {
+		return plumbing.ZeroHash, err
+	}
+
+	head, err := e.run(nil, nil, "rev-parse", "HEAD")
+	if err != nil {
+		return plumbing.ZeroHash, err
+	}
+	return plumbing.NewHash(strings.TrimSpace(head)), nil
+}
+
+func (e *Exec) Push(opts PushOptions) error {
+	// git resolves the remote's URL and credentials itself, including pushurl and ssh config
+	args := []string{"push", "--progress"}
+	if opts.NoVerify {
+		args = append(args, "--no-verify")
+	}
+	args = append(append(args, opts.Remote), opts.RefSpecs...)
+	return e.stream(opts.Progress, args...)
+}
+
+func (e *Exec) Fetch(opts FetchOptions) error {
+	args := append([]string{"fetch", "--quiet", opts.Remote}, opts.RefSpecs...)
+	return e.stream(nil, args...)
+}
+
+func (e *Exec) Log(from plumbing.Hash, base plumbing.Hash) ([]*object.Commit, error) {
+	out, err := e.run(nil, nil, "log", "-z", "--first-parent", "--format="+logFormat, from.String(), "--not", base.String())
+	if err != nil {
+		return nil, err
+	}
+	// each commit, including the last one, is terminated by a NUL
+	out = strings.TrimSuffix(out, "\x00")
+	if out == "" {
+		return nil, nil
+	}
+
+	fields := strings.Split(out, "\x00")
+	if len(fields)%logFields != 0 {
+		return nil, fmt.Errorf("unexpected git log output with %d fields", len(fields))
+	}
+	var commits []*object.Commit
+	for i := 0; i < len(fields); i += logFields {
+		c, err := parseCommit(fields[i : i+logFields])
+		if err != nil {
+			return nil, err
+		}
+		commits = append(commits, c)
+	}
+	return commits, nil
+}
+
+// parseCommit builds a commit from the fields of logFormat. Its parents can't be walked since
+// it isn't read from the object store.
+func parseCommit(f []string) (*object.Commit, error) {
+	author, err := signature(f[3], f[4], f[5])
+	if err != nil {
+		return nil, err
+	}
+	committer, err := signature(f[6], f[7], f[8])
+	if err != nil {
+		return nil, err
+	}
+	c := &object.Commit{
+		Hash:      plumbing.NewHash(f[0]),
+		TreeHash:  plumbing.NewHash(f[1]),
+		Author:    author,
+		Committer: committer,
+		Message:   f[9],
+	}
+	for _, p := range strings.Fields(f[2]) {
+		c.ParentHashes = append(c.ParentHashes, plumbing.NewHash(p))
+	}
+	return c, nil
+}
+
+func signature(name string, email string, date string) (object.Signature, error) {
+	when, err := time.Parse(time.RFC3339, date)
+	if err != nil {
+		return object.Signature{}, err
+	}
+	return object.Signature{Name: name, Email: email, When: when}, nil
+}
+
+// identityEnv passes a signature to git through its GIT_AUTHOR_* or GIT_COMMITTER_* variables.
+func identityEnv(role string, sig *object.Signature) []string {
+	if sig == nil {
+		return nil
+	}
+	prefix := "GIT_" + role + "_"
+	env := []string{prefix + "NAME=" + sig.Name, prefix + "EMAIL=" + sig.Email}
+	if !sig.When.IsZero() {
+		env = append(env, prefix+"DATE="+sig.When.Format(time.RFC3339))
+	}
+	return env
+}
+
+// run runs git and returns its output. Stderr is included in the error if it fails.
+func (e *Exec) run(stdin io.Reader, env []string, args ...string) (string, error) {
+	log.WithFields(log.Fields{"dir": e.Dir, "args": args}).Debug("Running git")
+	c := exec.Command("git", args...)
+	c.Dir = e.Dir
+	c.Stdin = stdin
+	c.Env = append(os.Environ(), env...)
+	var stdout, stderr bytes.Buffer
+	c.Stdout = &stdout
+	c.Stderr = &stderr
+	if err := c.Run(); err != nil {
+		return "", fmt.Errorf("git %s failed: %w: %s", args[0], err, strings.TrimSpace(stderr.String()))
+	}
+	return stdout.String(), nil
+}
+
+// stream runs git for an operation that talks to a remote. Its messages are copied to progress
+// and included in the error if it fails. Stdin is left connected so git can ask for credentials.
+func (e *Exec) stream(progress io.Writer, args ...string) error {
+	log.WithFields(log.Fields{"dir": e.Dir, "args": args}).Debug("Running git")
+	c := exec.Command("git", args...)
+	c.Dir = e.Dir
+	c.Stdin = os.Stdin
+	var stderr bytes.Buffer
+	c.Stdout = &stderr
+	c.Stderr = &stderr
+	if progress != nil {
+		c.Stdout = io.MultiWriter(&stderr, progress)
+		c.Stderr = c.Stdout
+	}
+	if err := c.Run(); err != nil {
+		return fmt.Errorf("git %s failed: %w: %s", args[0], err, strings.TrimSpace(stderr.String()))
+	}
+	return nil
+}
@@ -0,0 +1,237 @@
+package gitbackend
+
+import (
+	"container/heap"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/config"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	log "github.com/sirupsen/logrus"
+
+	"github.com/kunickiaj/beer/pkg/hooks"
+)
+
+// GoGit runs git operations in process with go-git.
+type GoGit struct {
+	Repo *git.Repository
+	// Signer returns the signer for new commits, or nil if they aren't signed. It is only called
+	// when a commit is created so keys aren't loaded needlessly.
+	Signer func() (git.Signer, error)
+	// Hooks runs the repository's hooks, which go-git doesn't. Nil skips them.
+	Hooks *hooks.Runner
+}
+
+// NewGoGit returns a backend operating on repo.
+func NewGoGit(repo *git.Repository, signer func() (git.Signer, error)) *GoGit {
+	return &GoGit{Repo: repo, Signer: signer}
+}
+
+func (g *GoGit) Open(dir string) (*git.Repository, error) {
+	// linked worktrees keep most of their state in the main repository
+	repo, err := git.PlainOpenWithOptions(dir, &git.PlainOpenOptions{DetectDotGit: true, EnableDotGitCommonDir: true})
+	if err != nil {
+		return nil, err
+	}
+	g.Repo = repo
+	return repo, nil
+}
+
+func (g *GoGit) Checkout(branch string, create bool) error {
+	workTree, err := g.Repo.Worktree()
+	if err != nil {
+		return err
+	}
+	return workTree.Checkout(&git.CheckoutOptions{Branch: plumbing.NewBranchReferenceName(branch), Create: create, Keep: true})
+}
+
+func (g *GoGit) Commit(message string, opts CommitOptions) (plumbing.Hash, error) {
+	workTree, err := g.Repo.Worktree()
+	if err != nil {
+		return plumbing.ZeroHash, err
+	}
+
+	if g.Hooks != nil {
+		if !opts.NoVerify {
+			if err := g.Hooks.Run(hooks.PreCommit, nil); err != nil {
+				return plumbing.ZeroHash, err
+			}
+		}
+		if message, err = g.Hooks.CommitMessage(message, !opts.NoVerify); err != nil {
+			return plumbing.ZeroHash, err
+		}
+	}
+
+	var signer git.Signer
+	if !opts.NoSign && g.Signer != nil {
+		if signer, err = g.Signer(); err != nil {
+			return plumbing.ZeroHash, err
+		}
+	}
+	hash, err := workTree.Commit(message, &git.CommitOptions{
+		Author:            opts.Author,
+		Committer:         opts.Committer,
+		Signer:            signer,
+		AllowEmptyCommits: opts.AllowEmpty,
+	})
+	if err != nil {
+		return plumbing.ZeroHash, err
+	}
+
+	if g.Hooks != nil {
+		// like with git, the commit stays when post-commit fails
+		if err := g.Hooks.Run(hooks.PostCommit, nil); err != nil {
+			log.WithError(err).Warn("post-commit hook failed")
+		}
+	}
+	return hash, nil
+}
+
+func (g *GoGit) Push(opts PushOptions) error {
+	if g.Hooks != nil && !opts.NoVerify && g.Hooks.Exists(hooks.PrePush) {
+		if err := g.Hooks.Run(hooks.PrePush, strings.NewReader(g.prePushInput(opts.RefSpecs)), opts.Remote, opts.URL); err != nil {
+			return err
+		}
+	}
+
+	err := g.Repo.Push(&git.PushOptions{
+		RemoteName: opts.Remote,
+		RemoteURL:  opts.URL,
+		RefSpecs:   refSpecs(opts.RefSpecs),
+		Auth:       opts.Auth,
+		Progress:   opts.Progress,
+	})
+	// git push succeeds when the remote is already up to date
+	if errors.Is(err, git.NoErrAlreadyUpToDate) {
+		return nil
+	}
+	return err
+}
+
+func (g *GoGit) Fetch(opts FetchOptions) error {
+	err := g.Repo.Fetch(&git.FetchOptions{
+		RemoteName: opts.Remote,
+		RemoteURL:  opts.URL,
+		RefSpecs:   refSpecs(opts.RefSpecs),
+		Auth:       opts.Auth,
+	})
+	if errors.Is(err, git.NoErrAlreadyUpToDate) {
+		return nil
+	}
+	return err
+}
+
+func (g *GoGit) Log(from plumbing.Hash, base plumbing.Hash) ([]*object.Commit, error) {
+	tip, err := g.Repo.CommitObject(from)
+	if err != nil {
+		return nil, err
+	}
+	target, err := g.Repo.CommitObject(base)
+	if err != nil {
+		return nil, err
+	}
+
+	// Like git log --first-parent from --not base, every commit reachable from base is excluded,
+	// also when base was merged into from and is only reachable through a second parent. Both
+	// histories are walked newest first, the way git does, and the walk stops once only commits
+	// reachable from base are left, so the target branch's older history isn't read.
+	marks := map[plumbing.Hash]side{}
+	queue := &commitQueue{}
+	mark := func(c *object.Commit, s side) {
+		if marks[c.Hash]&s != s {
+			marks[c.Hash] |= s
+			heap.Push(queue, c)
+		}
+	}
+	mark(tip, fromSide)
+	mark(target, baseSide)
+	for queue.Len() > 0 && !queue.onlyBase(marks) {
+		c := heap.Pop(queue).(*object.Commit)
+		s := marks[c.Hash]
+		err := c.Parents().ForEach(func(parent *object.Commit) error {
+			mark(parent, s)
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	var commits []*object.Commit
+	for commit := tip; marks[commit.Hash]&baseSide == 0; {
+		commits = append(commits, commit)
+		if commit.NumParents() == 0 {
+			break
+		}
+		if commit, err = commit.Parent(0); err != nil {
+			return nil, err
+		}
+	}
+	return commits, nil
+}
+
+// side records whether a commit is reachable from the commits Log lists, from its base or both.
+type side uint8
+
+const (
+	fromSide side = 1 << iota
+	baseSide
+)
+
+// commitQueue is a heap of commits, newest committer date first.
+type commitQueue []*object.Commit
+
+func (q commitQueue) Len() int            { return len(q) }
+func (q commitQueue) Less(i, j int) bool  { return q[i].Committer.When.After(q[j].Committer.When) }
+func (q commitQueue) Swap(i, j int)       { q[i], q[j] = q[j], q[i] }
+func (q *commitQueue) Push(x interface{}) { *q = append(*q, x.(*object.Commit)) }
+
+func (q *commitQueue) Pop() interface{} {
+	old := *q
+	c := old[len(old)-1]
+	*q = old[:len(old)-1]
+	return c
+}
+
+// onlyBase reports whether every queued commit is reachable from the base, so walking on can't
+// find more commits that aren't.
+func (q commitQueue) onlyBase(marks map[plumbing.Hash]side) bool {
+	for _, c := range q {
+		if marks[c.Hash]&baseSide == 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// prePushInput describes the refs being pushed to the pre-push hook, one line per ref with the
+// local ref and commit followed by the remote ref and commit. The remote commit is reported as
+// all zeros since go-git doesn't tell which commit the remote had before.
+func (g *GoGit) prePushInput(specs []string) string {
+	var b strings.Builder
+	for _, s := range specs {
+		spec := config.RefSpec(s)
+		if spec.IsDelete() {
+			continue
+		}
+		src := plumbing.ReferenceName(spec.Src())
+		hash, err := g.Repo.ResolveRevision(plumbing.Revision(src))
+		if err != nil {
+			log.WithError(err).WithField("ref", src).Debug("Unable to resolve pushed ref")
+			continue
+		}
+		fmt.Fprintf(&b, "%s %s %s %s\n", src, hash, spec.Dst(src), plumbing.ZeroHash)
+	}
+	return b.String()
+}
+
+func refSpecs(specs []string) []config.RefSpec {
+	var refs []config.RefSpec
+	for _, s := range specs {
+		refs = append(refs, config.RefSpec(s))
+	}
+	return refs
+}
@@ -0,0 +1,194 @@
+package gitbackend
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-git/go-billy/v5/memfs"
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/config"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/storage/memory"
+
+	"github.com/kunickiaj/beer/pkg/gitconfig"
+	"github.com/kunickiaj/beer/pkg/hooks"
+)
+
+func TestGoGitHooks(t *testing.T) {
+	f := newFixture(t)
+	// hooks are found through core.hooksPath, relative to the working tree
+	t.Setenv("GIT_CONFIG_COUNT", "1")
+	t.Setenv("GIT_CONFIG_KEY_0", "core.hooksPath")
+	t.Setenv("GIT_CONFIG_VALUE_0", ".githooks")
+	hookLog := filepath.Join(t.TempDir(), "pre-push.log")
+	t.Setenv("HOOK_LOG", hookLog)
+
+	g := &GoGit{}
+	repo, err := g.Open(f.dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg, err := gitconfig.Load(filepath.Join(f.dir, ".git"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	g.Hooks = hooks.New(cfg, filepath.Join(f.dir, ".git"), f.dir)
+	g.Hooks.Output = &bytes.Buffer{}
+	install := func(name string, script string) {
+		t.Helper()
+		dir := filepath.Join(f.dir, ".githooks")
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			t.Fatal(err)
+		}
+		file := filepath.Join(dir, name)
+		if err := os.WriteFile(file, []byte("#!/bin/sh\n"+script), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Chmod(file, 0o755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	install(hooks.PreCommit, "exit 1\n")
+	install(hooks.PrepareCommitMsg, "echo \"prepared: $2\" >> \"$1\"\n")
+	install(hooks.CommitMsg, "printf '\\nChange-Id: I0123\\n' >> \"$1\"\n")
+	install(hooks.PrePush, "{ echo \"$@\"; cat; } > \"$HOOK_LOG\"\n")
+
+	if err := g.Checkout("PRJ-2", true); err != nil {
+		t.Fatal(err)
+	}
+	sig := testSignature
+	opts := CommitOptions{Author: &sig, Committer: &sig, AllowEmpty: true}
+
+	if _, err := g.Commit("PRJ-2. Seed", opts); err == nil {
+		t.Fatal("Commit() with a failing pre-commit hook succeeded")
+	}
+	if head, err := repo.Head(); err != nil || head.Hash() != f.commits["w3"] {
+		t.Errorf("HEAD moved to %v, %v after pre-commit failed", head, err)
+	}
+
+	message := func(hash plumbing.Hash) string {
+		t.Helper()
+		c, err := repo.CommitObject(hash)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return c.Message
+	}
+
+	// --no-verify skips pre-commit and commit-msg, but not prepare-commit-msg
+	opts.NoVerify = true
+	hash, err := g.Commit("PRJ-2. Seed", opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := message(hash); got != "PRJ-2. Seed\nprepared: message\n" {
+		t.Errorf("Commit() with NoVerify created %q", got)
+	}
+
+	install(hooks.PreCommit, "exit 0\n")
+	opts.NoVerify = false
+	if hash, err = g.Commit("PRJ-2. Second", opts); err != nil {
+		t.Fatal(err)
+	}
+	if got := message(hash); got != "PRJ-2. Second\nprepared: message\n\nChange-Id: I0123\n" {
+		t.Errorf("Commit() created %q, want the message the hooks left", got)
+	}
+
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not found, which local remotes need")
+	}
+	remoteDir := t.TempDir()
+	if _, err := git.PlainInit(remoteDir, true); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := repo.CreateRemote(&config.RemoteConfig{Name: "origin", URLs: []string{remoteDir}}); err != nil {
+		t.Fatal(err)
+	}
+	push := PushOptions{Remote: "origin", URL: remoteDir, RefSpecs: []string{"refs/heads/main:refs/heads/main"}, NoVerify: true}
+	if err := g.Push(push); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(hookLog); !os.IsNotExist(err) {
+		t.Errorf("Push() with NoVerify ran pre-push: %v", err)
+	}
+
+	push.NoVerify = false
+	push.RefSpecs = []string{"refs/heads/PRJ-2:refs/heads/PRJ-2"}
+	if err := g.Push(push); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(hookLog)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "origin " + remoteDir + "\nrefs/heads/PRJ-2 " + hash.String() + " refs/heads/PRJ-2 " + plumbing.ZeroHash.String() + "\n"
+	if string(data) != want {
+		t.Errorf("pre-push got %q, want %q", data, want)
+	}
+
+	install(hooks.PrePush, "exit 1\n")
+	if err := g.Push(push); err == nil || !strings.Contains(err.Error(), "pre-push hook failed") {
+		t.Errorf("Push() with a failing pre-push hook = %v", err)
+	}
+}
+
+// countingStorage counts the objects read from a repository.
+type countingStorage struct {
+	*memory.Storage
+	reads int
+}
+
+func (s *countingStorage) EncodedObject(t plumbing.ObjectType, h plumbing.Hash) (plumbing.EncodedObject, error) {
+	s.reads++
+	return s.Storage.EncodedObject(t, h)
+}
+
+func TestGoGitLogStopsAtBase(t *testing.T) {
+	storage := &countingStorage{Storage: memory.NewStorage()}
+	repo, err := git.Init(storage, memfs.New())
+	if err != nil {
+		t.Fatal(err)
+	}
+	workTree, err := repo.Worktree()
+	if err != nil {
+		t.Fatal(err)
+	}
+	sig := testSignature
+	commit := func(message string) plumbing.Hash {
+		sig.When = sig.When.Add(time.Minute)
+		hash, err := workTree.Commit(message, &git.CommitOptions{Author: &sig, Committer: &sig, AllowEmptyCommits: true})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return hash
+	}
+
+	// a long target branch history with a short work branch on top
+	for i := 0; i < 500; i++ {
+		commit(fmt.Sprintf("r%d", i))
+	}
+	base := commit("base")
+	if err := workTree.Checkout(&git.CheckoutOptions{Branch: plumbing.NewBranchReferenceName("PRJ-1"), Create: true}); err != nil {
+		t.Fatal(err)
+	}
+	commit("w1")
+	tip := commit("w2")
+
+	storage.reads = 0
+	commits, err := (&GoGit{Repo: repo}).Log(tip, base)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(commits) != 2 {
+		t.Errorf("Log() = %d commits, want 2", len(commits))
+	}
+	if storage.reads > 20 {
+		t.Errorf("Log() read %d objects, want it to stop at the base instead of reading its history", storage.reads)
+	}
+}
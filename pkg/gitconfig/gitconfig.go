@@ -0,0 +1,302 @@
+package gitconfig
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	format "github.com/go-git/go-git/v5/plumbing/format/config"
+
+	"github.com/kunickiaj/beer/pkg/wildmatch"
+)
+
+// maxIncludeDepth stops include cycles, like git does.
+const maxIncludeDepth = 10
+
+// Config is the effective git configuration of a repository, merged from the same files git
+// reads: system, XDG, global and repository local config, in that order, followed by
+// GIT_CONFIG_COUNT style environment variables. Later values take precedence.
+type Config struct {
+	values map[string][]string // keyed by section[.subsection].name, section and name lower cased
+	gitDir string
+	branch string
+}
+
+// Load reads the configuration that applies to the repository with the given git directory. An
+// empty gitDir loads only the configuration outside of any repository.
+func Load(gitDir string) (*Config, error) {
+	c := &Config{values: map[string][]string{}, gitDir: gitDir}
+	if gitDir != "" {
+		c.branch = currentBranch(gitDir)
+	}
+
+	for _, file := range Files(gitDir) {
+		if err := c.loadFile(file, 0); err != nil {
+			return nil, err
+		}
+	}
+
+	if count, err := strconv.Atoi(os.Getenv("GIT_CONFIG_COUNT")); err == nil {
+		for i := 0; i < count; i++ {
+			key := os.Getenv("GIT_CONFIG_KEY_" + strconv.Itoa(i))
+			if key != "" {
+				c.add(key, os.Getenv("GIT_CONFIG_VALUE_"+strconv.Itoa(i)))
+			}
+		}
+	}
+	return c, nil
+}
+
+// Files returns the config files git reads for the repository, lowest precedence first. Files
+// that don't exist are included.
+func Files(gitDir string) []string {
+	var files []string
+	if os.Getenv("GIT_CONFIG_NOSYSTEM") == "" {
+		if system := os.Getenv("GIT_CONFIG_SYSTEM"); system != "" {
+			files = append(files, system)
+		} else {
+			files = append(files, "/etc/gitconfig")
+		}
+	}
+
+	home, _ := os.UserHomeDir()
+	if global := os.Getenv("GIT_CONFIG_GLOBAL"); global != "" {
+		files = append(files, global)
+	} else {
+		xdg := os.Getenv("XDG_CONFIG_HOME")
+		if xdg == "" && home != "" {
+			xdg = filepath.Join(home, ".config")
+		}
+		if xdg != "" {
+			files = append(files, filepath.Join(xdg, "git", "config"))
+		}
+		if home != "" {
+			files = append(files, filepath.Join(home, ".gitconfig"))
+		}
+	}
+
+	if gitDir != "" {
+		common := CommonDir(gitDir)
+		files = append(files, filepath.Join(common, "config"))
+		// per worktree config, which only exists when extensions.worktreeConfig is enabled
+		files = append(files, filepath.Join(gitDir, "config.worktree"))
+	}
+	return files
+}
+
+// Get returns the last value of key, e.g. "user.email" or "remote.origin.url", or an empty
+// string if it isn't set.
+func (c *Config) Get(key string) string {
+	values := c.values[normalize(key)]
+	if len(values) == 0 {
+		return ""
+	}
+	return values[len(values)-1]
+}
+
+// GetAll returns every value of a multi-valued key in the order they were read.
+func (c *Config) GetAll(key string) []string {
+	return c.values[normalize(key)]
+}
+
+// Has reports whether key is set.
+func (c *Config) Has(key string) bool {
+	return len(c.values[normalize(key)]) > 0
+}
+
+// Bool returns the value of key interpreted as a git boolean. A key without a value, e.g.
+// "[commit] gpgsign", is true.
+func (c *Config) Bool(key string) bool {
+	if !c.Has(key) {
+		return false
+	}
+	switch strings.ToLower(c.Get(key)) {
+	case "true", "yes", "on", "1", "":
+		return true
+	}
+	return false
+}
+
+// Subsections returns the names of the subsections of a section, e.g. the remotes for "remote".
+func (c *Config) Subsections(section string) []string {
+	prefix := strings.ToLower(section) + "."
+	seen := map[string]bool{}
+	var names []string
+	for key := range c.values {
+		rest, ok := strings.CutPrefix(key, prefix)
+		if !ok {
+			continue
+		}
+		i := strings.LastIndex(rest, ".")
+		if i < 0 {
+			continue
+		}
+		if name := rest[:i]; !seen[name] {
+			seen[name] = true
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+func (c *Config) add(key string, value string) {
+	key = normalize(key)
+	c.values[key] = append(c.values[key], value)
+}
+
+// loadFile merges a config file, following include and includeIf directives.
+func (c *Config) loadFile(file string, depth int) error {
+	if depth > maxIncludeDepth {
+		return errors.New("git config include depth exceeded in " + file)
+	}
+	f, err := os.Open(file)
+	if errors.Is(err, os.ErrNotExist) || errors.Is(err, os.ErrPermission) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	defer func() { _ = f.Close() }()
+
+	decoded := format.New()
+	if err := format.NewDecoder(f).Decode(decoded); err != nil {
+		return errors.New("unable to parse " + file + ": " + err.Error())
+	}
+
+	for _, s := range decoded.Sections {
+		section := strings.ToLower(s.Name)
+		for _, o := range s.Options {
+			if section == "include" && o.IsKey("path") {
+				if err := c.loadFile(includePath(file, o.Value), depth+1); err != nil {
+					return err
+				}
+				continue
+			}
+			c.add(section+"."+o.Key, o.Value)
+		}
+		for _, sub := range s.Subsections {
+			for _, o := range sub.Options {
+				if section == "includeif" && o.IsKey("path") {
+					if c.includeApplies(file, sub.Name) {
+						if err := c.loadFile(includePath(file, o.Value), depth+1); err != nil {
+							return err
+						}
+					}
+					continue
+				}
+				c.add(section+"."+sub.Name+"."+o.Key, o.Value)
+			}
+		}
+	}
+	return nil
+}
+
+// includeApplies evaluates an includeIf condition such as "gitdir:~/work/" or "onbranch:main".
+func (c *Config) includeApplies(file string, condition string) bool {
+	kind, pattern, ok := strings.Cut(condition, ":")
+	if !ok {
+		return false
+	}
+
+	switch kind {
+	case "gitdir", "gitdir/i":
+		if c.gitDir == "" {
+			return false
+		}
+		pattern = expandHome(pattern)
+		if strings.HasPrefix(pattern, "./") {
+			pattern = filepath.Join(filepath.Dir(file), pattern[2:]) + suffixSlash(pattern)
+		}
+		if !filepath.IsAbs(pattern) {
+			pattern = "**/" + pattern
+		}
+		if strings.HasSuffix(pattern, "/") {
+			pattern += "**"
+		}
+		dirs := []string{c.gitDir}
+		if resolved, err := filepath.EvalSymlinks(c.gitDir); err == nil && resolved != c.gitDir {
+			dirs = append(dirs, resolved)
+		}
+		for _, dir := range dirs {
+			if wildmatch.Match(pattern, filepath.ToSlash(dir), kind == "gitdir/i") {
+				return true
+			}
+		}
+	case "onbranch":
+		if c.branch == "" {
+			return false
+		}
+		if strings.HasSuffix(pattern, "/") {
+			pattern += "**"
+		}
+		return wildmatch.Match(pattern, c.branch, false)
+	}
+	return false
+}
+
+// includePath resolves the path of an included file, which is relative to the including file.
+func includePath(file string, path string) string {
+	path = expandHome(path)
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(filepath.Dir(file), path)
+	}
+	return path
+}
+
+func expandHome(path string) string {
+	if strings.HasPrefix(path, "~/") {
+		if home, err := os.UserHomeDir(); err == nil {
+			return filepath.Join(home, path[2:]) + suffixSlash(path)
+		}
+	}
+	return path
+}
+
+// suffixSlash returns "/" if path ends with one, since filepath.Join drops it.
+func suffixSlash(path string) string {
+	if strings.HasSuffix(path, "/") {
+		return "/"
+	}
+	return ""
+}
+
+// CommonDir returns the directory shared by all worktrees of a repository, which is gitDir
+// itself unless gitDir belongs to a linked worktree.
+func CommonDir(gitDir string) string {
+	data, err := os.ReadFile(filepath.Join(gitDir, "commondir"))
+	if err != nil {
+		return gitDir
+	}
+	dir := strings.TrimSpace(string(data))
+	if !filepath.IsAbs(dir) {
+		dir = filepath.Join(gitDir, dir)
+	}
+	return filepath.Clean(dir)
+}
+
+// currentBranch returns the short name of the branch HEAD points to.
+func currentBranch(gitDir string) string {
+	data, err := os.ReadFile(filepath.Join(gitDir, "HEAD"))
+	if err != nil {
+		return ""
+	}
+	ref, ok := strings.CutPrefix(strings.TrimSpace(string(data)), "ref: refs/heads/")
+	if !ok {
+		return ""
+	}
+	return ref
+}
+
+// normalize lower cases the section and name of a key but not its subsection.
+func normalize(key string) string {
+	first := strings.Index(key, ".")
+	last := strings.LastIndex(key, ".")
+	if first < 0 {
+		return strings.ToLower(key)
+	}
+	if first == last {
+		return strings.ToLower(key)
+	}
+	return strings.ToLower(key[:first]) + key[first:last] + strings.ToLower(key[last:])
+}
@@ -0,0 +1,184 @@
+package gitconfig
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+)
+
+// testEnv isolates the configuration from the user's and returns a temporary directory that is
+// also $HOME.
+func testEnv(t *testing.T) string {
+	t.Helper()
+	dir := t.TempDir()
+	t.Setenv("HOME", dir)
+	t.Setenv("XDG_CONFIG_HOME", "")
+	t.Setenv("GIT_CONFIG_NOSYSTEM", "1")
+	t.Setenv("GIT_CONFIG_SYSTEM", "")
+	t.Setenv("GIT_CONFIG_GLOBAL", filepath.Join(dir, "global"))
+	t.Setenv("GIT_CONFIG_COUNT", "")
+	return dir
+}
+
+func writeFile(t *testing.T, path string, content string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// newGitDir creates a git directory under dir/work/repo with HEAD on branch.
+func newGitDir(t *testing.T, dir string, branch string) string {
+	t.Helper()
+	gitDir := filepath.Join(dir, "work", "repo", ".git")
+	writeFile(t, filepath.Join(gitDir, "HEAD"), "ref: refs/heads/"+branch+"\n")
+	return gitDir
+}
+
+func TestLoad(t *testing.T) {
+	dir := testEnv(t)
+	gitDir := newGitDir(t, dir, "main")
+
+	t.Setenv("GIT_CONFIG_NOSYSTEM", "")
+	t.Setenv("GIT_CONFIG_SYSTEM", filepath.Join(dir, "system"))
+	writeFile(t, filepath.Join(dir, "system"), "[user]\n\tname = System\n\temail = system@example.com\n[core]\n\tautocrlf = true\n")
+	writeFile(t, filepath.Join(dir, "global"), "[user]\n\temail = global@example.com\n[commit]\n\tgpgsign\n[remote \"origin\"]\n\tpushurl = ssh://global/repo\n")
+	writeFile(t, filepath.Join(gitDir, "config"), "[user]\n\temail = local@example.com\n[core]\n\tautocrlf = false\n[remote \"origin\"]\n\turl = https://local/repo\n\tfetch = +refs/heads/*:refs/remotes/origin/*\n\tfetch = +refs/notes/*:refs/notes/*\n[remote \"fork\"]\n\turl = https://fork/repo\n")
+	t.Setenv("GIT_CONFIG_COUNT", "1")
+	t.Setenv("GIT_CONFIG_KEY_0", "user.name")
+	t.Setenv("GIT_CONFIG_VALUE_0", "Environment")
+
+	c, err := Load(gitDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for key, want := range map[string]string{
+		"user.name":         "Environment",
+		"user.email":        "local@example.com",
+		"remote.origin.url": "https://local/repo",
+		// values of lower precedence files are kept for keys the others don't set
+		"remote.origin.pushurl": "ssh://global/repo",
+		"user.signingkey":       "",
+	} {
+		if got := c.Get(key); got != want {
+			t.Errorf("Get(%s) = %q, want %q", key, got, want)
+		}
+	}
+	if got := c.GetAll("user.email"); !reflect.DeepEqual(got, []string{"system@example.com", "global@example.com", "local@example.com"}) {
+		t.Errorf("GetAll(user.email) = %q, want the values of each file in order", got)
+	}
+	if got := c.GetAll("remote.origin.fetch"); len(got) != 2 {
+		t.Errorf("GetAll(remote.origin.fetch) = %q, want both refspecs", got)
+	}
+	if !c.Bool("commit.gpgsign") || c.Bool("core.autocrlf") || c.Bool("tag.gpgsign") {
+		t.Errorf("Bool() = %t, %t, %t, want true, false, false", c.Bool("commit.gpgsign"), c.Bool("core.autocrlf"), c.Bool("tag.gpgsign"))
+	}
+	remotes := c.Subsections("remote")
+	sort.Strings(remotes)
+	if !reflect.DeepEqual(remotes, []string{"fork", "origin"}) {
+		t.Errorf("Subsections(remote) = %q, want fork and origin", remotes)
+	}
+
+	// without a repository only the files outside of it apply
+	if c, err = Load(""); err != nil {
+		t.Fatal(err)
+	}
+	if got := c.Get("user.email"); got != "global@example.com" {
+		t.Errorf("Get(user.email) outside a repository = %q, want global@example.com", got)
+	}
+}
+
+func TestNormalize(t *testing.T) {
+	dir := testEnv(t)
+	writeFile(t, filepath.Join(dir, "global"), "[User]\n\tEmail = test@example.com\n[Remote \"Origin\"]\n\tURL = https://example.com/repo\n[url \"git@example.com:\"]\n\tinsteadOf = https://example.com/\n")
+	c, err := Load("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		key  string
+		want string
+	}{
+		{key: "user.email", want: "test@example.com"},
+		{key: "USER.EMAIL", want: "test@example.com"},
+		{key: "remote.Origin.url", want: "https://example.com/repo"},
+		{key: "REMOTE.Origin.Url", want: "https://example.com/repo"},
+		// subsections are case sensitive
+		{key: "remote.origin.url", want: ""},
+		// subsections may contain dots
+		{key: "url.git@example.com:.insteadof", want: "https://example.com/"},
+	}
+	for _, tt := range tests {
+		if got := c.Get(tt.key); got != tt.want {
+			t.Errorf("Get(%s) = %q, want %q", tt.key, got, tt.want)
+		}
+	}
+}
+
+func TestIncludes(t *testing.T) {
+	tests := []struct {
+		name   string
+		global string // the include directive in the global config
+		branch string // checked out in the repository, defaults to feature/x
+		noRepo bool   // load without a repository
+		want   bool
+	}{
+		{name: "relative include", global: "[include]\n\tpath = included", want: true},
+		{name: "include from home", global: "[include]\n\tpath = ~/included", want: true},
+		{name: "gitdir", global: "[includeIf \"gitdir:DIR/work/\"]\n\tpath = included", want: true},
+		{name: "gitdir of another directory", global: "[includeIf \"gitdir:DIR/other/\"]\n\tpath = included"},
+		{name: "gitdir relative to home", global: "[includeIf \"gitdir:~/work/\"]\n\tpath = included", want: true},
+		{name: "gitdir relative to the config file", global: "[includeIf \"gitdir:./work/\"]\n\tpath = included", want: true},
+		{name: "gitdir without a leading slash matches anywhere", global: "[includeIf \"gitdir:repo/\"]\n\tpath = included", want: true},
+		{name: "gitdir wildcard", global: "[includeIf \"gitdir:DIR/w*k/*/.git\"]\n\tpath = included", want: true},
+		{name: "gitdir double star", global: "[includeIf \"gitdir:**/repo/.git\"]\n\tpath = included", want: true},
+		{name: "gitdir is case sensitive", global: "[includeIf \"gitdir:DIR/WORK/\"]\n\tpath = included"},
+		{name: "gitdir/i ignores case", global: "[includeIf \"gitdir/i:DIR/WORK/\"]\n\tpath = included", want: true},
+		{name: "gitdir without a repository", global: "[includeIf \"gitdir:DIR/work/\"]\n\tpath = included", noRepo: true},
+		{name: "onbranch", global: "[includeIf \"onbranch:feature/x\"]\n\tpath = included", want: true},
+		{name: "onbranch of another branch", global: "[includeIf \"onbranch:main\"]\n\tpath = included"},
+		{name: "onbranch prefix", global: "[includeIf \"onbranch:feature/\"]\n\tpath = included", want: true},
+		{name: "onbranch wildcard", global: "[includeIf \"onbranch:feat*/*\"]\n\tpath = included", want: true},
+		{name: "onbranch star doesn't match slashes", global: "[includeIf \"onbranch:*\"]\n\tpath = included", branch: "feature/x"},
+		{name: "unknown condition", global: "[includeIf \"hasconfig:remote.*.url:*\"]\n\tpath = included"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := testEnv(t)
+			branch := tt.branch
+			if branch == "" {
+				branch = "feature/x"
+			}
+			gitDir := newGitDir(t, dir, branch)
+			if tt.noRepo {
+				gitDir = ""
+			}
+			writeFile(t, filepath.Join(dir, "global"), strings.ReplaceAll(tt.global, "DIR", filepath.ToSlash(dir))+"\n[beer]\n\tincluded = false\n")
+			writeFile(t, filepath.Join(dir, "included"), "[beer]\n\tincluded = true\n")
+
+			c, err := Load(gitDir)
+			if err != nil {
+				t.Fatal(err)
+			}
+			// the include is read where it appears, so the global file's own value comes last
+			included := len(c.GetAll("beer.included")) == 2
+			if included != tt.want {
+				t.Errorf("included = %t, want %t", included, tt.want)
+			}
+		})
+	}
+}
+
+func TestIncludeCycle(t *testing.T) {
+	dir := testEnv(t)
+	writeFile(t, filepath.Join(dir, "global"), "[include]\n\tpath = global\n")
+	if _, err := Load(""); err == nil {
+		t.Error("Load() of a config including itself succeeded")
+	}
+}
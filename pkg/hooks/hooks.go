@@ -0,0 +1,104 @@
+package hooks
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/mitchellh/go-homedir"
+	log "github.com/sirupsen/logrus"
+
+	"github.com/kunickiaj/beer/pkg/gitconfig"
+)
+
+// Names of the hooks beer runs, at the same points as git commit and git push.
+const (
+	PreCommit        = "pre-commit"
+	PrepareCommitMsg = "prepare-commit-msg"
+	CommitMsg        = "commit-msg"
+	PostCommit       = "post-commit"
+	PrePush          = "pre-push"
+)
+
+// Runner runs the hooks of a repository. Hooks are executables named after the hook in
+// core.hooksPath, or in the hooks directory of the repository.
+type Runner struct {
+	Dir      string // Directory containing the hooks
+	GitDir   string // Where the commit message is written for the commit message hooks
+	WorkTree string // Hooks run in the root of the working tree, like with git
+	Output   io.Writer
+}
+
+// New returns a runner for the repository with the given git directory and working tree.
+func New(cfg *gitconfig.Config, gitDir string, workTree string) *Runner {
+	dir := filepath.Join(gitconfig.CommonDir(gitDir), "hooks")
+	if path := cfg.Get("core.hooksPath"); path != "" {
+		if expanded, err := homedir.Expand(path); err == nil {
+			path = expanded
+		}
+		if !filepath.IsAbs(path) {
+			path = filepath.Join(workTree, path)
+		}
+		dir = path
+	}
+	return &Runner{Dir: dir, GitDir: gitDir, WorkTree: workTree, Output: os.Stderr}
+}
+
+// Exists reports whether a hook is installed, i.e. there is an executable with its name.
+func (r *Runner) Exists(name string) bool {
+	info, err := os.Stat(filepath.Join(r.Dir, name))
+	return err == nil && !info.IsDir() && info.Mode()&0o111 != 0
+}
+
+// Run runs a hook if it is installed. Its output is copied to Output and a non-zero exit status
+// is returned as an error, which should abort the operation like it does in git.
+func (r *Runner) Run(name string, stdin io.Reader, args ...string) error {
+	if !r.Exists(name) {
+		return nil
+	}
+	log.WithFields(log.Fields{"hook": name, "args": args}).Debug("Running hook")
+
+	c := exec.Command(filepath.Join(r.Dir, name), args...)
+	c.Dir = r.WorkTree
+	c.Stdin = stdin
+	c.Stdout = r.Output
+	c.Stderr = r.Output
+	if err := c.Run(); err != nil {
+		return fmt.Errorf("%s hook failed: %w, use --no-verify to skip it", name, err)
+	}
+	return nil
+}
+
+// CommitMessage runs the prepare-commit-msg and commit-msg hooks on a commit message and returns
+// the message they leave, e.g. with a Change-Id added by Gerrit's commit-msg hook. Like git
+// commit --no-verify, commit-msg is skipped unless verify is set.
+func (r *Runner) CommitMessage(message string, verify bool) (string, error) {
+	if !r.Exists(PrepareCommitMsg) && !(verify && r.Exists(CommitMsg)) {
+		return message, nil
+	}
+
+	// git hands hooks the message with a final newline, which hooks appending trailers rely on
+	if !strings.HasSuffix(message, "\n") {
+		message += "\n"
+	}
+	file := filepath.Join(r.GitDir, "COMMIT_EDITMSG")
+	if err := os.WriteFile(file, []byte(message), 0o644); err != nil {
+		return "", err
+	}
+	if err := r.Run(PrepareCommitMsg, nil, file, "message"); err != nil {
+		return "", err
+	}
+	if verify {
+		if err := r.Run(CommitMsg, nil, file); err != nil {
+			return "", err
+		}
+	}
+	edited, err := os.ReadFile(file)
+	if err != nil {
+		return "", err
+	}
+	return string(edited), nil
+}
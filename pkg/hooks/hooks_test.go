@@ -0,0 +1,190 @@
+package hooks
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/kunickiaj/beer/pkg/gitconfig"
+)
+
+// newRepoDirs returns a git directory and working tree whose local config is content, isolated
+// from the user's configuration. $HOME is a temporary directory.
+func newRepoDirs(t *testing.T, content string) (*gitconfig.Config, string, string) {
+	t.Helper()
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv("GIT_CONFIG_NOSYSTEM", "1")
+	t.Setenv("GIT_CONFIG_GLOBAL", os.DevNull)
+	t.Setenv("GIT_CONFIG_COUNT", "")
+	workTree := t.TempDir()
+	gitDir := filepath.Join(workTree, ".git")
+	if err := os.MkdirAll(gitDir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(gitDir, "config"), []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	cfg, err := gitconfig.Load(gitDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return cfg, gitDir, workTree
+}
+
+// writeHook installs a shell script as a hook in dir.
+func writeHook(t *testing.T, dir string, name string, script string) {
+	t.Helper()
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	file := filepath.Join(dir, name)
+	if err := os.WriteFile(file, []byte("#!/bin/sh\n"+script), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	// WriteFile keeps the mode of an existing file
+	if err := os.Chmod(file, 0o755); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestNew(t *testing.T) {
+	tests := []struct {
+		name     string
+		hooksDir string // core.hooksPath, if set
+		want     func(gitDir string, workTree string, home string) string
+	}{
+		{name: "repository hooks", want: func(gitDir, _, _ string) string { return filepath.Join(gitDir, "hooks") }},
+		{name: "relative hooksPath", hooksDir: ".githooks", want: func(_, workTree, _ string) string { return filepath.Join(workTree, ".githooks") }},
+		{name: "hooksPath in home", hooksDir: "~/hooks", want: func(_, _, home string) string { return filepath.Join(home, "hooks") }},
+		{name: "absolute hooksPath", hooksDir: "/opt/hooks", want: func(_, _, _ string) string { return "/opt/hooks" }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			content := ""
+			if tt.hooksDir != "" {
+				content = "[core]\n\thooksPath = " + tt.hooksDir + "\n"
+			}
+			cfg, gitDir, workTree := newRepoDirs(t, content)
+			r := New(cfg, gitDir, workTree)
+			if want := tt.want(gitDir, workTree, os.Getenv("HOME")); r.Dir != want {
+				t.Errorf("New().Dir = %s, want %s", r.Dir, want)
+			}
+		})
+	}
+}
+
+func TestRun(t *testing.T) {
+	cfg, gitDir, workTree := newRepoDirs(t, "[core]\n\thooksPath = .githooks\n")
+	r := New(cfg, gitDir, workTree)
+	var out bytes.Buffer
+	r.Output = &out
+	dir := filepath.Join(workTree, ".githooks")
+
+	// missing hooks and files that aren't executable are skipped
+	if err := r.Run(PrePush, nil); err != nil {
+		t.Errorf("Run() of a missing hook = %v", err)
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, PreCommit), []byte("#!/bin/sh\nexit 1\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if r.Exists(PreCommit) {
+		t.Error("Exists() of a hook that isn't executable = true")
+	}
+
+	writeHook(t, dir, PrePush, "echo \"args: $*\"\necho \"cwd: $(pwd)\"\ncat\n")
+	if err := r.Run(PrePush, strings.NewReader("refs/heads/PRJ-1 abc refs/heads/PRJ-1 000\n"), "origin", "https://example.com/repo"); err != nil {
+		t.Fatal(err)
+	}
+	cwd, err := filepath.EvalSymlinks(workTree)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "args: origin https://example.com/repo\ncwd: " + cwd + "\nrefs/heads/PRJ-1 abc refs/heads/PRJ-1 000\n"
+	if out.String() != want {
+		t.Errorf("hook output = %q, want %q", out.String(), want)
+	}
+
+	writeHook(t, dir, PreCommit, "echo 'lint failed' >&2\nexit 3\n")
+	err = r.Run(PreCommit, nil)
+	if err == nil || !strings.Contains(err.Error(), "pre-commit hook failed") || !strings.Contains(err.Error(), "--no-verify") {
+		t.Errorf("Run() of a failing hook = %v, want an error suggesting --no-verify", err)
+	}
+	if !strings.Contains(out.String(), "lint failed") {
+		t.Errorf("hook output = %q, want the hook's stderr", out.String())
+	}
+}
+
+func TestCommitMessage(t *testing.T) {
+	tests := []struct {
+		name    string
+		hooks   map[string]string
+		verify  bool
+		message string
+		want    string
+		wantErr bool
+	}{
+		{
+			name:    "no hooks",
+			verify:  true,
+			message: "PRJ-1. Seed",
+			want:    "PRJ-1. Seed",
+		},
+		{
+			name:    "commit-msg adds a Change-Id",
+			hooks:   map[string]string{CommitMsg: "printf '\\nChange-Id: I0123\\n' >> \"$1\"\n"},
+			verify:  true,
+			message: "PRJ-1. Seed",
+			want:    "PRJ-1. Seed\n\nChange-Id: I0123\n",
+		},
+		{
+			name:    "prepare-commit-msg gets the file and source",
+			hooks:   map[string]string{PrepareCommitMsg: "[ \"$2\" = message ] && [ \"$1\" = \"$GIT_DIR_EXPECTED/COMMIT_EDITMSG\" ] && echo \"prepared: $#\" >> \"$1\"\n"},
+			verify:  true,
+			message: "PRJ-1. Seed\n",
+			want:    "PRJ-1. Seed\nprepared: 2\n",
+		},
+		{
+			name:    "failing commit-msg",
+			hooks:   map[string]string{CommitMsg: "exit 1\n"},
+			verify:  true,
+			message: "PRJ-1. Seed",
+			wantErr: true,
+		},
+		{
+			name:    "without verify commit-msg is skipped but prepare-commit-msg runs",
+			hooks:   map[string]string{CommitMsg: "exit 1\n", PrepareCommitMsg: "echo prepared >> \"$1\"\n"},
+			message: "PRJ-1. Seed",
+			want:    "PRJ-1. Seed\nprepared\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg, gitDir, workTree := newRepoDirs(t, "")
+			t.Setenv("GIT_DIR_EXPECTED", gitDir)
+			r := New(cfg, gitDir, workTree)
+			r.Output = &bytes.Buffer{}
+			for name, script := range tt.hooks {
+				writeHook(t, r.Dir, name, script)
+			}
+
+			got, err := r.CommitMessage(tt.message, tt.verify)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("CommitMessage() = %q, want an error", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("CommitMessage() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,121 @@
+package markup
+
+import (
+	"strconv"
+	"strings"
+)
+
+// Node is a node of an Atlassian Document Format (ADF) document.
+// See https://developer.atlassian.com/cloud/jira/platform/apis/document/structure/
+type Node struct {
+	Type    string                 `json:"type"`
+	Version int                    `json:"version,omitempty"`
+	Attrs   map[string]interface{} `json:"attrs,omitempty"`
+	Content []*Node                `json:"content,omitempty"`
+	Text    string                 `json:"text,omitempty"`
+	Marks   []*Mark                `json:"marks,omitempty"`
+}
+
+// Mark is formatting applied to an ADF text node, e.g. strong or link.
+type Mark struct {
+	Type  string                 `json:"type"`
+	Attrs map[string]interface{} `json:"attrs,omitempty"`
+}
+
+// ADFToText converts an ADF document to plain text suitable for a commit message.
+func ADFToText(doc *Node) string {
+	if doc == nil {
+		return ""
+	}
+	var sb strings.Builder
+	writeText(&sb, doc, "")
+	return strings.TrimSpace(collapseBlankLines(sb.String()))
+}
+
+func writeText(sb *strings.Builder, n *Node, indent string) {
+	switch n.Type {
+	case "text":
+		sb.WriteString(n.Text)
+		for _, m := range n.Marks {
+			if href, ok := m.Attrs["href"].(string); m.Type == "link" && ok && href != n.Text {
+				sb.WriteString(" (" + href + ")")
+			}
+		}
+		return
+	case "hardBreak":
+		sb.WriteString("\n" + indent)
+		return
+	case "mention", "emoji", "status":
+		sb.WriteString(attr(n, "text", "shortName"))
+		return
+	case "inlineCard", "blockCard", "embedCard":
+		sb.WriteString(attr(n, "url"))
+		return
+	case "rule":
+		sb.WriteString("---\n\n")
+		return
+	case "bulletList", "orderedList":
+		for i, item := range n.Content {
+			marker := "- "
+			if n.Type == "orderedList" {
+				marker = strconv.Itoa(i+1) + ". "
+			}
+			sb.WriteString(indent + marker)
+			for _, c := range item.Content {
+				writeText(sb, c, indent+"  ")
+			}
+			if !strings.HasSuffix(sb.String(), "\n") {
+				sb.WriteString("\n")
+			}
+		}
+		sb.WriteString("\n")
+		return
+	}
+
+	for _, c := range n.Content {
+		writeText(sb, c, indent)
+	}
+
+	switch n.Type {
+	case "paragraph", "heading", "codeBlock", "blockquote", "panel", "table", "mediaSingle":
+		if indent != "" {
+			sb.WriteString("\n")
+		} else {
+			sb.WriteString("\n\n")
+		}
+	case "tableCell", "tableHeader":
+		sb.WriteString(" ")
+	case "tableRow":
+		sb.WriteString("\n")
+	}
+}
+
+// attr returns the first of the named attributes that is set on n.
+func attr(n *Node, names ...string) string {
+	for _, name := range names {
+		if v, ok := n.Attrs[name].(string); ok && v != "" {
+			return v
+		}
+	}
+	return ""
+}
+
+// collapseBlankLines reduces runs of blank lines to a single blank line.
+func collapseBlankLines(s string) string {
+	lines := strings.Split(s, "\n")
+	var out []string
+	blank := false
+	for _, line := range lines {
+		line = strings.TrimRight(line, " ")
+		if line == "" {
+			if blank {
+				continue
+			}
+			blank = true
+		} else {
+			blank = false
+		}
+		out = append(out, line)
+	}
+	return strings.Join(out, "\n")
+}
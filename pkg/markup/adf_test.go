@@ -0,0 +1,87 @@
+package markup
+
+import "testing"
+
+func text(s string, marks ...*Mark) *Node {
+	return &Node{Type: "text", Text: s, Marks: marks}
+}
+
+func paragraph(content ...*Node) *Node {
+	return &Node{Type: "paragraph", Content: content}
+}
+
+func TestADFToText(t *testing.T) {
+	tests := []struct {
+		name string
+		doc  *Node
+		want string
+	}{
+		{name: "nil", doc: nil, want: ""},
+		{
+			name: "paragraphs",
+			doc:  &Node{Type: "doc", Content: []*Node{paragraph(text("one"), &Node{Type: "hardBreak"}, text("two")), paragraph(text("three"))}},
+			want: "one\ntwo\n\nthree",
+		},
+		{
+			name: "heading and code",
+			doc:  &Node{Type: "doc", Content: []*Node{{Type: "heading", Content: []*Node{text("Steps")}}, {Type: "codeBlock", Content: []*Node{text("make")}}}},
+			want: "Steps\n\nmake",
+		},
+		{
+			name: "lists",
+			doc: &Node{Type: "doc", Content: []*Node{
+				{Type: "bulletList", Content: []*Node{{Type: "listItem", Content: []*Node{paragraph(text("a"))}}, {Type: "listItem", Content: []*Node{paragraph(text("b"))}}}},
+				{Type: "orderedList", Content: []*Node{{Type: "listItem", Content: []*Node{paragraph(text("c"))}}, {Type: "listItem", Content: []*Node{paragraph(text("d"))}}}},
+			}},
+			want: "- a\n- b\n\n1. c\n2. d",
+		},
+		{
+			name: "links",
+			doc: &Node{Type: "doc", Content: []*Node{paragraph(
+				text("docs", &Mark{Type: "link", Attrs: map[string]interface{}{"href": "https://example.com"}}),
+				text(" "),
+				text("https://example.org", &Mark{Type: "link", Attrs: map[string]interface{}{"href": "https://example.org"}}),
+			)}},
+			want: "docs (https://example.com) https://example.org",
+		},
+		{
+			name: "inline nodes",
+			doc: &Node{Type: "doc", Content: []*Node{paragraph(
+				&Node{Type: "mention", Attrs: map[string]interface{}{"text": "@alice"}},
+				text(" "),
+				&Node{Type: "emoji", Attrs: map[string]interface{}{"shortName": ":tada:"}},
+				text(" "),
+				&Node{Type: "inlineCard", Attrs: map[string]interface{}{"url": "https://example.com/PRJ-1"}},
+			)}},
+			want: "@alice :tada: https://example.com/PRJ-1",
+		},
+		{
+			name: "rule",
+			doc:  &Node{Type: "doc", Content: []*Node{paragraph(text("above")), {Type: "rule"}, paragraph(text("below"))}},
+			want: "above\n\n---\n\nbelow",
+		},
+		{
+			name: "table",
+			doc: &Node{Type: "doc", Content: []*Node{{Type: "table", Content: []*Node{
+				{Type: "tableRow", Content: []*Node{{Type: "tableHeader", Content: []*Node{text("a")}}, {Type: "tableHeader", Content: []*Node{text("b")}}}},
+				{Type: "tableRow", Content: []*Node{{Type: "tableCell", Content: []*Node{text("1")}}, {Type: "tableCell", Content: []*Node{text("2")}}}},
+			}}}},
+			want: "a b\n1 2",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ADFToText(tt.doc); got != tt.want {
+				t.Errorf("ADFToText() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMarkdownADFRoundTrip(t *testing.T) {
+	md := "# Title\n\nSome **bold** text\n\n- one\n- two\n\n```\ncode\n```"
+	want := "Title\n\nSome bold text\n\n- one\n- two\n\ncode"
+	if got := ADFToText(MarkdownToADF(md)); got != want {
+		t.Errorf("ADFToText(MarkdownToADF()) = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,268 @@
+package markup
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// blockKind is the type of a block level Markdown element.
+type blockKind int
+
+const (
+	paragraphBlock blockKind = iota
+	headingBlock
+	bulletListBlock
+	orderedListBlock
+	codeBlock
+	quoteBlock
+	ruleBlock
+)
+
+// block is a block level element of a Markdown document.
+type block struct {
+	kind     blockKind
+	level    int      // heading level
+	language string   // code block language
+	text     string   // code block contents, or the text of paragraphs, headings and quotes
+	items    []string // list items
+}
+
+// span is a run of inline text sharing the same formatting.
+type span struct {
+	text   string
+	strong bool
+	em     bool
+	code   bool
+	href   string
+}
+
+var (
+	headingPattern = regexp.MustCompile(`^(#{1,6})\s+(.*?)\s*#*$`)
+	bulletPattern  = regexp.MustCompile(`^\s*[-*+]\s+(.*)$`)
+	orderedPattern = regexp.MustCompile(`^\s*\d+[.)]\s+(.*)$`)
+	rulePattern    = regexp.MustCompile(`^\s*(?:(?:-\s*){3,}|(?:\*\s*){3,}|(?:_\s*){3,})$`)
+	inlinePattern  = regexp.MustCompile("`([^`]+)`|\\*\\*(.+?)\\*\\*|__(.+?)__|\\*(.+?)\\*|\\b_(.+?)_\\b|\\[([^\\]]+)\\]\\(([^)\\s]+)\\)")
+)
+
+// parseMarkdown splits a Markdown document into blocks. It supports the subset of Markdown
+// commonly used in issue descriptions: headings, paragraphs, lists, fenced code, quotes and rules.
+func parseMarkdown(md string) []block {
+	var blocks []block
+	var current *block
+
+	flush := func() {
+		if current != nil {
+			blocks = append(blocks, *current)
+			current = nil
+		}
+	}
+
+	lines := strings.Split(strings.ReplaceAll(md, "\r\n", "\n"), "\n")
+	for i := 0; i < len(lines); i++ {
+		line := lines[i]
+		trimmed := strings.TrimSpace(line)
+
+		if strings.HasPrefix(trimmed, "```") || strings.HasPrefix(trimmed, "~~~") {
+			flush()
+			fence := trimmed[:3]
+			code := block{kind: codeBlock, language: strings.TrimSpace(trimmed[3:])}
+			var body []string
+			for i++; i < len(lines) && !strings.HasPrefix(strings.TrimSpace(lines[i]), fence); i++ {
+				body = append(body, lines[i])
+			}
+			code.text = strings.Join(body, "\n")
+			blocks = append(blocks, code)
+			continue
+		}
+
+		switch {
+		case trimmed == "":
+			flush()
+		case rulePattern.MatchString(line):
+			flush()
+			blocks = append(blocks, block{kind: ruleBlock})
+		case headingPattern.MatchString(trimmed):
+			flush()
+			m := headingPattern.FindStringSubmatch(trimmed)
+			blocks = append(blocks, block{kind: headingBlock, level: len(m[1]), text: m[2]})
+		case bulletPattern.MatchString(line):
+			if current == nil || current.kind != bulletListBlock {
+				flush()
+				current = &block{kind: bulletListBlock}
+			}
+			current.items = append(current.items, bulletPattern.FindStringSubmatch(line)[1])
+		case orderedPattern.MatchString(line):
+			if current == nil || current.kind != orderedListBlock {
+				flush()
+				current = &block{kind: orderedListBlock}
+			}
+			current.items = append(current.items, orderedPattern.FindStringSubmatch(line)[1])
+		case strings.HasPrefix(trimmed, ">"):
+			text := strings.TrimSpace(strings.TrimPrefix(trimmed, ">"))
+			if current == nil || current.kind != quoteBlock {
+				flush()
+				current = &block{kind: quoteBlock, text: text}
+			} else {
+				current.text += " " + text
+			}
+		default:
+			if current != nil && (current.kind == bulletListBlock || current.kind == orderedListBlock) && strings.HasPrefix(line, " ") {
+				// continuation of the previous list item
+				current.items[len(current.items)-1] += " " + trimmed
+			} else if current == nil || current.kind != paragraphBlock {
+				flush()
+				current = &block{kind: paragraphBlock, text: trimmed}
+			} else {
+				current.text += "\n" + trimmed
+			}
+		}
+	}
+	flush()
+	return blocks
+}
+
+// parseInline splits text into spans of uniformly formatted text. Nested formatting is not supported.
+func parseInline(text string) []span {
+	var spans []span
+	last := 0
+	for _, m := range inlinePattern.FindAllStringSubmatchIndex(text, -1) {
+		if m[0] > last {
+			spans = append(spans, span{text: text[last:m[0]]})
+		}
+		group := func(n int) string { return text[m[2*n]:m[2*n+1]] }
+		switch {
+		case m[2] >= 0:
+			spans = append(spans, span{text: group(1), code: true})
+		case m[4] >= 0:
+			spans = append(spans, span{text: group(2), strong: true})
+		case m[6] >= 0:
+			spans = append(spans, span{text: group(3), strong: true})
+		case m[8] >= 0:
+			spans = append(spans, span{text: group(4), em: true})
+		case m[10] >= 0:
+			spans = append(spans, span{text: group(5), em: true})
+		case m[12] >= 0:
+			spans = append(spans, span{text: group(6), href: group(7)})
+		}
+		last = m[1]
+	}
+	if last < len(text) {
+		spans = append(spans, span{text: text[last:]})
+	}
+	return spans
+}
+
+// MarkdownToWiki converts Markdown to JIRA wiki markup as used by the REST API version 2.
+func MarkdownToWiki(md string) string {
+	var out []string
+	for _, b := range parseMarkdown(md) {
+		switch b.kind {
+		case headingBlock:
+			out = append(out, "h"+strconv.Itoa(b.level)+". "+inlineToWiki(b.text))
+		case bulletListBlock, orderedListBlock:
+			marker := "*"
+			if b.kind == orderedListBlock {
+				marker = "#"
+			}
+			items := make([]string, len(b.items))
+			for i, item := range b.items {
+				items[i] = marker + " " + inlineToWiki(item)
+			}
+			out = append(out, strings.Join(items, "\n"))
+		case codeBlock:
+			open := "{code}"
+			if b.language != "" {
+				open = "{code:" + b.language + "}"
+			}
+			out = append(out, open+"\n"+b.text+"\n{code}")
+		case quoteBlock:
+			out = append(out, "bq. "+inlineToWiki(b.text))
+		case ruleBlock:
+			out = append(out, "----")
+		default:
+			out = append(out, inlineToWiki(b.text))
+		}
+	}
+	return strings.Join(out, "\n\n")
+}
+
+func inlineToWiki(text string) string {
+	var sb strings.Builder
+	for _, s := range parseInline(text) {
+		switch {
+		case s.code:
+			sb.WriteString("{{" + s.text + "}}")
+		case s.strong:
+			sb.WriteString("*" + s.text + "*")
+		case s.em:
+			sb.WriteString("_" + s.text + "_")
+		case s.href != "":
+			sb.WriteString("[" + s.text + "|" + s.href + "]")
+		default:
+			sb.WriteString(s.text)
+		}
+	}
+	return sb.String()
+}
+
+// MarkdownToADF converts Markdown to an Atlassian Document Format document as used by the
+// REST API version 3.
+func MarkdownToADF(md string) *Node {
+	doc := &Node{Type: "doc", Version: 1, Content: []*Node{}}
+	for _, b := range parseMarkdown(md) {
+		switch b.kind {
+		case headingBlock:
+			doc.Content = append(doc.Content, &Node{Type: "heading", Attrs: map[string]interface{}{"level": b.level}, Content: inlineToADF(b.text)})
+		case bulletListBlock, orderedListBlock:
+			list := &Node{Type: "bulletList"}
+			if b.kind == orderedListBlock {
+				list.Type = "orderedList"
+			}
+			for _, item := range b.items {
+				list.Content = append(list.Content, &Node{Type: "listItem", Content: []*Node{{Type: "paragraph", Content: inlineToADF(item)}}})
+			}
+			doc.Content = append(doc.Content, list)
+		case codeBlock:
+			code := &Node{Type: "codeBlock"}
+			if b.language != "" {
+				code.Attrs = map[string]interface{}{"language": b.language}
+			}
+			if b.text != "" {
+				code.Content = []*Node{{Type: "text", Text: b.text}}
+			}
+			doc.Content = append(doc.Content, code)
+		case quoteBlock:
+			doc.Content = append(doc.Content, &Node{Type: "blockquote", Content: []*Node{{Type: "paragraph", Content: inlineToADF(b.text)}}})
+		case ruleBlock:
+			doc.Content = append(doc.Content, &Node{Type: "rule"})
+		default:
+			doc.Content = append(doc.Content, &Node{Type: "paragraph", Content: inlineToADF(b.text)})
+		}
+	}
+	return doc
+}
+
+func inlineToADF(text string) []*Node {
+	var nodes []*Node
+	for i, line := range strings.Split(text, "\n") {
+		if i > 0 {
+			nodes = append(nodes, &Node{Type: "hardBreak"})
+		}
+		for _, s := range parseInline(line) {
+			n := &Node{Type: "text", Text: s.text}
+			switch {
+			case s.code:
+				n.Marks = []*Mark{{Type: "code"}}
+			case s.strong:
+				n.Marks = []*Mark{{Type: "strong"}}
+			case s.em:
+				n.Marks = []*Mark{{Type: "em"}}
+			case s.href != "":
+				n.Marks = []*Mark{{Type: "link", Attrs: map[string]interface{}{"href": s.href}}}
+			}
+			nodes = append(nodes, n)
+		}
+	}
+	return nodes
+}
@@ -0,0 +1,98 @@
+package markup
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestMarkdownToWiki(t *testing.T) {
+	tests := []struct {
+		name string
+		md   string
+		want string
+	}{
+		{name: "empty", md: "", want: ""},
+		{name: "paragraphs", md: "First line\nsecond line\r\n\r\nNext paragraph", want: "First line\nsecond line\n\nNext paragraph"},
+		{name: "headings", md: "# Title\n### Details ###", want: "h1. Title\n\nh3. Details"},
+		{name: "bullet list", md: "- one\n* two\n  continued\n+ three", want: "* one\n* two continued\n* three"},
+		{name: "ordered list", md: "1. one\n2) two", want: "# one\n# two"},
+		{name: "code block", md: "```go\nfmt.Println(\"*x*\")\n```", want: "{code:go}\nfmt.Println(\"*x*\")\n{code}"},
+		{name: "code block without language", md: "~~~\nmake\n~~~", want: "{code}\nmake\n{code}"},
+		{name: "quote", md: "> quoted\n> text", want: "bq. quoted text"},
+		{name: "rule", md: "above\n\n---\n\nbelow", want: "above\n\n----\n\nbelow"},
+		{name: "strong", md: "**bold** and __bold__", want: "*bold* and *bold*"},
+		{name: "emphasis", md: "*it* and _it_", want: "_it_ and _it_"},
+		{name: "inline code", md: "run `make *all*`", want: "run {{make *all*}}"},
+		{name: "link", md: "see [the docs](https://example.com/docs)", want: "see [the docs|https://example.com/docs]"},
+		{name: "underscores within words", md: "snake_case_name", want: "snake_case_name"},
+		{name: "wiki bold becomes emphasis", md: "*bold*", want: "_bold_"},
+		{name: "wiki numbered list becomes a heading", md: "# item", want: "h1. item"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := MarkdownToWiki(tt.md); got != tt.want {
+				t.Errorf("MarkdownToWiki() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMarkdownToADF(t *testing.T) {
+	tests := []struct {
+		name string
+		md   string
+		want string // JSON of the document's content
+	}{
+		{name: "empty", md: "", want: `[]`},
+		{
+			name: "paragraph with hard break",
+			md:   "one\ntwo",
+			want: `[{"type":"paragraph","content":[{"type":"text","text":"one"},{"type":"hardBreak"},{"type":"text","text":"two"}]}]`,
+		},
+		{
+			name: "heading",
+			md:   "## Title",
+			want: `[{"type":"heading","attrs":{"level":2},"content":[{"type":"text","text":"Title"}]}]`,
+		},
+		{
+			name: "lists",
+			md:   "- a\n\n1. b",
+			want: `[{"type":"bulletList","content":[{"type":"listItem","content":[{"type":"paragraph","content":[{"type":"text","text":"a"}]}]}]},` +
+				`{"type":"orderedList","content":[{"type":"listItem","content":[{"type":"paragraph","content":[{"type":"text","text":"b"}]}]}]}]`,
+		},
+		{
+			name: "code block",
+			md:   "```sh\nmake\n```",
+			want: `[{"type":"codeBlock","attrs":{"language":"sh"},"content":[{"type":"text","text":"make"}]}]`,
+		},
+		{name: "empty code block", md: "```\n```", want: `[{"type":"codeBlock"}]`},
+		{
+			name: "quote and rule",
+			md:   "> q\n\n***",
+			want: `[{"type":"blockquote","content":[{"type":"paragraph","content":[{"type":"text","text":"q"}]}]},{"type":"rule"}]`,
+		},
+		{
+			name: "marks",
+			md:   "**b** *e* `c` [l](https://example.com)",
+			want: `[{"type":"paragraph","content":[{"type":"text","text":"b","marks":[{"type":"strong"}]},{"type":"text","text":" "},` +
+				`{"type":"text","text":"e","marks":[{"type":"em"}]},{"type":"text","text":" "},` +
+				`{"type":"text","text":"c","marks":[{"type":"code"}]},{"type":"text","text":" "},` +
+				`{"type":"text","text":"l","marks":[{"type":"link","attrs":{"href":"https://example.com"}}]}]}]`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			doc := MarkdownToADF(tt.md)
+			if doc.Type != "doc" || doc.Version != 1 {
+				t.Errorf("MarkdownToADF() = %s document version %d, want doc version 1", doc.Type, doc.Version)
+			}
+			got, err := json.Marshal(doc.Content)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(got) != tt.want {
+				t.Errorf("MarkdownToADF() content = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,38 @@
+package markup
+
+import (
+	"regexp"
+	"strings"
+)
+
+// wikiReplacements strip JIRA wiki markup, applied in order.
+var wikiReplacements = []struct {
+	pattern     *regexp.Regexp
+	replacement string
+}{
+	{regexp.MustCompile(`\{\{(.+?)\}\}`), "$1"},
+	// block macros such as {code:java}, {noformat}, {quote} and {panel:title=x} on their own
+	{regexp.MustCompile(`\{(code|noformat|quote|panel|color)(:[^}]*)?\}`), ""},
+	{regexp.MustCompile(`(?m)^h[1-6]\.[ \t]*`), ""},
+	{regexp.MustCompile(`(?m)^bq\.[ \t]*`), ""},
+	{regexp.MustCompile(`(?m)^[ \t]*[*#-]+[ \t]+`), "- "},
+	{regexp.MustCompile(`(?m)^-{4,}[ \t]*$`), "---"},
+	{regexp.MustCompile(`!\S+?!`), ""},
+	{regexp.MustCompile(`\[([^|\]]+)\|([^\]]+)\]`), "$1 ($2)"},
+	{regexp.MustCompile(`\[(https?://[^\]]+)\]`), "$1"},
+	{regexp.MustCompile(`\[~([^\]]+)\]`), "@$1"},
+	{regexp.MustCompile(`(^|\W)\*(\S(?:.*?\S)?)\*(\W|$)`), "$1$2$3"},
+	{regexp.MustCompile(`(^|\W)_(\S(?:.*?\S)?)_(\W|$)`), "$1$2$3"},
+	{regexp.MustCompile(`\|\|`), "|"},
+	{regexp.MustCompile(`\\\\`), "\n"},
+}
+
+// WikiToText converts JIRA wiki markup, as returned by the REST API version 2, to plain text
+// suitable for a commit message.
+func WikiToText(wiki string) string {
+	text := strings.ReplaceAll(wiki, "\r\n", "\n")
+	for _, r := range wikiReplacements {
+		text = r.pattern.ReplaceAllString(text, r.replacement)
+	}
+	return strings.TrimSpace(collapseBlankLines(text))
+}
@@ -0,0 +1,34 @@
+package markup
+
+import "testing"
+
+func TestWikiToText(t *testing.T) {
+	tests := []struct {
+		name string
+		wiki string
+		want string
+	}{
+		{name: "plain text", wiki: "Nothing to do", want: "Nothing to do"},
+		{name: "headings and quotes", wiki: "h2. Steps\r\nbq. quoted", want: "Steps\nquoted"},
+		{name: "lists", wiki: "* one\n** nested\n# first\n- dash", want: "- one\n- nested\n- first\n- dash"},
+		{name: "code block", wiki: "{code:java}\nint x = 1;\n{code}", want: "int x = 1;"},
+		{name: "panel and noformat", wiki: "{panel:title=Note}\nhi\n{panel}\n{noformat}raw{noformat}", want: "hi\n\nraw"},
+		{name: "monospace", wiki: "run {{make}}", want: "run make"},
+		{name: "bold and italic", wiki: "*bold* and _italic_ text", want: "bold and italic text"},
+		{name: "asterisks within words", wiki: "2*3*4", want: "2*3*4"},
+		{name: "links", wiki: "[the docs|https://example.com] and [https://example.org]", want: "the docs (https://example.com) and https://example.org"},
+		{name: "mention", wiki: "ask [~alice]", want: "ask @alice"},
+		{name: "images", wiki: "see !screenshot.png! above", want: "see  above"},
+		{name: "rule", wiki: "above\n----\nbelow", want: "above\n---\nbelow"},
+		{name: "table", wiki: "||a||b||\n|1|2|", want: "|a|b|\n|1|2|"},
+		{name: "line breaks", wiki: "one\\\\two", want: "one\ntwo"},
+		{name: "blank lines collapse", wiki: "one\n\n\n\ntwo  \n", want: "one\n\ntwo"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := WikiToText(tt.wiki); got != tt.want {
+				t.Errorf("WikiToText() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,172 @@
+package metadata
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+
+	"github.com/kunickiaj/beer/pkg/wildmatch"
+)
+
+// FileRule adds components and labels when a file matching Glob is changed on the branch, or
+// present in the repository when nothing has changed yet. Globs match paths relative to the root
+// of the repository, where * doesn't match a slash and ** matches any number of directories,
+// e.g. *.tf for Terraform files in the root or **/*.tf for any.
+type FileRule struct {
+	Glob       string
+	Components []string
+	Labels     []string
+}
+
+// RepoRule sets the project and adds components and labels when the URL of the repository
+// matches the regular expression Pattern. Values can refer to submatches, e.g. $1 or ${name}.
+type RepoRule struct {
+	Pattern    string
+	Project    string
+	Components []string
+	Labels     []string
+}
+
+// Rules map files and repositories to issue metadata.
+type Rules struct {
+	Files []FileRule
+	Repos []RepoRule
+}
+
+// DefaultRules are used when none are configured: Terraform files in the root of the
+// repository add a Terraform component and the name of the repository is added as a component.
+var DefaultRules = Rules{
+	Files: []FileRule{{Glob: "*.tf", Components: []string{"Terraform"}}},
+	Repos: []RepoRule{{Pattern: `([^/:]+?)(?:\.git)?/?$`, Components: []string{"$1"}}},
+}
+
+// Result is the metadata detected for an issue.
+type Result struct {
+	Project    string   `json:"project,omitempty" yaml:"project,omitempty"`
+	Components []string `json:"components,omitempty" yaml:"components,omitempty"`
+	Labels     []string `json:"labels,omitempty" yaml:"labels,omitempty"`
+}
+
+// IsEmpty reports whether nothing was detected.
+func (r *Result) IsEmpty() bool {
+	return r.Project == "" && len(r.Components) == 0 && len(r.Labels) == 0
+}
+
+// Detect applies the rules to a list of slash separated file paths and a repository URL, which
+// may be empty. The project is taken from the first matching repository rule that sets one.
+func (r Rules) Detect(files []string, repoURL string) (*Result, error) {
+	result := &Result{}
+
+	for _, rule := range r.Files {
+		re, err := wildmatch.Compile(rule.Glob, false)
+		if err != nil {
+			return nil, fmt.Errorf("invalid glob %q: %w", rule.Glob, err)
+		}
+		for _, f := range files {
+			if re.MatchString(f) {
+				result.add(rule.Components, rule.Labels)
+				break
+			}
+		}
+	}
+
+	if repoURL == "" {
+		return result, nil
+	}
+	for _, rule := range r.Repos {
+		re, err := regexp.Compile(rule.Pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid repository pattern %q: %w", rule.Pattern, err)
+		}
+		match := re.FindStringSubmatchIndex(repoURL)
+		if match == nil {
+			continue
+		}
+		expand := func(values []string) []string {
+			var expanded []string
+			for _, v := range values {
+				if v = string(re.ExpandString(nil, v, repoURL, match)); v != "" {
+					expanded = append(expanded, v)
+				}
+			}
+			return expanded
+		}
+		if project := expand([]string{rule.Project}); result.Project == "" && len(project) > 0 {
+			result.Project = strings.ToUpper(project[0])
+		}
+		result.add(expand(rule.Components), expand(rule.Labels))
+	}
+	return result, nil
+}
+
+// add appends components and labels that haven't been added yet, ignoring case.
+func (r *Result) add(components []string, labels []string) {
+	r.Components = appendMissing(r.Components, components...)
+	r.Labels = appendMissing(r.Labels, labels...)
+}
+
+// appendMissing appends the values that aren't in list yet, ignoring case.
+func appendMissing(list []string, values ...string) []string {
+	for _, v := range values {
+		if !Contains(list, v) {
+			list = append(list, v)
+		}
+	}
+	return list
+}
+
+// Contains reports whether list contains value, ignoring case like JIRA does for components.
+func Contains(list []string, value string) bool {
+	for _, v := range list {
+		if strings.EqualFold(v, value) {
+			return true
+		}
+	}
+	return false
+}
+
+// TrackedFiles lists the files tracked in a repository's index, or in the tree of HEAD if the
+// index is empty, so untracked and ignored files such as dependencies and build output are
+// skipped. Paths are slash separated and sorted.
+func TrackedFiles(repo *git.Repository) ([]string, error) {
+	var files []string
+	idx, err := repo.Storer.Index()
+	if err != nil {
+		return nil, err
+	}
+	for _, e := range idx.Entries {
+		files = append(files, e.Name)
+	}
+
+	if len(files) == 0 {
+		head, err := repo.Head()
+		if errors.Is(err, plumbing.ErrReferenceNotFound) {
+			return nil, nil
+		} else if err != nil {
+			return nil, err
+		}
+		commit, err := repo.CommitObject(head.Hash())
+		if err != nil {
+			return nil, err
+		}
+		tree, err := commit.Tree()
+		if err != nil {
+			return nil, err
+		}
+		err = tree.Files().ForEach(func(f *object.File) error {
+			files = append(files, f.Name)
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	sort.Strings(files)
+	return files, nil
+}
@@ -0,0 +1,162 @@
+package metadata
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-git/go-billy/v5/memfs"
+	"github.com/go-git/go-billy/v5/util"
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing/format/index"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/go-git/go-git/v5/storage/memory"
+)
+
+func TestDetect(t *testing.T) {
+	rules := Rules{
+		Files: []FileRule{
+			{Glob: "**/*.tf", Components: []string{"Terraform"}},
+			{Glob: "docs/**", Labels: []string{"docs"}},
+			{Glob: "*.go", Components: []string{"terraform"}, Labels: []string{"go"}},
+		},
+		Repos: []RepoRule{
+			{Pattern: `github\.com[:/]acme/(?P<repo>[^/]+?)(\.git)?$`, Project: "prj", Components: []string{"${repo}"}},
+			{Pattern: `github\.com[:/]acme/`, Project: "OTHER", Labels: []string{"acme"}},
+		},
+	}
+	tests := []struct {
+		name  string
+		rules Rules
+		files []string
+		url   string
+		want  Result
+	}{
+		{
+			name:  "nested glob",
+			rules: rules,
+			files: []string{"modules/vpc/main.tf"},
+			want:  Result{Components: []string{"Terraform"}},
+		},
+		{
+			name:  "components are added once ignoring case",
+			rules: rules,
+			files: []string{"main.tf", "main.go", "docs/a/index.md"},
+			want:  Result{Components: []string{"Terraform"}, Labels: []string{"docs", "go"}},
+		},
+		{
+			name:  "root only glob",
+			rules: rules,
+			files: []string{"cmd/main.go"},
+			want:  Result{},
+		},
+		{
+			name:  "first matching repository rule sets the project",
+			rules: rules,
+			url:   "git@github.com:acme/widgets.git",
+			want:  Result{Project: "PRJ", Components: []string{"widgets"}, Labels: []string{"acme"}},
+		},
+		{
+			name:  "no match",
+			rules: rules,
+			files: []string{"README.md"},
+			url:   "https://gitlab.com/acme/widgets",
+			want:  Result{},
+		},
+		{
+			name:  "default rules",
+			rules: DefaultRules,
+			files: []string{"main.tf"},
+			url:   "ssh://git@example.com:29418/team/widgets.git",
+			want:  Result{Components: []string{"Terraform", "widgets"}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.rules.Detect(tt.files, tt.url)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(*got, tt.want) {
+				t.Errorf("Detect() = %+v, want %+v", *got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDetectInvalidRules(t *testing.T) {
+	if _, err := (Rules{Repos: []RepoRule{{Pattern: "("}}}).Detect(nil, "https://example.com/x"); err == nil {
+		t.Error("Detect() with an invalid pattern succeeded")
+	}
+}
+
+func TestTrackedFiles(t *testing.T) {
+	tests := []struct {
+		name      string
+		tracked   []string
+		untracked []string
+		dropIndex bool // read the files from HEAD instead
+		want      string
+	}{
+		{
+			name:      "untracked and ignored files are skipped",
+			tracked:   []string{"main.tf", "modules/vpc/main.tf", ".gitignore"},
+			untracked: []string{"node_modules/left-pad/index.js", "build/out.bin"},
+			want:      ".gitignore main.tf modules/vpc/main.tf",
+		},
+		{
+			name:      "files from HEAD without an index",
+			tracked:   []string{"b.go", "a/a.go"},
+			untracked: []string{"c.go"},
+			dropIndex: true,
+			want:      "a/a.go b.go",
+		},
+		{
+			name: "empty repository",
+			want: "",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fs := memfs.New()
+			repo, err := git.Init(memory.NewStorage(), fs)
+			if err != nil {
+				t.Fatal(err)
+			}
+			workTree, err := repo.Worktree()
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, f := range append(tt.tracked, tt.untracked...) {
+				if err := util.WriteFile(fs, f, []byte(f), 0o644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			for _, f := range tt.tracked {
+				if _, err := workTree.Add(f); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if len(tt.tracked) > 0 {
+				sig := &object.Signature{Name: "Test", Email: "test@example.com", When: time.Now()}
+				if _, err := workTree.Commit("PRJ-1. Files", &git.CommitOptions{Author: sig}); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if tt.dropIndex {
+				if err := repo.Storer.SetIndex(&index.Index{Version: 2}); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			files, err := TrackedFiles(repo)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := strings.Join(files, " "); got != tt.want {
+				t.Errorf("TrackedFiles() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
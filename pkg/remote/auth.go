@@ -0,0 +1,164 @@
+package remote
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/go-git/go-git/v5/plumbing/transport"
+	githttp "github.com/go-git/go-git/v5/plumbing/transport/http"
+	gitssh "github.com/go-git/go-git/v5/plumbing/transport/ssh"
+	"github.com/mitchellh/go-homedir"
+	log "github.com/sirupsen/logrus"
+	"golang.org/x/crypto/ssh"
+)
+
+// defaultIdentities are the private keys ssh tries when no agent is running.
+var defaultIdentities = []string{"~/.ssh/id_ed25519", "~/.ssh/id_ecdsa", "~/.ssh/id_rsa"}
+
+// AuthOptions configures how beer authenticates to remotes.
+type AuthOptions struct {
+	SSHKey     string                           // Private key for SSH remotes instead of ssh-agent
+	Username   string                           // User for HTTPS remotes when the URL has none
+	Token      string                           // Password or access token for HTTPS remotes
+	Passphrase func(key string) ([]byte, error) // Asks for the passphrase of an encrypted SSH key
+}
+
+// Auth returns the credentials for a remote URL. SSH remotes use the configured key, then
+// ssh-agent, then the default keys in ~/.ssh. HTTPS remotes use the configured token, then the
+// user's git credential helper. It returns nil when no credentials are needed or found.
+func Auth(url string, opts AuthOptions) (transport.AuthMethod, error) {
+	ep, err := transport.NewEndpoint(url)
+	if err != nil {
+		return nil, err
+	}
+
+	switch ep.Protocol {
+	case "ssh":
+		return sshAuth(firstNonEmpty(ep.User, gitssh.DefaultUsername), opts)
+	case "http", "https":
+		if ep.Password != "" {
+			return nil, nil
+		}
+		user := firstNonEmpty(ep.User, opts.Username)
+		if opts.Token != "" {
+			// GitHub and most forges accept any user name with a token
+			return &githttp.BasicAuth{Username: firstNonEmpty(user, "git"), Password: opts.Token}, nil
+		}
+		return credentialHelper(ep, user)
+	}
+	return nil, nil
+}
+
+func sshAuth(user string, opts AuthOptions) (transport.AuthMethod, error) {
+	if opts.SSHKey != "" {
+		key, err := homedir.Expand(opts.SSHKey)
+		if err != nil {
+			return nil, err
+		}
+		return publicKeys(user, key, opts.Passphrase)
+	}
+
+	if sock := os.Getenv("SSH_AUTH_SOCK"); sock != "" {
+		if conn, err := net.Dial("unix", sock); err == nil {
+			_ = conn.Close()
+			return gitssh.NewSSHAgentAuth(user)
+		}
+		log.WithField("socket", sock).Debug("Unable to reach ssh-agent")
+	}
+
+	for _, identity := range defaultIdentities {
+		key, err := homedir.Expand(identity)
+		if err != nil {
+			continue
+		}
+		if _, err := os.Stat(key); err == nil {
+			log.WithField("key", key).Debug("Using default SSH key")
+			return publicKeys(user, key, opts.Passphrase)
+		}
+	}
+	return nil, errors.New("no SSH key found: start ssh-agent and add your key with ssh-add, or set git.sshKey")
+}
+
+// publicKeys reads a private key file, asking for its passphrase if it is encrypted.
+func publicKeys(user string, file string, passphrase func(string) ([]byte, error)) (transport.AuthMethod, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read SSH key: %w", err)
+	}
+	signer, err := ssh.ParsePrivateKey(data)
+	var missing *ssh.PassphraseMissingError
+	if errors.As(err, &missing) && passphrase != nil {
+		var pass []byte
+		if pass, err = passphrase(filepath.Base(file)); err != nil {
+			return nil, err
+		}
+		signer, err = ssh.ParsePrivateKeyWithPassphrase(data, pass)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("unable to load SSH key %s: %w", file, err)
+	}
+	return &gitssh.PublicKeys{User: user, Signer: signer}, nil
+}
+
+// credentialHelper asks git's credential helpers for the user name and password of an HTTPS
+// remote. It requires the git command line tool and returns nil if it isn't available or no
+// helper is configured.
+func credentialHelper(ep *transport.Endpoint, user string) (transport.AuthMethod, error) {
+	var input bytes.Buffer
+	fmt.Fprintf(&input, "protocol=%s\nhost=%s\n", ep.Protocol, hostPort(ep))
+	if path := strings.TrimPrefix(ep.Path, "/"); path != "" {
+		fmt.Fprintf(&input, "path=%s\n", path)
+	}
+	if user != "" {
+		fmt.Fprintf(&input, "username=%s\n", user)
+	}
+	input.WriteString("\n")
+
+	cmd := exec.Command("git", "credential", "fill")
+	cmd.Stdin = &input
+	cmd.Stderr = os.Stderr
+	// only use stored credentials, beer asks for anything else itself
+	cmd.Env = append(os.Environ(), "GIT_TERMINAL_PROMPT=0", "GIT_ASKPASS=", "SSH_ASKPASS=")
+	out, err := cmd.Output()
+	if err != nil {
+		log.WithError(err).Debug("No credentials from git credential helper")
+		return nil, nil
+	}
+
+	auth := &githttp.BasicAuth{}
+	for _, line := range strings.Split(string(out), "\n") {
+		key, value, _ := strings.Cut(line, "=")
+		switch key {
+		case "username":
+			auth.Username = value
+		case "password":
+			auth.Password = value
+		}
+	}
+	if auth.Password == "" {
+		return nil, nil
+	}
+	return auth, nil
+}
+
+func hostPort(ep *transport.Endpoint) string {
+	if ep.Port == 0 {
+		return ep.Host
+	}
+	return fmt.Sprintf("%s:%d", ep.Host, ep.Port)
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
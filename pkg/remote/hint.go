@@ -0,0 +1,69 @@
+package remote
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/go-git/go-git/v5/plumbing/transport"
+)
+
+// Hint adds advice on how to fix an error returned when pushing to or fetching from url, such
+// as an unknown SSH host key or rejected credentials. Other errors are returned unchanged.
+func Hint(err error, url string) error {
+	if err == nil {
+		return nil
+	}
+	ep, perr := transport.NewEndpoint(url)
+	if perr != nil {
+		return err
+	}
+	ssh := ep.Protocol == "ssh"
+	msg := err.Error()
+
+	var hint string
+	switch {
+	case strings.Contains(msg, "knownhosts: key is unknown"), strings.Contains(msg, "Host key verification failed"):
+		hint = fmt.Sprintf("the SSH host key of %s isn't known, connect once with 'ssh -p %d %s' to add it to ~/.ssh/known_hosts", ep.Host, port(ep), ep.Host)
+	case strings.Contains(msg, "knownhosts: key mismatch"):
+		hint = fmt.Sprintf("the SSH host key of %s doesn't match ~/.ssh/known_hosts, check it hasn't been tampered with before updating the file", ep.Host)
+	case !IsAuthError(err):
+		return err
+	case ssh:
+		hint = fmt.Sprintf("check your key is loaded with 'ssh-add -l' or set git.sshKey, and that its public key is registered for user %s on %s", firstNonEmpty(ep.User, "git"), ep.Host)
+	default:
+		hint = fmt.Sprintf("set git.token to an access token for %s or store your credentials with a git credential helper", ep.Host)
+	}
+	return fmt.Errorf("%w\nhint: %s", err, hint)
+}
+
+// authMessages are the messages of go-git's SSH client and of the git command line tool when a
+// remote rejects the credentials.
+var authMessages = []string{
+	"ssh: unable to authenticate",
+	"Permission denied (publickey",
+	"Authentication failed",
+	"could not read Username",
+	"terminal prompts disabled",
+}
+
+// IsAuthError reports whether a git transport error means the remote rejected the credentials.
+func IsAuthError(err error) bool {
+	if errors.Is(err, transport.ErrAuthenticationRequired) || errors.Is(err, transport.ErrAuthorizationFailed) {
+		return true
+	}
+	msg := err.Error()
+	for _, m := range authMessages {
+		if strings.Contains(msg, m) {
+			return true
+		}
+	}
+	return false
+}
+
+func port(ep *transport.Endpoint) int {
+	if ep.Port == 0 {
+		return 22
+	}
+	return ep.Port
+}
@@ -0,0 +1,91 @@
+package remote
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/go-git/go-git/v5/plumbing/transport"
+)
+
+func TestHint(t *testing.T) {
+	tests := []struct {
+		name     string
+		err      error
+		url      string
+		wantHint string // empty if err is returned unchanged
+	}{
+		{
+			name:     "unknown host key",
+			err:      errors.New("ssh: handshake failed: knownhosts: key is unknown"),
+			url:      "ssh://jdoe@gerrit.example.com:29418/project",
+			wantHint: "connect once with 'ssh -p 29418 gerrit.example.com'",
+		},
+		{
+			name:     "unknown host key from git",
+			err:      errors.New("Host key verification failed.\nfatal: Could not read from remote repository."),
+			url:      "git@github.com:acme/widgets.git",
+			wantHint: "connect once with 'ssh -p 22 github.com'",
+		},
+		{
+			name:     "changed host key",
+			err:      errors.New("ssh: handshake failed: knownhosts: key mismatch"),
+			url:      "git@github.com:acme/widgets.git",
+			wantHint: "the SSH host key of github.com doesn't match",
+		},
+		{
+			name:     "rejected SSH key",
+			err:      errors.New("ssh: handshake failed: ssh: unable to authenticate, attempted methods [none publickey]"),
+			url:      "ssh://jdoe@gerrit.example.com:29418/project",
+			wantHint: "registered for user jdoe on gerrit.example.com",
+		},
+		{
+			name:     "rejected SSH key without a user",
+			err:      errors.New("git@github.com: Permission denied (publickey)."),
+			url:      "ssh://github.com/acme/widgets.git",
+			wantHint: "registered for user git on github.com",
+		},
+		{
+			name:     "HTTPS credentials required",
+			err:      transport.ErrAuthenticationRequired,
+			url:      "https://github.com/acme/widgets.git",
+			wantHint: "set git.token to an access token for github.com",
+		},
+		{
+			name:     "HTTPS credentials rejected by git",
+			err:      errors.New("fatal: Authentication failed for 'https://github.com/acme/widgets.git/'"),
+			url:      "https://github.com/acme/widgets.git",
+			wantHint: "git credential helper",
+		},
+		{
+			name: "other errors",
+			err:  errors.New("fatal: couldn't find remote ref main"),
+			url:  "https://github.com/acme/widgets.git",
+		},
+		{
+			name: "invalid URL",
+			err:  transport.ErrAuthenticationRequired,
+			url:  "https://[::1",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Hint(tt.err, tt.url)
+			if !errors.Is(got, tt.err) {
+				t.Errorf("Hint() = %v, doesn't wrap %v", got, tt.err)
+			}
+			if tt.wantHint == "" {
+				if got != tt.err {
+					t.Errorf("Hint() = %v, want the error unchanged", got)
+				}
+				return
+			}
+			if !strings.HasPrefix(got.Error(), tt.err.Error()+"\nhint: ") || !strings.Contains(got.Error(), tt.wantHint) {
+				t.Errorf("Hint() = %v, want a hint containing %q", got, tt.wantHint)
+			}
+		})
+	}
+	if Hint(nil, "https://github.com/acme/widgets.git") != nil {
+		t.Error("Hint(nil) isn't nil")
+	}
+}
@@ -0,0 +1,51 @@
+package remote
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/kunickiaj/beer/pkg/gitconfig"
+)
+
+// Remote is a git remote with its URLs resolved the way git resolves them.
+type Remote struct {
+	Name    string
+	URL     string // Used to fetch, after url.<base>.insteadOf rewriting
+	PushURL string // Used to push: remote.<name>.pushurl or URL, after pushInsteadOf rewriting
+}
+
+// Resolve looks up a remote in the git configuration.
+func Resolve(cfg *gitconfig.Config, name string) (*Remote, error) {
+	urls := cfg.GetAll("remote." + name + ".url")
+	if len(urls) == 0 {
+		return nil, fmt.Errorf("remote %s doesn't exist or has no URL", name)
+	}
+
+	r := &Remote{Name: name, URL: rewrite(cfg, urls[0], "insteadOf")}
+	if pushURLs := cfg.GetAll("remote." + name + ".pushurl"); len(pushURLs) > 0 {
+		// pushInsteadOf doesn't apply to explicit push URLs
+		r.PushURL = rewrite(cfg, pushURLs[0], "insteadOf")
+	} else if pushURL := rewrite(cfg, urls[0], "pushInsteadOf"); pushURL != urls[0] {
+		r.PushURL = pushURL
+	} else {
+		r.PushURL = r.URL
+	}
+	return r, nil
+}
+
+// rewrite replaces the longest prefix of url matching a url.<base>.insteadOf (or
+// pushInsteadOf) value with <base>.
+func rewrite(cfg *gitconfig.Config, url string, key string) string {
+	best, match := "", ""
+	for _, base := range cfg.Subsections("url") {
+		for _, prefix := range cfg.GetAll("url." + base + "." + key) {
+			if strings.HasPrefix(url, prefix) && len(prefix) > len(match) {
+				best, match = base, prefix
+			}
+		}
+	}
+	if match == "" {
+		return url
+	}
+	return best + strings.TrimPrefix(url, match)
+}
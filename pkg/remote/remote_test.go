@@ -0,0 +1,99 @@
+package remote
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/kunickiaj/beer/pkg/gitconfig"
+)
+
+// loadConfig returns the configuration of a repository whose local config is content, isolated
+// from the user's configuration.
+func loadConfig(t *testing.T, content string) *gitconfig.Config {
+	t.Helper()
+	dir := t.TempDir()
+	t.Setenv("GIT_CONFIG_NOSYSTEM", "1")
+	t.Setenv("GIT_CONFIG_GLOBAL", os.DevNull)
+	t.Setenv("GIT_CONFIG_COUNT", "")
+	if err := os.WriteFile(filepath.Join(dir, "config"), []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	cfg, err := gitconfig.Load(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return cfg
+}
+
+func TestResolve(t *testing.T) {
+	tests := []struct {
+		name        string
+		config      string
+		wantURL     string
+		wantPushURL string
+	}{
+		{
+			name:        "plain URL",
+			config:      "[remote \"origin\"]\n\turl = https://github.com/acme/widgets.git\n",
+			wantURL:     "https://github.com/acme/widgets.git",
+			wantPushURL: "https://github.com/acme/widgets.git",
+		},
+		{
+			name:        "first of several URLs",
+			config:      "[remote \"origin\"]\n\turl = https://github.com/acme/widgets.git\n\turl = https://mirror.example.com/widgets.git\n",
+			wantURL:     "https://github.com/acme/widgets.git",
+			wantPushURL: "https://github.com/acme/widgets.git",
+		},
+		{
+			name:        "insteadOf applies to fetching and pushing",
+			config:      "[url \"git@github.com:\"]\n\tinsteadOf = gh:\n[remote \"origin\"]\n\turl = gh:acme/widgets.git\n",
+			wantURL:     "git@github.com:acme/widgets.git",
+			wantPushURL: "git@github.com:acme/widgets.git",
+		},
+		{
+			name:        "longest insteadOf prefix wins",
+			config:      "[url \"https://a.example.com/\"]\n\tinsteadOf = https://example.com/\n[url \"https://b.example.com/acme/\"]\n\tinsteadOf = https://example.com/acme/\n[remote \"origin\"]\n\turl = https://example.com/acme/widgets.git\n",
+			wantURL:     "https://b.example.com/acme/widgets.git",
+			wantPushURL: "https://b.example.com/acme/widgets.git",
+		},
+		{
+			name:        "pushInsteadOf only applies to pushing",
+			config:      "[url \"ssh://git@github.com/\"]\n\tpushInsteadOf = https://github.com/\n[remote \"origin\"]\n\turl = https://github.com/acme/widgets.git\n",
+			wantURL:     "https://github.com/acme/widgets.git",
+			wantPushURL: "ssh://git@github.com/acme/widgets.git",
+		},
+		{
+			name:        "pushurl takes precedence over pushInsteadOf",
+			config:      "[url \"ssh://git@github.com/\"]\n\tpushInsteadOf = https://github.com/\n[remote \"origin\"]\n\turl = https://github.com/acme/widgets.git\n\tpushurl = https://github.com/me/widgets.git\n",
+			wantURL:     "https://github.com/acme/widgets.git",
+			wantPushURL: "https://github.com/me/widgets.git",
+		},
+		{
+			name:        "insteadOf applies to pushurl",
+			config:      "[url \"git@github.com:\"]\n\tinsteadOf = gh:\n[remote \"origin\"]\n\turl = https://github.com/acme/widgets.git\n\tpushurl = gh:me/widgets.git\n",
+			wantURL:     "https://github.com/acme/widgets.git",
+			wantPushURL: "git@github.com:me/widgets.git",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r, err := Resolve(loadConfig(t, tt.config), "origin")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if r.Name != "origin" || r.URL != tt.wantURL || r.PushURL != tt.wantPushURL {
+				t.Errorf("Resolve() = %+v, want URL %s and push URL %s", r, tt.wantURL, tt.wantPushURL)
+			}
+		})
+	}
+}
+
+func TestResolveUnknownRemote(t *testing.T) {
+	cfg := loadConfig(t, "[remote \"origin\"]\n\tfetch = +refs/heads/*:refs/remotes/origin/*\n")
+	for _, name := range []string{"origin", "upstream"} {
+		if r, err := Resolve(cfg, name); err == nil {
+			t.Errorf("Resolve(%s) = %+v, want an error", name, r)
+		}
+	}
+}
@@ -0,0 +1,63 @@
+package review
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing/transport"
+
+	"github.com/kunickiaj/beer/pkg/remote"
+)
+
+// Error categories returned by review tools. Errors are wrapped so callers can
+// check the category with errors.Is and still see the underlying cause.
+var (
+	ErrAuthentication = errors.New("authentication failed")
+	ErrNotFound       = errors.New("not found")
+	ErrConflict       = errors.New("conflict")
+	ErrNetwork        = errors.New("network error")
+	ErrNotImplemented = errors.New("functionality not yet implemented")
+)
+
+// ClassifyGitError wraps an error returned by a git push or fetch with the matching error category.
+func ClassifyGitError(err error) error {
+	var netErr net.Error
+	switch {
+	case err == nil:
+		return nil
+	case remote.IsAuthError(err):
+		return fmt.Errorf("%w: %w", ErrAuthentication, err)
+	case errors.Is(err, transport.ErrRepositoryNotFound), errors.Is(err, git.ErrRemoteNotFound), containsAny(err, notFoundMessages):
+		return fmt.Errorf("%w: %w", ErrNotFound, err)
+	case errors.Is(err, git.ErrNonFastForwardUpdate), isRejected(err):
+		return fmt.Errorf("%w: push rejected: %w", ErrConflict, err)
+	case errors.As(err, &netErr), containsAny(err, networkMessages):
+		return fmt.Errorf("%w: %w", ErrNetwork, err)
+	}
+	return err
+}
+
+// Messages of the git command line tool, which are all the exec backend has to go on.
+var (
+	notFoundMessages = []string{"Repository not found", "does not appear to be a git repository", "No such remote"}
+	networkMessages  = []string{"Could not resolve host", "Connection refused", "Connection timed out", "Network is unreachable"}
+)
+
+func containsAny(err error, messages []string) bool {
+	msg := err.Error()
+	for _, m := range messages {
+		if strings.Contains(msg, m) {
+			return true
+		}
+	}
+	return false
+}
+
+// isRejected reports whether the remote refused one of the pushed references, e.g. Gerrit's "no new changes".
+func isRejected(err error) bool {
+	msg := err.Error()
+	return strings.Contains(msg, "command error on") || strings.Contains(msg, "rejected")
+}
@@ -1,20 +1,32 @@
 package review
 
 import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
 	"fmt"
 	"os"
+	"regexp"
+	"strconv"
 	"strings"
 
 	"github.com/go-git/go-git/v5"
-	"github.com/go-git/go-git/v5/config"
 	log "github.com/sirupsen/logrus"
+
+	"github.com/kunickiaj/beer/pkg/gitbackend"
+	"github.com/kunickiaj/beer/pkg/remote"
 )
 
+// changeURLPattern matches the change URL Gerrit reports after receiving a push,
+// e.g. https://review.example.com/c/project/+/1234
+var changeURLPattern = regexp.MustCompile(`(https?://\S+?/(\d+))(?:\s|$)`)
+
 type GerritReview struct {
 	Meta
+	API GerritAPI // Looks up the patchset number of published changes, if its URL is set
 }
 
-func NewGerritReview(title string, description string, reviewers []string, baseBranch string, isDraft bool) Review {
+func NewGerritReview(title string, description string, reviewers []string, baseBranch string, isDraft bool, target Target, api GerritAPI) Review {
 	return &GerritReview{
 		Meta: Meta{
 			Title:       title,
@@ -22,19 +34,45 @@ func NewGerritReview(title string, description string, reviewers []string, baseB
 			Reviewers:   reviewers,
 			BaseBranch:  baseBranch,
 			IsDraft:     isDraft,
+			Target:      target,
 		},
+		API: api,
+	}
+}
+
+// GerritAPI is the REST API of a Gerrit server.
+type GerritAPI struct {
+	URL      string // Web URL of the server, e.g. https://gerrit.example.com
+	Username string // HTTP credentials. Requests are anonymous without them
+	Password string
+}
+
+// get fetches a REST API endpoint such as /changes/1234. With credentials the authenticated
+// variant under /a/ is used.
+func (a GerritAPI) get(endpoint string) ([]byte, error) {
+	u := strings.TrimSuffix(a.URL, "/")
+	var authorization string
+	if a.Username != "" {
+		u += "/a"
+		authorization = "Basic " + base64.StdEncoding.EncodeToString([]byte(a.Username+":"+a.Password))
+	}
+	body, err := getJSON(u+endpoint, authorization)
+	if err != nil {
+		return nil, err
 	}
+	// Gerrit prefixes JSON responses with a magic string to prevent XSSI
+	return bytes.TrimPrefix(body, []byte(")]}'")), nil
 }
 
-func (g GerritReview) Publish() error {
+func (g GerritReview) Publish() (*Result, error) {
 	cwd, err := os.Getwd()
 	if err != nil {
-		panic(err)
+		return nil, fmt.Errorf("unable to determine working directory: %w", err)
 	}
 
-	repo, err := git.PlainOpenWithOptions(cwd, &git.PlainOpenOptions{DetectDotGit: true})
+	repo, err := git.PlainOpenWithOptions(cwd, &git.PlainOpenOptions{DetectDotGit: true, EnableDotGitCommonDir: true})
 	if err != nil {
-		panic(err)
+		return nil, fmt.Errorf("%w: unable to open git repository: %w", ErrNotFound, err)
 	}
 
 	var ref string
@@ -44,25 +82,71 @@ func (g GerritReview) Publish() error {
 		ref = g.BaseBranch
 	}
 
-	head, _ := repo.Head()
+	head, err := repo.Head()
+	if err != nil {
+		return nil, fmt.Errorf("unable to resolve HEAD: %w", err)
+	}
 	refspec := fmt.Sprintf("%s:refs/for/%s", head.Name(), ref)
 	if len(g.Reviewers) > 0 {
 		refspec = fmt.Sprintf("%s%%r=%s", refspec, strings.Join(g.Reviewers, ",r="))
 	}
 	log.WithField("refspec", refspec).Debug("Using refspec")
 
-	err = repo.Push(&git.PushOptions{
-		RemoteName: "origin",
-		RefSpecs:   []config.RefSpec{config.RefSpec(refspec)},
+	var progress bytes.Buffer
+	err = g.Target.Git.Push(gitbackend.PushOptions{
+		Remote:   g.Target.Remote,
+		URL:      g.Target.URL,
+		RefSpecs: []string{refspec},
+		Auth:     g.Target.Auth,
+		Progress: &progress,
+		NoVerify: g.Target.NoVerify,
 	})
+	log.WithField("output", progress.String()).Debug("Push output")
 	if err != nil {
-		log.WithError(err).Error("Error publishing review")
-		os.Exit(1)
+		return nil, remote.Hint(ClassifyGitError(err), g.Target.URL)
+	}
+	result := parsePushOutput(progress.String())
+	if g.API.URL != "" && result.Number > 0 {
+		// the patchset is only informational, so failing to look it up isn't an error
+		if result.Patchset, err = currentPatchset(g.API, result.Number); err != nil {
+			log.WithError(err).Warn("Unable to determine patchset number")
+		}
 	}
-	log.Info("Published review")
-	return nil
+	return result, nil
 }
 
 func (g GerritReview) Merge() error {
 	return &NotImplementedError{}
 }
+
+// parsePushOutput extracts the change URL and number from the messages Gerrit sends back on push.
+func parsePushOutput(output string) *Result {
+	result := &Result{}
+	match := changeURLPattern.FindStringSubmatch(output)
+	if match == nil {
+		return result
+	}
+
+	result.URL = match[1]
+	result.Number, _ = strconv.Atoi(match[2])
+	return result
+}
+
+// currentPatchset queries Gerrit's REST API for the current patchset number of a change.
+func currentPatchset(api GerritAPI, number int) (int, error) {
+	body, err := api.get(fmt.Sprintf("/changes/%d?o=CURRENT_REVISION", number))
+	if err != nil {
+		return 0, err
+	}
+
+	var change struct {
+		CurrentRevision string `json:"current_revision"`
+		Revisions       map[string]struct {
+			Number int `json:"_number"`
+		} `json:"revisions"`
+	}
+	if err := json.Unmarshal(body, &change); err != nil {
+		return 0, err
+	}
+	return change.Revisions[change.CurrentRevision].Number, nil
+}
@@ -0,0 +1,77 @@
+package review
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestParsePushOutput(t *testing.T) {
+	output := `remote: Processing changes: refs: 1, new: 1, done
+remote:
+remote: SUCCESS
+remote:
+remote:   https://gerrit.example.com/c/project/+/1234 PRJ-1. Seed [NEW]
+remote:
+To ssh://gerrit.example.com:29418/project
+ * [new reference]   HEAD -> refs/for/main
+`
+	result := parsePushOutput(output)
+	if result.URL != "https://gerrit.example.com/c/project/+/1234" || result.Number != 1234 || result.Patchset != 0 {
+		t.Errorf("parsePushOutput() = %+v", result)
+	}
+	if result := parsePushOutput("Everything up-to-date"); result.URL != "" || result.Number != 0 {
+		t.Errorf("parsePushOutput() without a change = %+v", result)
+	}
+}
+
+// newGerritServer serves change 1234 at patchset 3 from /changes/ and, with credentials, from
+// /a/changes/.
+func newGerritServer(t *testing.T, username string, password string) string {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		user, pass, ok := req.BasicAuth()
+		switch {
+		case req.URL.Path == "/a/changes/1234" && ok && user == username && pass == password:
+		case req.URL.Path == "/changes/1234" && username == "":
+		default:
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+		_, _ = fmt.Fprint(w, `)]}'
+{"current_revision": "abc", "revisions": {"abc": {"_number": 3}, "def": {"_number": 2}}}`)
+	}))
+	t.Cleanup(server.Close)
+	return server.URL
+}
+
+func TestCurrentPatchset(t *testing.T) {
+	tests := []struct {
+		name     string
+		server   [2]string // credentials the server requires
+		api      GerritAPI
+		want     int
+		wantAuth bool // fails with ErrAuthentication
+	}{
+		{name: "anonymous", api: GerritAPI{}, want: 3},
+		{name: "authenticated", server: [2]string{"alice", "secret"}, api: GerritAPI{Username: "alice", Password: "secret"}, want: 3},
+		{name: "credentials required", server: [2]string{"alice", "secret"}, api: GerritAPI{}, wantAuth: true},
+		{name: "wrong password", server: [2]string{"alice", "secret"}, api: GerritAPI{Username: "alice", Password: "nope"}, wantAuth: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.api.URL = newGerritServer(t, tt.server[0], tt.server[1]) + "/"
+			got, err := currentPatchset(tt.api, 1234)
+			if tt.wantAuth {
+				if !errors.Is(err, ErrAuthentication) {
+					t.Errorf("currentPatchset() = %d, %v, want an authentication error", got, err)
+				}
+				return
+			}
+			if err != nil || got != tt.want {
+				t.Errorf("currentPatchset() = %d, %v, want %d", got, err, tt.want)
+			}
+		})
+	}
+}
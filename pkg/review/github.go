@@ -4,7 +4,7 @@ type GitHubReview struct {
 	Meta
 }
 
-func NewGitHubReview(title string, description string, reviewers []string, baseBranch string, isDraft bool) Review {
+func NewGitHubReview(title string, description string, reviewers []string, baseBranch string, isDraft bool, target Target) Review {
 	return &GitHubReview{
 		Meta: Meta{
 			Title:       title,
@@ -12,12 +12,13 @@ func NewGitHubReview(title string, description string, reviewers []string, baseB
 			Reviewers:   reviewers,
 			BaseBranch:  baseBranch,
 			IsDraft:     isDraft,
+			Target:      target,
 		},
 	}
 }
 
-func (g GitHubReview) Publish() error {
-	return &NotImplementedError{}
+func (g GitHubReview) Publish() (*Result, error) {
+	return nil, &NotImplementedError{}
 }
 
 func (g GitHubReview) Merge() error {
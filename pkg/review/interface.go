@@ -1,16 +1,39 @@
 package review
 
+import (
+	"github.com/go-git/go-git/v5/plumbing/transport"
+
+	"github.com/kunickiaj/beer/pkg/gitbackend"
+)
+
 type Review interface {
-	Publish() error
+	Publish() (*Result, error)
 	Merge() error
 }
 
+// Result describes a review after it has been published.
+type Result struct {
+	URL      string `json:"url,omitempty" yaml:"url,omitempty"`           // Link to the change or pull request
+	Number   int    `json:"number,omitempty" yaml:"number,omitempty"`     // Gerrit change number or GitHub PR number
+	Patchset int    `json:"patchset,omitempty" yaml:"patchset,omitempty"` // Gerrit patchset number, if known
+}
+
 type Meta struct {
 	Title       string   // First line of the commit message
 	Description string   // The rest of the commit message or PR description
 	Reviewers   []string // Reviewers to notify
 	BaseBranch  string   // The branch to merge into
 	IsDraft     bool     // Is this a draft request?
+	Target      Target   // Where the review is pushed
+}
+
+// Target is the remote a review is pushed to.
+type Target struct {
+	Remote   string               // Name of the remote
+	URL      string               // URL to push to, after pushurl and insteadOf rewriting
+	Auth     transport.AuthMethod // Credentials for the remote, nil if none are needed
+	Git      gitbackend.Backend   // Performs the push
+	NoVerify bool                 // Skip the pre-push hook
 }
 
 type NotImplementedError struct{}
@@ -18,3 +41,8 @@ type NotImplementedError struct{}
 func (e *NotImplementedError) Error() string {
 	return "Functionality not yet implemented"
 }
+
+// Is allows errors.Is(err, ErrNotImplemented) to match a NotImplementedError.
+func (e *NotImplementedError) Is(target error) bool {
+	return target == ErrNotImplemented
+}
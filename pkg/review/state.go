@@ -0,0 +1,128 @@
+package review
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// State is the state of the review of a work branch.
+type State string
+
+const (
+	StateNone      State = "none" // No review was found
+	StateOpen      State = "open"
+	StateMerged    State = "merged"
+	StateAbandoned State = "abandoned" // Abandoned in Gerrit or closed without merging on GitHub
+	StateUnknown   State = "unknown"   // The review couldn't be looked up
+)
+
+// gitHubRepoPattern matches the owner and name of a repository in SSH or HTTPS remote URLs.
+var gitHubRepoPattern = regexp.MustCompile(`[/:]([^/:]+)/([^/]+?)(?:\.git)?/?$`)
+
+var httpClient = http.Client{Timeout: 10 * time.Second}
+
+// GerritChangeState looks up the state of the change with the given Change-Id using the REST API
+// of a Gerrit server. A change merged on any branch counts as merged.
+func GerritChangeState(api GerritAPI, changeID string) (State, error) {
+	body, err := api.get("/changes/?q=change:" + url.QueryEscape(changeID))
+	if err != nil {
+		return "", err
+	}
+
+	var changes []struct {
+		Status string `json:"status"`
+	}
+	if err := json.Unmarshal(body, &changes); err != nil {
+		return "", err
+	}
+	if len(changes) == 0 {
+		return StateNone, nil
+	}
+
+	state := StateAbandoned
+	for _, c := range changes {
+		switch c.Status {
+		case "MERGED":
+			return StateMerged, nil
+		case "NEW":
+			state = StateOpen
+		}
+	}
+	return state, nil
+}
+
+// GitHubPullState looks up the state of the most recent pull request for branch in the
+// repository baseURL points to. headURL is the repository branch was pushed to, which is a fork
+// of the base repository in fork workflows. token may be empty for public repositories.
+func GitHubPullState(apiURL string, baseURL string, headURL string, branch string, token string) (State, error) {
+	match := gitHubRepoPattern.FindStringSubmatch(baseURL)
+	if match == nil {
+		return "", fmt.Errorf("%w: %s is not a GitHub repository URL", ErrNotFound, baseURL)
+	}
+	owner, repo := match[1], match[2]
+	head := gitHubRepoPattern.FindStringSubmatch(headURL)
+	if head == nil {
+		return "", fmt.Errorf("%w: %s is not a GitHub repository URL", ErrNotFound, headURL)
+	}
+
+	query := url.Values{"head": {head[1] + ":" + branch}, "state": {"all"}, "per_page": {"1"}}
+	var authorization string
+	if token != "" {
+		authorization = "Bearer " + token
+	}
+	body, err := getJSON(fmt.Sprintf("%s/repos/%s/%s/pulls?%s", strings.TrimSuffix(apiURL, "/"), owner, repo, query.Encode()), authorization)
+	if err != nil {
+		return "", err
+	}
+
+	var pulls []struct {
+		State    string  `json:"state"`
+		MergedAt *string `json:"merged_at"`
+	}
+	if err := json.Unmarshal(body, &pulls); err != nil {
+		return "", err
+	}
+	switch {
+	case len(pulls) == 0:
+		return StateNone, nil
+	case pulls[0].MergedAt != nil:
+		return StateMerged, nil
+	case pulls[0].State == "closed":
+		return StateAbandoned, nil
+	}
+	return StateOpen, nil
+}
+
+// getJSON fetches a JSON document, classifying failures like other review errors.
+func getJSON(u string, authorization string) ([]byte, error) {
+	req, err := http.NewRequest(http.MethodGet, u, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/json")
+	if authorization != "" {
+		req.Header.Set("Authorization", authorization)
+	}
+
+	res, err := httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrNetwork, err)
+	}
+	defer func() { _ = res.Body.Close() }()
+
+	switch res.StatusCode {
+	case http.StatusOK:
+		return io.ReadAll(res.Body)
+	case http.StatusUnauthorized, http.StatusForbidden:
+		return nil, fmt.Errorf("%w: %s returned %s", ErrAuthentication, u, res.Status)
+	case http.StatusNotFound:
+		return nil, fmt.Errorf("%w: %s returned %s", ErrNotFound, u, res.Status)
+	}
+	return nil, fmt.Errorf("%s returned %s", u, res.Status)
+}
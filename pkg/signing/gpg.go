@@ -0,0 +1,54 @@
+package signing
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os/exec"
+	"strings"
+)
+
+// gpgSigCreated is the status line gpg prints once it has made a signature.
+const gpgSigCreated = "[GNUPG:] SIG_CREATED "
+
+// GPGSigner signs git objects by running gpg the way git does when gpg.format is openpgp. The
+// secret key stays with gpg-agent, so keys protected by a passphrase or kept on a smartcard work.
+type GPGSigner struct {
+	Program string // gpg or a compatible program, such as set by gpg.program
+	Key     string // Key ID, fingerprint or user ID of the signing key
+}
+
+// NewGPGSigner returns a signer running program to sign with key.
+func NewGPGSigner(program string, key string) *GPGSigner {
+	return &GPGSigner{Program: program, Key: key}
+}
+
+// Sign returns the armored detached signature of message.
+func (s *GPGSigner) Sign(message io.Reader) ([]byte, error) {
+	c := exec.Command(s.Program, "--status-fd=2", "-bsau", s.Key)
+	c.Stdin = message
+	var stdout, stderr bytes.Buffer
+	c.Stdout = &stdout
+	c.Stderr = &stderr
+	err := c.Run()
+
+	// like git, only trust the signature if gpg reported creating it
+	if err != nil || !strings.Contains("\n"+stderr.String(), "\n"+gpgSigCreated) {
+		if err == nil {
+			err = fmt.Errorf("%s didn't create a signature", s.Program)
+		}
+		return nil, fmt.Errorf("unable to sign with OpenPGP key %s: %w: %s", s.Key, err, gpgMessages(stderr.String()))
+	}
+	return stdout.Bytes(), nil
+}
+
+// gpgMessages returns the messages gpg printed for people, leaving out its status lines.
+func gpgMessages(stderr string) string {
+	var messages []string
+	for _, line := range strings.Split(stderr, "\n") {
+		if line = strings.TrimSpace(line); line != "" && !strings.HasPrefix(line, "[GNUPG:]") {
+			messages = append(messages, line)
+		}
+	}
+	return strings.Join(messages, "; ")
+}
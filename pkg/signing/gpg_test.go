@@ -0,0 +1,95 @@
+package signing
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// newGPGHome creates a gpg home with a throwaway signing key for test@example.com, protected by
+// passphrase if it isn't empty.
+func newGPGHome(t *testing.T, passphrase string) {
+	t.Helper()
+	if _, err := exec.LookPath("gpg"); err != nil {
+		t.Skip("gpg not found")
+	}
+	// gpg-agent's socket path must be short, so the home isn't nested in the test's name
+	home, err := os.MkdirTemp("", "gpg")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("GNUPGHOME", home)
+	t.Cleanup(func() {
+		_ = exec.Command("gpgconf", "--kill", "gpg-agent").Run()
+		os.RemoveAll(home)
+	})
+
+	// allow giving the passphrase on the command line and remember it, like an unlocked agent
+	if err := os.WriteFile(filepath.Join(home, "gpg-agent.conf"), []byte("allow-loopback-pinentry\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	gen := exec.Command("gpg", "--batch", "--pinentry-mode", "loopback", "--passphrase", passphrase,
+		"--quick-generate-key", "Test <test@example.com>", "ed25519", "sign", "never")
+	if out, err := gen.CombinedOutput(); err != nil {
+		t.Fatalf("unable to generate key: %v: %s", err, out)
+	}
+	if passphrase != "" {
+		// signing once caches the passphrase in the agent, as the user's pinentry would
+		unlock := exec.Command("gpg", "--batch", "--pinentry-mode", "loopback", "--passphrase", passphrase, "-bsau", "test@example.com")
+		unlock.Stdin = strings.NewReader("unlock")
+		if out, err := unlock.CombinedOutput(); err != nil {
+			t.Fatalf("unable to unlock key: %v: %s", err, out)
+		}
+	}
+}
+
+func verifyGPG(t *testing.T, signature []byte) {
+	t.Helper()
+	sig := filepath.Join(t.TempDir(), "payload.asc")
+	if err := os.WriteFile(sig, signature, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	verify := exec.Command("gpg", "--batch", "--status-fd=1", "--verify", sig, "-")
+	verify.Stdin = strings.NewReader(testPayload)
+	out, err := verify.CombinedOutput()
+	if err != nil || !strings.Contains(string(out), "[GNUPG:] GOODSIG ") {
+		t.Errorf("gpg --verify failed: %v: %s", err, out)
+	}
+}
+
+func TestGPGSigner(t *testing.T) {
+	tests := []struct {
+		name       string
+		passphrase string
+		key        string
+	}{
+		{name: "unprotected key", key: "test@example.com"},
+		{name: "passphrase protected key", passphrase: "secret", key: "Test <test@example.com>"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			newGPGHome(t, tt.passphrase)
+			signature, err := NewGPGSigner("gpg", tt.key).Sign(strings.NewReader(testPayload))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !strings.HasPrefix(string(signature), "-----BEGIN PGP SIGNATURE-----") {
+				t.Errorf("Sign() = %q, want an armored signature", signature)
+			}
+			verifyGPG(t, signature)
+		})
+	}
+}
+
+func TestGPGSignerUnknownKey(t *testing.T) {
+	newGPGHome(t, "")
+	_, err := NewGPGSigner("gpg", "nobody@example.com").Sign(strings.NewReader(testPayload))
+	if err == nil {
+		t.Fatal("Sign() with an unknown key succeeded")
+	}
+	if strings.Contains(err.Error(), "[GNUPG:]") {
+		t.Errorf("Sign() error includes gpg status lines: %v", err)
+	}
+}
@@ -0,0 +1,198 @@
+package signing
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/sha512"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"strings"
+
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/agent"
+)
+
+// sshNamespace is the namespace git uses for SSH signatures of commits and tags.
+const sshNamespace = "git"
+
+// SSHSigner signs git objects with an SSH key in the SSHSIG format, like git does when
+// gpg.format is ssh. See https://github.com/openssh/openssh-portable/blob/master/PROTOCOL.sshsig
+type SSHSigner struct {
+	signer ssh.Signer
+}
+
+// NewSSHSigner returns a signer using the given SSH key.
+func NewSSHSigner(signer ssh.Signer) *SSHSigner {
+	return &SSHSigner{signer: signer}
+}
+
+// Sign returns the armored SSH signature of message.
+func (s *SSHSigner) Sign(message io.Reader) ([]byte, error) {
+	h := sha512.New()
+	if _, err := io.Copy(h, message); err != nil {
+		return nil, err
+	}
+
+	signed := ssh.Marshal(struct {
+		Magic     [6]byte
+		Namespace string
+		Reserved  string
+		Hash      string
+		Digest    []byte
+	}{sshMagic(), sshNamespace, "", "sha512", h.Sum(nil)})
+
+	var sig *ssh.Signature
+	var err error
+	if as, ok := s.signer.(ssh.AlgorithmSigner); ok && s.signer.PublicKey().Type() == ssh.KeyAlgoRSA {
+		// ssh-rsa signatures use SHA-1, which OpenSSH no longer accepts for SSHSIG
+		sig, err = as.SignWithAlgorithm(rand.Reader, signed, ssh.KeyAlgoRSASHA512)
+	} else {
+		sig, err = s.signer.Sign(rand.Reader, signed)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("unable to sign with SSH key: %w", err)
+	}
+
+	blob := ssh.Marshal(struct {
+		Magic     [6]byte
+		Version   uint32
+		PublicKey []byte
+		Namespace string
+		Reserved  string
+		Hash      string
+		Signature []byte
+	}{sshMagic(), 1, s.signer.PublicKey().Marshal(), sshNamespace, "", "sha512", ssh.Marshal(sig)})
+
+	return armor(blob), nil
+}
+
+func sshMagic() [6]byte {
+	var magic [6]byte
+	copy(magic[:], "SSHSIG")
+	return magic
+}
+
+// armor encodes an SSH signature blob the way ssh-keygen -Y sign does.
+func armor(blob []byte) []byte {
+	encoded := base64.StdEncoding.EncodeToString(blob)
+	var b bytes.Buffer
+	b.WriteString("-----BEGIN SSH SIGNATURE-----\n")
+	for len(encoded) > 70 {
+		b.WriteString(encoded[:70] + "\n")
+		encoded = encoded[70:]
+	}
+	b.WriteString(encoded + "\n")
+	b.WriteString("-----END SSH SIGNATURE-----\n")
+	return b.Bytes()
+}
+
+// LoadSSHKey finds the SSH key named by user.signingkey: a private key file, a public key file
+// or a literal "key::<public key>". Public keys are looked up in the SSH agent first, then a
+// private key file next to the public key is used. passphrase is called for encrypted keys.
+func LoadSSHKey(signingKey string, passphrase func() ([]byte, error)) (ssh.Signer, error) {
+	var public ssh.PublicKey
+	var privateFile string
+
+	switch {
+	case strings.HasPrefix(signingKey, "key::"):
+		key, _, _, _, err := ssh.ParseAuthorizedKey([]byte(strings.TrimPrefix(signingKey, "key::")))
+		if err != nil {
+			return nil, fmt.Errorf("invalid user.signingkey: %w", err)
+		}
+		public = key
+	default:
+		data, err := os.ReadFile(signingKey)
+		if err != nil {
+			return nil, fmt.Errorf("unable to read signing key: %w", err)
+		}
+		if key, _, _, _, err := ssh.ParseAuthorizedKey(data); err == nil {
+			public = key
+			privateFile = strings.TrimSuffix(signingKey, ".pub")
+		} else {
+			return parsePrivateKey(data, passphrase)
+		}
+	}
+
+	if signer, err := agentSigner(public); err == nil {
+		return signer, nil
+	}
+	if privateFile == "" || privateFile == signingKey {
+		return nil, errors.New("the SSH signing key isn't loaded in ssh-agent")
+	}
+	data, err := os.ReadFile(privateFile)
+	if err != nil {
+		return nil, fmt.Errorf("the SSH signing key isn't loaded in ssh-agent and its private key can't be read: %w", err)
+	}
+	return parsePrivateKey(data, passphrase)
+}
+
+func parsePrivateKey(data []byte, passphrase func() ([]byte, error)) (ssh.Signer, error) {
+	signer, err := ssh.ParsePrivateKey(data)
+	var missing *ssh.PassphraseMissingError
+	if errors.As(err, &missing) && passphrase != nil {
+		pass, perr := passphrase()
+		if perr != nil {
+			return nil, perr
+		}
+		return ssh.ParsePrivateKeyWithPassphrase(data, pass)
+	}
+	return signer, err
+}
+
+// agentSigner returns the signer for public from the SSH agent at $SSH_AUTH_SOCK.
+func agentSigner(public ssh.PublicKey) (ssh.Signer, error) {
+	sock := os.Getenv("SSH_AUTH_SOCK")
+	if sock == "" {
+		return nil, errors.New("SSH_AUTH_SOCK is not set")
+	}
+	conn, err := net.Dial("unix", sock)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	keys, err := agent.NewClient(conn).List()
+	if err != nil {
+		return nil, err
+	}
+	for _, k := range keys {
+		if bytes.Equal(k.Marshal(), public.Marshal()) {
+			return &agentKey{sock: sock, public: public}, nil
+		}
+	}
+	return nil, errors.New("key not found in ssh-agent")
+}
+
+// agentKey signs with a key held by the SSH agent, connecting to the agent for each signature so
+// no connection is left open.
+type agentKey struct {
+	sock   string
+	public ssh.PublicKey
+}
+
+func (k *agentKey) PublicKey() ssh.PublicKey {
+	return k.public
+}
+
+func (k *agentKey) Sign(rand io.Reader, data []byte) (*ssh.Signature, error) {
+	return k.SignWithAlgorithm(rand, data, "")
+}
+
+func (k *agentKey) SignWithAlgorithm(_ io.Reader, data []byte, algorithm string) (*ssh.Signature, error) {
+	var flags agent.SignatureFlags
+	switch algorithm {
+	case ssh.KeyAlgoRSASHA256:
+		flags = agent.SignatureFlagRsaSha256
+	case ssh.KeyAlgoRSASHA512:
+		flags = agent.SignatureFlagRsaSha512
+	}
+	conn, err := net.Dial("unix", k.sock)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	return agent.NewClient(conn).SignWithFlags(k.public, data, flags)
+}
@@ -0,0 +1,203 @@
+package signing
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/pem"
+	"net"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/agent"
+)
+
+const testPayload = "tree 4b825dc642cb6eb9a060e54bf8d69288fbee4904\nauthor Test <test@example.com> 1704099600 +0000\n\nPRJ-1. Seed\n"
+
+func generateKeys(t *testing.T) map[string]crypto.Signer {
+	t.Helper()
+	_, ed, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ec, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rs, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return map[string]crypto.Signer{"ed25519": ed, "ecdsa": ec, "rsa": rs}
+}
+
+// verifySSH checks a signature with ssh-keygen, the way git verifies SSH signed commits.
+func verifySSH(t *testing.T, public ssh.PublicKey, signature []byte) {
+	t.Helper()
+	if _, err := exec.LookPath("ssh-keygen"); err != nil {
+		t.Skip("ssh-keygen not found")
+	}
+	dir := t.TempDir()
+	allowed := filepath.Join(dir, "allowed_signers")
+	if err := os.WriteFile(allowed, []byte("test@example.com "+string(ssh.MarshalAuthorizedKey(public))), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	sig := filepath.Join(dir, "payload.sig")
+	if err := os.WriteFile(sig, signature, 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	verify := exec.Command("ssh-keygen", "-Y", "verify", "-f", allowed, "-I", "test@example.com", "-n", "git", "-s", sig)
+	verify.Stdin = strings.NewReader(testPayload)
+	if out, err := verify.CombinedOutput(); err != nil {
+		t.Errorf("ssh-keygen -Y verify failed: %v: %s", err, out)
+	}
+}
+
+func TestSSHSigner(t *testing.T) {
+	for name, key := range generateKeys(t) {
+		t.Run(name, func(t *testing.T) {
+			signer, err := ssh.NewSignerFromSigner(key)
+			if err != nil {
+				t.Fatal(err)
+			}
+			signature, err := NewSSHSigner(signer).Sign(strings.NewReader(testPayload))
+			if err != nil {
+				t.Fatal(err)
+			}
+			verifySSH(t, signer.PublicKey(), signature)
+		})
+	}
+}
+
+func TestLoadSSHKey(t *testing.T) {
+	keys := generateKeys(t)
+	passphrase := []byte("secret")
+
+	// an agent holding the RSA key, to sign with through its public key
+	keyring := agent.NewKeyring()
+	if err := keyring.Add(agent.AddedKey{PrivateKey: keys["rsa"]}); err != nil {
+		t.Fatal(err)
+	}
+	sock := filepath.Join(t.TempDir(), "agent.sock")
+	listener, err := net.Listen("unix", sock)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { listener.Close() })
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				_ = agent.ServeAgent(keyring, conn)
+			}()
+		}
+	}()
+	t.Setenv("SSH_AUTH_SOCK", sock)
+
+	dir := t.TempDir()
+	write := func(name string, data []byte) string {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, data, 0o600); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+	publicKey := func(name string) ssh.PublicKey {
+		public, err := ssh.NewPublicKey(keys[name].Public())
+		if err != nil {
+			t.Fatal(err)
+		}
+		return public
+	}
+	private := func(name string, pass []byte) []byte {
+		var block *pem.Block
+		var err error
+		if pass == nil {
+			block, err = ssh.MarshalPrivateKey(keys[name], "")
+		} else {
+			block, err = ssh.MarshalPrivateKeyWithPassphrase(keys[name], "", pass)
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		return pem.EncodeToMemory(block)
+	}
+
+	tests := []struct {
+		name       string
+		signingKey string
+		public     ssh.PublicKey
+		wantAsked  bool
+	}{
+		{
+			name:       "private key file",
+			signingKey: write("id_ed25519", private("ed25519", nil)),
+			public:     publicKey("ed25519"),
+		},
+		{
+			name:       "encrypted private key file",
+			signingKey: write("id_ecdsa", private("ecdsa", passphrase)),
+			public:     publicKey("ecdsa"),
+			wantAsked:  true,
+		},
+		{
+			name:       "public key file in the agent",
+			signingKey: write("id_rsa.pub", ssh.MarshalAuthorizedKey(publicKey("rsa"))),
+			public:     publicKey("rsa"),
+		},
+		{
+			name:       "public key literal in the agent",
+			signingKey: "key::" + strings.TrimSpace(string(ssh.MarshalAuthorizedKey(publicKey("rsa")))),
+			public:     publicKey("rsa"),
+		},
+		{
+			name: "public key file next to its encrypted private key",
+			signingKey: func() string {
+				write("signing", private("ed25519", passphrase))
+				return write("signing.pub", ssh.MarshalAuthorizedKey(publicKey("ed25519")))
+			}(),
+			public:    publicKey("ed25519"),
+			wantAsked: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			asked := false
+			signer, err := LoadSSHKey(tt.signingKey, func() ([]byte, error) {
+				asked = true
+				return passphrase, nil
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if asked != tt.wantAsked {
+				t.Errorf("passphrase asked = %t, want %t", asked, tt.wantAsked)
+			}
+			if !bytes.Equal(signer.PublicKey().Marshal(), tt.public.Marshal()) {
+				t.Fatalf("LoadSSHKey() loaded %s, want %s", ssh.FingerprintSHA256(signer.PublicKey()), ssh.FingerprintSHA256(tt.public))
+			}
+			signature, err := NewSSHSigner(signer).Sign(strings.NewReader(testPayload))
+			if err != nil {
+				t.Fatal(err)
+			}
+			verifySSH(t, tt.public, signature)
+		})
+	}
+
+	if _, err := LoadSSHKey(write("unknown.pub", ssh.MarshalAuthorizedKey(publicKey("ecdsa"))), nil); err == nil {
+		t.Error("LoadSSHKey() of a public key without agent or private key succeeded")
+	}
+}
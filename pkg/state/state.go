@@ -0,0 +1,61 @@
+package state
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+
+	"github.com/kunickiaj/beer/pkg/atomicfile"
+)
+
+// Store keeps JSON encoded state that must survive between runs, such as running timers. Unlike
+// the cache, its contents can't be fetched again, so it lives in the user's state directory.
+type Store struct {
+	Dir string
+}
+
+// New returns a store in dir, or in beer's directory under $XDG_STATE_HOME (~/.local/state by
+// default) when dir is empty.
+func New(dir string) (*Store, error) {
+	if dir == "" {
+		base := os.Getenv("XDG_STATE_HOME")
+		if base == "" {
+			home, err := os.UserHomeDir()
+			if err != nil {
+				return nil, err
+			}
+			base = filepath.Join(home, ".local", "state")
+		}
+		dir = filepath.Join(base, "beer")
+	}
+	return &Store{Dir: dir}, nil
+}
+
+// Load decodes the state stored under name into v. v is left unchanged if nothing is stored.
+func (s *Store) Load(name string, v interface{}) error {
+	data, err := os.ReadFile(s.path(name))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
+
+// Save stores v under name.
+func (s *Store) Save(name string, v interface{}) error {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(s.Dir, 0o700); err != nil {
+		return err
+	}
+
+	return atomicfile.WriteFile(s.path(name), data)
+}
+
+func (s *Store) path(name string) string {
+	return filepath.Join(s.Dir, name+".json")
+}
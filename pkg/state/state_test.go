@@ -0,0 +1,69 @@
+package state
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestNew(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	tests := []struct {
+		name string
+		dir  string
+		xdg  string
+		want string
+	}{
+		{name: "configured directory", dir: "/var/lib/beer", xdg: "/xdg", want: "/var/lib/beer"},
+		{name: "XDG_STATE_HOME", xdg: "/xdg", want: "/xdg/beer"},
+		{name: "home directory", want: filepath.Join(home, ".local", "state", "beer")},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("XDG_STATE_HOME", tt.xdg)
+			s, err := New(tt.dir)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if s.Dir != tt.want {
+				t.Errorf("New(%q).Dir = %s, want %s", tt.dir, s.Dir, tt.want)
+			}
+		})
+	}
+}
+
+func TestStore(t *testing.T) {
+	s := &Store{Dir: filepath.Join(t.TempDir(), "beer")}
+	started := time.Date(2024, 1, 1, 9, 0, 0, 0, time.UTC)
+
+	timers := map[string]time.Time{"PRJ-0": started}
+	if err := s.Load("timers", &timers); err != nil {
+		t.Fatal(err)
+	}
+	if len(timers) != 1 {
+		t.Errorf("Load() of missing state changed the value to %v", timers)
+	}
+
+	if err := s.Save("timers", map[string]time.Time{"PRJ-1": started}); err != nil {
+		t.Fatal(err)
+	}
+	loaded := map[string]time.Time{}
+	if err := s.Load("timers", &loaded); err != nil {
+		t.Fatal(err)
+	}
+	if len(loaded) != 1 || !loaded["PRJ-1"].Equal(started) {
+		t.Errorf("Load() = %v, want PRJ-1 started at %s", loaded, started)
+	}
+	if info, err := os.Stat(s.Dir); err != nil || info.Mode().Perm() != 0o700 {
+		t.Errorf("state directory = %v, %v, want it only accessible by the user", info, err)
+	}
+
+	if err := os.WriteFile(filepath.Join(s.Dir, "broken.json"), []byte("{"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Load("broken", &loaded); err == nil {
+		t.Error("Load() of invalid JSON succeeded")
+	}
+}
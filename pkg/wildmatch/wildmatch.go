@@ -0,0 +1,42 @@
+// Package wildmatch matches slash separated paths against git style wildcard patterns.
+package wildmatch
+
+import (
+	"regexp"
+	"strings"
+)
+
+// Compile converts a pattern to a regular expression matching whole paths. * and ? don't match a
+// slash while ** matches across directories, so **/ matches any number of leading directories.
+// With fold set the match ignores case.
+func Compile(pattern string, fold bool) (*regexp.Regexp, error) {
+	var sb strings.Builder
+	if fold {
+		sb.WriteString("(?i)")
+	}
+	sb.WriteString("^")
+	for i := 0; i < len(pattern); i++ {
+		switch {
+		case strings.HasPrefix(pattern[i:], "**/"):
+			sb.WriteString("(?:.*/)?")
+			i += 2
+		case strings.HasPrefix(pattern[i:], "**"):
+			sb.WriteString(".*")
+			i++
+		case pattern[i] == '*':
+			sb.WriteString("[^/]*")
+		case pattern[i] == '?':
+			sb.WriteString("[^/]")
+		default:
+			sb.WriteString(regexp.QuoteMeta(pattern[i : i+1]))
+		}
+	}
+	sb.WriteString("$")
+	return regexp.Compile(sb.String())
+}
+
+// Match reports whether name matches pattern. Invalid patterns match nothing.
+func Match(pattern string, name string, fold bool) bool {
+	re, err := Compile(pattern, fold)
+	return err == nil && re.MatchString(name)
+}
@@ -0,0 +1,32 @@
+package wildmatch
+
+import "testing"
+
+func TestMatch(t *testing.T) {
+	tests := []struct {
+		pattern string
+		name    string
+		fold    bool
+		want    bool
+	}{
+		{"*.tf", "main.tf", false, true},
+		{"*.tf", "modules/main.tf", false, false},
+		{"**/*.tf", "main.tf", false, true},
+		{"**/*.tf", "modules/vpc/main.tf", false, true},
+		{"docs/**", "docs/a/b.md", false, true},
+		{"docs/**", "src/docs/a.md", false, false},
+		{"a?c", "abc", false, true},
+		{"a?c", "a/c", false, false},
+		{"/home/me/work/**", "/home/me/work/repo/.git", false, true},
+		{"/home/Me/**", "/home/me/repo", false, false},
+		{"/home/Me/**", "/home/me/repo", true, true},
+		{"release/*", "release/1.0", false, true},
+		{"file.go", "fileXgo", false, false},
+		{"(a+)", "(a+)", false, true},
+	}
+	for _, tt := range tests {
+		if got := Match(tt.pattern, tt.name, tt.fold); got != tt.want {
+			t.Errorf("Match(%q, %q, %t) = %t, want %t", tt.pattern, tt.name, tt.fold, got, tt.want)
+		}
+	}
+}